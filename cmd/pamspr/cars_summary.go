@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// carsSummaryCommand prints the CARS TAS/BETC amounts of a file rolled up by
+// TAS and BETC
+func carsSummaryCommand(args []string) {
+	flags := flag.NewFlagSet("cars-summary", flag.ExitOnError)
+	var (
+		input  = flags.String("input", "", "Input file path")
+		format = flags.String("format", "text", "Summary format (text, json or csv)")
+	)
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if *input == "" {
+		log.Fatal("Input file required for cars-summary")
+	}
+
+	var write func(*pamspr.CARSSummary) error
+	switch *format {
+	case "text":
+		write = func(s *pamspr.CARSSummary) error { return s.WriteText(os.Stdout) }
+	case "json":
+		write = func(s *pamspr.CARSSummary) error { return s.WriteJSON(os.Stdout) }
+	case "csv":
+		write = func(s *pamspr.CARSSummary) error { return s.WriteCSV(os.Stdout) }
+	default:
+		log.Fatalf("Unknown summary format: %s (use 'text', 'json' or 'csv')", *format)
+	}
+
+	// CARS records are only attached to payments when the whole file is read
+	if err := write(pamspr.SummarizeCARS(readFile(*input))); err != nil {
+		log.Fatalf("Error writing summary: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// diffCommand prints what changed between two files and exits with status 1
+// when they differ, like diff(1)
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "text", "Difference report format (text or json)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pamspr diff [-format text|json] original.spr corrected.spr")
+		flags.PrintDefaults()
+	}
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown report format: %s (use 'text' or 'json')", *format)
+	}
+
+	diff := pamspr.Diff(readFile(flags.Arg(0)), readFile(flags.Arg(1)))
+
+	var err error
+	if *format == "json" {
+		err = diff.WriteJSON(os.Stdout)
+	} else {
+		err = diff.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("Error writing differences: %v", err)
+	}
+
+	if !diff.Empty() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// duplicatesCommand streams a file through the duplicate payment detector and
+// exits with status 1 when duplicates are found
+func duplicatesCommand(args []string) {
+	flags := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	var (
+		input  = flags.String("input", "", "Input file path")
+		store  = flags.String("store", "", "Fingerprint store of earlier files (created if missing)")
+		record = flags.Bool("record", false, "Add the file's fingerprints to -store when no duplicates are found")
+		window = flags.Duration("window", pamspr.DefaultDuplicateHistoryWindow, "Ignore stored fingerprints older than this (0 for no limit)")
+		format = flags.String("format", "text", "Duplicate report format (text or json)")
+		rules  []pamspr.DuplicateRule
+	)
+	flags.Func("rule", "Match rule name=Key,Key,... replacing the defaults; repeatable (keys: PaymentID, TIN, Amount, RoutingNumber, AccountNumber, PayeeName, AgencyAccountIdentifier)", func(value string) error {
+		name, keys, ok := strings.Cut(value, "=")
+		if !ok || keys == "" {
+			return fmt.Errorf("rule %q must have the form name=Key,Key", value)
+		}
+		rule := pamspr.DuplicateRule{Name: strings.TrimSpace(name), History: true}
+		for _, key := range strings.Split(keys, ",") {
+			rule.Keys = append(rule.Keys, pamspr.DuplicateKey(strings.TrimSpace(key)))
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if *input == "" {
+		log.Fatal("Input file required for duplicates")
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown report format: %s (use 'text' or 'json')", *format)
+	}
+	if *record && *store == "" {
+		log.Fatal("-record requires -store")
+	}
+
+	config := pamspr.DefaultDuplicateConfig()
+	config.HistoryWindow = *window
+	config.Source = *input
+	if rules != nil {
+		config.Rules = rules
+	}
+	if *store != "" {
+		var err error
+		if config.Store, err = pamspr.OpenFingerprintStore(*store); err != nil {
+			log.Fatalf("Error opening fingerprint store: %v", err)
+		}
+	}
+
+	detector, err := pamspr.NewDuplicateDetector(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	reader := newReader(file)
+	duplicates, err := detector.CheckReader(reader)
+	printWarnings(reader)
+	if err != nil {
+		log.Fatalf("Error reading file: %v", err)
+	}
+
+	if *format == "json" {
+		if duplicates == nil {
+			duplicates = []pamspr.Duplicate{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(duplicates); err != nil {
+			log.Fatalf("Error writing duplicates: %v", err)
+		}
+	} else {
+		for _, duplicate := range duplicates {
+			fmt.Println(duplicate)
+		}
+		fmt.Printf("%d duplicate(s) found\n", len(duplicates))
+	}
+
+	if len(duplicates) > 0 {
+		if *record {
+			fmt.Fprintln(os.Stderr, "Fingerprints not recorded because duplicates were found")
+		}
+		os.Exit(1)
+	}
+
+	if *record {
+		if err := detector.Record(); err != nil {
+			log.Fatal(err)
+		}
+		if *window > 0 {
+			config.Store.Prune(time.Now().Add(-*window)) // Older fingerprints are never matched
+		}
+		if err := config.Store.Save(*store); err != nil {
+			log.Fatalf("Error saving fingerprint store: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Recorded fingerprints in %s\n", *store)
+	}
+}
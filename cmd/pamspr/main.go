@@ -1,32 +1,57 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 
 	"github.com/moov-io/pamspr/pkg/pamspr"
 )
 
+// commands are subcommands with their own flags, e.g. "pamspr normalize -input x.spr"
+var commands = map[string]func(args []string){
+	"cars-summary": carsSummaryCommand,
+	"diff":         diffCommand,
+	"duplicates":   duplicatesCommand,
+	"merge":        mergeCommand,
+	"normalize":    normalizeCommand,
+	"prenote":      prenoteCommand,
+	"split":        splitCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	var (
 		validate = flag.Bool("validate", false, "Validate a PAM SPR file")
 		info     = flag.Bool("info", false, "Display file information")
-		convert  = flag.Bool("convert", false, "Convert file to JSON")
+		convert  = flag.Bool("convert", false, "Convert between SPR and JSON (see -to)")
+		to       = flag.String("to", "json", "Conversion target format (json or spr)")
+		format   = flag.String("format", "text", "Validation report format (text or json)")
+		cars     = flag.Bool("cars", false, "With -validate, also check CARS TAS/BETC formats and balances")
 		create   = flag.String("create", "", "Create a sample file (ach or check)")
 		input    = flag.String("input", "", "Input file path")
 		output   = flag.String("output", "", "Output file path")
 	)
+	parseFileFlags := fileFlags(flag.CommandLine)
 
 	flag.Parse()
+	parseFileFlags()
 
 	switch {
 	case *validate:
 		if *input == "" {
 			log.Fatal("Input file required for validation")
 		}
-		validateFile(*input)
+		validateFile(*input, *format, *cars)
 
 	case *info:
 		if *input == "" {
@@ -38,7 +63,14 @@ func main() {
 		if *input == "" || *output == "" {
 			log.Fatal("Both input and output files required for conversion")
 		}
-		convertToJSON(*input, *output)
+		switch *to {
+		case "json":
+			convertToJSON(*input, *output)
+		case "spr":
+			convertToSPR(*input, *output)
+		default:
+			log.Fatalf("Unknown conversion target: %s (use 'json' or 'spr')", *to)
+		}
 
 	case *create != "":
 		if *output == "" {
@@ -51,35 +83,108 @@ func main() {
 	}
 }
 
-func validateFile(filename string) {
+var (
+	// encoding is the character set of SPR files read and written
+	encoding pamspr.Encoding
+	// lineTerminator delimits records in SPR files read and written
+	lineTerminator pamspr.LineTerminator
+	// padShortRecords right-pads short records on input
+	padShortRecords bool
+)
+
+// fileFlags registers the flags every command shares for SPR files and
+// returns a function that applies them once the flags are parsed
+func fileFlags(flags *flag.FlagSet) func() {
+	charset := flags.String("encoding", "ascii", "SPR file encoding (ascii, cp037 or cp1047)")
+	terminator := flags.String("line-terminator", "auto", "Record delimiter (auto, lf, crlf or none for fixed 850-byte blocks)")
+	pad := flags.Bool("pad-short-records", false, "Right-pad input records shorter than 850 characters with spaces")
+
+	return func() {
+		var err error
+		if encoding, err = pamspr.ParseEncoding(*charset); err != nil {
+			log.Fatal(err)
+		}
+		if lineTerminator, err = pamspr.ParseLineTerminator(*terminator); err != nil {
+			log.Fatal(err)
+		}
+		padShortRecords = *pad
+	}
+}
+
+func newReader(f *os.File) *pamspr.Reader {
+	config := pamspr.DefaultConfig()
+	config.Encoding = encoding
+	config.LineTerminator = lineTerminator
+	config.PadShortRecords = padShortRecords
+	return pamspr.NewReaderWithConfig(f, config)
+}
+
+func newWriter(f *os.File) *pamspr.Writer {
+	return pamspr.NewWriterWithConfig(f, writerConfig())
+}
+
+func writerConfig() *pamspr.WriterConfig {
+	config := pamspr.DefaultWriterConfig()
+	config.Encoding = encoding
+	config.LineTerminator = lineTerminator
+	return config
+}
+
+// printWarnings reports how the input was adjusted to be read
+func printWarnings(reader *pamspr.Reader) {
+	for _, warning := range reader.GetWarnings() {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+}
+
+func validateFile(filename, format string, cars bool) {
+	if format != "text" && format != "json" {
+		log.Fatalf("Unknown report format: %s (use 'text' or 'json')", format)
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
 		log.Fatalf("Error opening file: %v", err)
 	}
 	defer file.Close()
 
-	reader := pamspr.NewReader(file)
+	reader := newReader(file)
 	pamFile, err := reader.Read()
+	printWarnings(reader)
 	if err != nil {
-		log.Fatalf("Validation failed: %v", err)
+		fmt.Println("✗ File validation failed")
+		fmt.Println(pamspr.NewDiagnostic(err).Format(filename))
+		os.Exit(1)
 	}
 
-	// Additional validation
+	// Collect every structure, balancing, Same Day ACH and payment error
 	validator := pamspr.NewValidator()
-
-	// Validate file structure
-	if err := validator.ValidateFileStructure(pamFile); err != nil {
-		log.Fatalf("File structure validation failed: %v", err)
+	validator.Encoding = encoding
+	validator.CheckCARSAccounting = cars
+	report := validator.ValidateFileLines(pamFile, reader.RecordLines())
+
+	// Field type and character checks need the raw records, so they come from the reader
+	for _, issue := range reader.GetValidationReport().Issues {
+		var fieldErr *pamspr.FieldError
+		if errors.As(issue.Err, &fieldErr) || issue.Code == pamspr.CodeInvalidHexCharacter {
+			report.Add(issue)
+		}
 	}
 
-	// Validate balancing
-	if err := validator.ValidateBalancing(pamFile); err != nil {
-		log.Fatalf("Balancing validation failed: %v", err)
+	if format == "json" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("Error writing report: %v", err)
+		}
+		if report.HasIssues() {
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Validate Same Day ACH if applicable
-	if err := validator.ValidateSameDayACH(pamFile); err != nil {
-		log.Fatalf("Same Day ACH validation failed: %v", err)
+	if report.HasIssues() {
+		fmt.Println("✗ File validation failed")
+		printDiagnostics(filename, report)
+		os.Exit(1)
 	}
 
 	fmt.Println("✓ File validation passed")
@@ -88,6 +193,30 @@ func validateFile(filename string) {
 	fmt.Printf("  Total Amount: $%.2f\n", float64(pamFile.Trailer.TotalAmountPayments)/100)
 }
 
+// printDiagnostics prints each issue with an excerpt of its record, compiler style
+func printDiagnostics(filename string, report *pamspr.ValidationReport) {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	diagnostics, err := report.Diagnostics(encoding.NewReader(file))
+	if err != nil {
+		log.Fatalf("Error reading file: %v", err)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	for _, d := range diagnostics {
+		fmt.Println(d.Format(filename))
+	}
+	if report.Dropped > 0 {
+		fmt.Printf("... %d more issues not shown\n", report.Dropped)
+	}
+	fmt.Printf("%d validation errors\n", len(report.Issues)+report.Dropped)
+}
+
 func displayFileInfo(filename string) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -95,8 +224,9 @@ func displayFileInfo(filename string) {
 	}
 	defer file.Close()
 
-	reader := pamspr.NewReader(file)
+	reader := newReader(file)
 	pamFile, err := reader.Read()
+	printWarnings(reader)
 	if err != nil {
 		log.Fatalf("Error reading file: %v", err)
 	}
@@ -155,11 +285,56 @@ func displayFileInfo(filename string) {
 }
 
 func convertToJSON(inputFile, outputFile string) {
-	// This would convert the PAM SPR file to JSON format
-	// Implementation would use encoding/json package
-	fmt.Printf("Converting %s to JSON format...\n", inputFile)
-	fmt.Printf("Output will be saved to %s\n", outputFile)
-	// TODO: Implement JSON conversion
+	file, err := os.Open(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	reader := newReader(file)
+	pamFile, err := reader.Read()
+	printWarnings(reader)
+	if err != nil {
+		log.Fatalf("Error reading file: %v", err)
+	}
+
+	output, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer output.Close()
+
+	if err := pamspr.EncodeJSON(output, pamFile); err != nil {
+		log.Fatalf("Error writing JSON: %v", err)
+	}
+
+	fmt.Printf("Converted %s to JSON: %s\n", inputFile, outputFile)
+}
+
+func convertToSPR(inputFile, outputFile string) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	pamFile, err := pamspr.DecodeJSON(file)
+	if err != nil {
+		log.Fatalf("Error reading JSON: %v", err)
+	}
+
+	output, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer output.Close()
+
+	writer := newWriter(output)
+	if err := writer.Write(pamFile); err != nil {
+		log.Fatalf("Error writing file: %v", err)
+	}
+
+	fmt.Printf("Converted %s to SPR: %s\n", inputFile, outputFile)
 }
 
 func createSampleFile(fileType, outputFile string) {
@@ -182,7 +357,7 @@ func createSampleFile(fileType, outputFile string) {
 	}
 	defer output.Close()
 
-	writer := pamspr.NewWriter(output)
+	writer := newWriter(output)
 	if err := writer.Write(file); err != nil {
 		log.Fatalf("Error writing file: %v", err)
 	}
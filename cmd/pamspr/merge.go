@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// mergeCommand combines the files named after the flags into one transmission
+func mergeCommand(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	output := flags.String("output", "", "Output file path")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pamspr merge -output merged.spr input1.spr input2.spr ...")
+		flags.PrintDefaults()
+	}
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if *output == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	files := make([]*pamspr.File, 0, flags.NArg())
+	for _, name := range flags.Args() {
+		files = append(files, readFile(name))
+	}
+
+	merged, err := pamspr.Merge(files...)
+	if err != nil {
+		log.Fatalf("Error merging files:\n%v", err)
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer out.Close()
+
+	if err := newWriter(out).Write(merged); err != nil {
+		log.Fatalf("Error writing file: %v", err)
+	}
+
+	fmt.Printf("Merged %d file(s) into %s: %d schedule(s), %d payment(s), total $%.2f\n",
+		len(files), *output, len(merged.Schedules), merged.Trailer.TotalCountPayments,
+		float64(merged.Trailer.TotalAmountPayments)/100)
+}
+
+// readFile reads and parses the SPR file at name
+func readFile(name string) *pamspr.File {
+	f, err := os.Open(name)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer f.Close()
+
+	reader := newReader(f)
+	file, err := reader.Read()
+	printWarnings(reader)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", name, err)
+	}
+	return file
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// normalizeCommand applies PAM's justification corrections and prints what changed
+func normalizeCommand(args []string) {
+	flags := flag.NewFlagSet("normalize", flag.ExitOnError)
+	var (
+		input  = flags.String("input", "", "Input file path")
+		output = flags.String("output", "", "Output file path (omit to only print the changes)")
+		format = flags.String("format", "text", "Change report format (text or json)")
+	)
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if *input == "" {
+		log.Fatal("Input file required for normalize")
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown report format: %s (use 'text' or 'json')", *format)
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	reader := newReader(file)
+	pamFile, err := reader.Read()
+	printWarnings(reader)
+	if err != nil {
+		log.Fatalf("Error reading file: %v", err)
+	}
+
+	changes := pamspr.Normalize(pamFile)
+
+	if *output != "" {
+		out, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Error creating output file: %v", err)
+		}
+		defer out.Close()
+
+		if err := newWriter(out).Write(pamFile); err != nil {
+			log.Fatalf("Error writing file: %v", err)
+		}
+	}
+
+	if *format == "json" {
+		if changes == nil {
+			changes = []pamspr.NormalizeChange{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(changes); err != nil {
+			log.Fatalf("Error writing changes: %v", err)
+		}
+		return
+	}
+
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	fmt.Printf("%d field(s) normalized\n", len(changes))
+	if *output != "" {
+		fmt.Printf("Wrote %s\n", *output)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// prenoteCommand writes a prenote file for the ACH schedules of a file
+func prenoteCommand(args []string) {
+	flags := flag.NewFlagSet("prenote", flag.ExitOnError)
+	var (
+		input  = flags.String("input", "", "Input file path")
+		output = flags.String("output", "", "Output file path")
+		prefix = flags.String("prefix", "", "Prefix for prenote schedule numbers, e.g. P turns 00000000000001 into P1 (default: keep the numbers)")
+	)
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if *input == "" || *output == "" {
+		log.Fatal("Both input and output files required for prenote")
+	}
+
+	var number func(string, int) string
+	if *prefix != "" {
+		number = func(original string, _ int) string {
+			return *prefix + strings.TrimLeft(original, "0")
+		}
+	}
+
+	prenote, err := pamspr.NewPrenoteFile(readFile(*input), number)
+	if err != nil {
+		log.Fatalf("Error building prenote file: %v", err)
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer out.Close()
+
+	if err := newWriter(out).Write(prenote); err != nil {
+		log.Fatalf("Error writing file: %v", err)
+	}
+
+	fmt.Printf("Wrote %d prenote schedule(s) with %d payment(s) to %s\n",
+		len(prenote.Schedules), prenote.Trailer.TotalCountPayments, *output)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moov-io/pamspr/pkg/pamspr"
+)
+
+// splitCommand splits a file into files within Treasury's size limits
+func splitCommand(args []string) {
+	flags := flag.NewFlagSet("split", flag.ExitOnError)
+	var (
+		input        = flags.String("input", "", "Input file path")
+		output       = flags.String("output", "", "Output file name pattern with a number verb, e.g. part-%03d.spr (default: input name with -%03d before the extension)")
+		maxSchedules = flags.Int("max-schedules", pamspr.MaxSchedulesPerFile, "Maximum schedules per file")
+		maxPayments  = flags.Int("max-payments", pamspr.MaxPaymentsPerSchedule, "Maximum payments per schedule")
+		maxSize      = flags.Int64("max-size", pamspr.MaxFileSizeBytes, "Maximum file size in bytes")
+	)
+	parseFileFlags := fileFlags(flags)
+	flags.Parse(args)
+	parseFileFlags()
+
+	if *input == "" {
+		log.Fatal("Input file required for split")
+	}
+	pattern := *output
+	if pattern == "" {
+		ext := filepath.Ext(*input)
+		pattern = strings.TrimSuffix(*input, ext) + "-%03d" + ext
+	}
+	if !strings.Contains(pattern, "%") {
+		log.Fatalf("Output pattern %q needs a number verb such as %%03d", pattern)
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		log.Fatalf("Error opening file: %v", err)
+	}
+	defer file.Close()
+
+	options := &pamspr.SplitOptions{
+		MaxSchedulesPerFile:    *maxSchedules,
+		MaxPaymentsPerSchedule: *maxPayments,
+		MaxFileSizeBytes:       *maxSize,
+		LineTerminator:         lineTerminator,
+	}
+
+	var written []string
+	reader := newReader(file)
+	count, err := pamspr.SplitReader(reader, options, writerConfig(), func(part int) (io.Writer, error) {
+		name := fmt.Sprintf(pattern, part)
+		written = append(written, name)
+		return os.Create(name)
+	})
+	printWarnings(reader)
+	if err != nil {
+		log.Fatalf("Error splitting file: %v", err)
+	}
+
+	fmt.Printf("Wrote %d file(s)\n", count)
+	for _, name := range written {
+		fmt.Printf("  %s\n", name)
+	}
+}
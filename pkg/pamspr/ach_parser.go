@@ -85,6 +85,7 @@ func (p *ACHParser) ParseACHPayment(line string) (*ACHPayment, error) {
 		PayerMechanism:               extractField(line, fields["PayerMechanism"]),
 		PaymentDescriptionCode:       extractField(line, fields["PaymentDescriptionCode"]),
 		Addenda:                      make([]*ACHAddendum, 0),
+		CTXAddenda:                   make([]*CTXAddendum, 0),
 		CARSTASBETC:                  make([]*CARSTASBETC, 0),
 	}
 
@@ -94,29 +95,30 @@ func (p *ACHParser) ParseACHPayment(line string) (*ACHPayment, error) {
 	return payment, nil
 }
 
-// ParseACHAddendum parses an ACH addendum record ("03" or "04")
+// ParseACHAddendum parses an ACH addendum record ("03")
 func (p *ACHParser) ParseACHAddendum(line string) (*ACHAddendum, error) {
-	if len(line) != RecordLength {
-		return nil, fmt.Errorf("invalid record length: expected %d, got %d", RecordLength, len(line))
+	fields, err := addendumFields(line, RecordTypeACHAddendum)
+	if err != nil {
+		return nil, err
 	}
 
-	recordCode := extractFieldByPosition(line, 1, 2)
-
-	var fields map[string]FieldDefinition
-	switch recordCode {
-	case "03":
-		fields = GetFieldDefinitions("03")
-	case "04":
-		fields = GetFieldDefinitions("04")
-	default:
-		return nil, fmt.Errorf("invalid addendum record code: %s", recordCode)
+	addendum := &ACHAddendum{
+		RecordCode:         extractField(line, fields["RecordCode"]),
+		PaymentID:          extractField(line, fields["PaymentID"]),
+		AddendaInformation: extractField(line, fields["AddendaInformation"]),
 	}
 
-	if fields == nil {
-		return nil, fmt.Errorf("no field definitions for addendum record %s", recordCode)
+	return addendum, nil
+}
+
+// ParseCTXAddendum parses a CTX ACH addendum record ("04")
+func (p *ACHParser) ParseCTXAddendum(line string) (*CTXAddendum, error) {
+	fields, err := addendumFields(line, RecordTypeACHAddendumCTX)
+	if err != nil {
+		return nil, err
 	}
 
-	addendum := &ACHAddendum{
+	addendum := &CTXAddendum{
 		RecordCode:         extractField(line, fields["RecordCode"]),
 		PaymentID:          extractField(line, fields["PaymentID"]),
 		AddendaInformation: extractField(line, fields["AddendaInformation"]),
@@ -125,6 +127,24 @@ func (p *ACHParser) ParseACHAddendum(line string) (*ACHAddendum, error) {
 	return addendum, nil
 }
 
+// addendumFields checks the record length and code and returns the addendum field definitions
+func addendumFields(line string, recordType RecordType) (map[string]FieldDefinition, error) {
+	if len(line) != RecordLength {
+		return nil, fmt.Errorf("invalid record length: expected %d, got %d", RecordLength, len(line))
+	}
+
+	recordCode := extractFieldByPosition(line, 1, 2)
+	if recordCode != string(recordType) {
+		return nil, fmt.Errorf("invalid addendum record code: expected %s, got %s", recordType, recordCode)
+	}
+
+	fields := GetFieldDefinitions(recordCode)
+	if fields == nil {
+		return nil, fmt.Errorf("no field definitions for addendum record %s", recordCode)
+	}
+	return fields, nil
+}
+
 // Helper function for extracting fields by position (legacy support)
 func extractFieldByPosition(line string, start, end int) string {
 	field := FieldDefinition{Start: start, End: end}
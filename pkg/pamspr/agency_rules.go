@@ -0,0 +1,182 @@
+package pamspr
+
+import (
+	"sort"
+	"sync"
+)
+
+// PaymentRule validates a single payment for a Custom Agency Rule ID; v is
+// the Validator running the rule, with the caller's configuration
+type PaymentRule func(v *Validator, payment Payment, ruleID string) error
+
+// ScheduleRule validates a schedule for a Custom Agency Rule ID
+type ScheduleRule func(v *Validator, schedule Schedule, ruleID string) error
+
+// FileRule validates a whole file for a Custom Agency Rule ID
+type FileRule func(v *Validator, file *File, ruleID string) error
+
+// AgencyRuleSet is implemented by anything that validates files for an agency.
+// Each method is passed the calling Validator and returns the first failure in
+// its scope, or nil.
+type AgencyRuleSet interface {
+	ValidatePayment(v *Validator, payment Payment, ruleID string) error
+	ValidateSchedule(v *Validator, schedule Schedule, ruleID string) error
+	ValidateFile(v *Validator, file *File, ruleID string) error
+}
+
+// RuleSet is an AgencyRuleSet built from lists of rules, run in order
+type RuleSet struct {
+	PaymentRules  []PaymentRule
+	ScheduleRules []ScheduleRule
+	FileRules     []FileRule
+}
+
+// ValidatePayment runs the payment rules
+func (s RuleSet) ValidatePayment(v *Validator, payment Payment, ruleID string) error {
+	for _, rule := range s.PaymentRules {
+		if err := rule(v, payment, ruleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSchedule runs the schedule rules
+func (s RuleSet) ValidateSchedule(v *Validator, schedule Schedule, ruleID string) error {
+	for _, rule := range s.ScheduleRules {
+		if err := rule(v, schedule, ruleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateFile runs the file rules
+func (s RuleSet) ValidateFile(v *Validator, file *File, ruleID string) error {
+	for _, rule := range s.FileRules {
+		if err := rule(v, file, ruleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RuleRegistry maps Custom Agency Rule IDs to the rule sets that enforce them
+type RuleRegistry struct {
+	mu   sync.RWMutex
+	sets map[string]AgencyRuleSet
+}
+
+// NewRuleRegistry creates an empty registry
+func NewRuleRegistry() *RuleRegistry {
+	return &RuleRegistry{
+		sets: make(map[string]AgencyRuleSet),
+	}
+}
+
+// DefaultRuleRegistry creates a registry with the built-in IRS, VA, SSA, RRB and CCC rules
+func DefaultRuleRegistry() *RuleRegistry {
+	registry := NewRuleRegistry()
+
+	registry.Register("IRS", builtinRuleSet((*Validator).validateIRSPayment))
+
+	va := builtinRuleSet((*Validator).validateVAPayment)
+	registry.Register("VA", va)
+	registry.Register("VACP", va)
+
+	ssa := builtinRuleSet((*Validator).validateSSAPayment)
+	registry.Register("SSA", ssa)
+	registry.Register("SSA-Daily", ssa)
+	registry.Register("SSA-A", ssa)
+
+	registry.Register("RRB", builtinRuleSet((*Validator).validateRRBPayment))
+	registry.Register("CCC", builtinRuleSet((*Validator).validateCCCPayment))
+
+	return registry
+}
+
+// Register sets the rule set for a Custom Agency Rule ID, replacing any existing one
+func (r *RuleRegistry) Register(ruleID string, set AgencyRuleSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sets[ruleID] = set
+}
+
+// Unregister removes the rule set for a Custom Agency Rule ID
+func (r *RuleRegistry) Unregister(ruleID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sets, ruleID)
+}
+
+// Lookup returns the rule set registered for a Custom Agency Rule ID
+func (r *RuleRegistry) Lookup(ruleID string) (AgencyRuleSet, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set, ok := r.sets[ruleID]
+	return set, ok
+}
+
+// RuleIDs returns the registered Custom Agency Rule IDs in sorted order
+func (r *RuleRegistry) RuleIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.sets))
+	for id := range r.sets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// builtinRuleSet adapts a built-in validator method to a payment-scoped rule
+// set. The methods read the rule ID from the validator, so they run on a copy
+// of the caller's with CustomAgencyRuleID set to ruleID.
+func builtinRuleSet(check func(v *Validator, payment Payment) error) RuleSet {
+	return RuleSet{
+		PaymentRules: []PaymentRule{
+			func(v *Validator, payment Payment, ruleID string) error {
+				var scoped Validator
+				if v != nil {
+					scoped = *v
+				}
+				scoped.CustomAgencyRuleID = ruleID
+				return check(&scoped, payment)
+			},
+		},
+	}
+}
+
+// builtinRules serves validators without a registry, such as Validator literals
+var builtinRules = DefaultRuleRegistry()
+
+// lookupRuleSet finds the rule set for ruleID in the validator's registry, or
+// among the built-in rules when it has none
+func (v *Validator) lookupRuleSet(ruleID string) (AgencyRuleSet, bool) {
+	if ruleID == "" {
+		return nil, false
+	}
+	if v.Rules == nil {
+		return builtinRules.Lookup(ruleID)
+	}
+	return v.Rules.Lookup(ruleID)
+}
+
+// ValidateAgencySchedule runs the schedule-scoped rules registered for customAgencyRuleID
+func (v *Validator) ValidateAgencySchedule(schedule Schedule, customAgencyRuleID string) error {
+	set, ok := v.lookupRuleSet(customAgencyRuleID)
+	if !ok {
+		return nil
+	}
+	return set.ValidateSchedule(v, schedule, customAgencyRuleID)
+}
+
+// ValidateAgencyFile runs the file-scoped rules registered for customAgencyRuleID
+func (v *Validator) ValidateAgencyFile(file *File, customAgencyRuleID string) error {
+	set, ok := v.lookupRuleSet(customAgencyRuleID)
+	if !ok {
+		return nil
+	}
+	return set.ValidateFile(v, file, customAgencyRuleID)
+}
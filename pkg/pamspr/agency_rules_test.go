@@ -0,0 +1,179 @@
+package pamspr
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRuleRegistry(t *testing.T) {
+	registry := DefaultRuleRegistry()
+
+	expected := []string{"CCC", "IRS", "RRB", "SSA", "SSA-A", "SSA-Daily", "VA", "VACP"}
+	if ids := registry.RuleIDs(); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected built-in rule IDs %v, got %v", expected, ids)
+	}
+
+	set, ok := registry.Lookup("IRS")
+	if !ok {
+		t.Fatal("Expected IRS rule set")
+	}
+	if err := set.ValidatePayment(NewValidator(), &ACHPayment{Reconcilement: "SHORT"}, "IRS"); err == nil {
+		t.Error("Expected IRS reconcilement length error")
+	}
+	if err := set.ValidatePayment(NewValidator(), &ACHPayment{Reconcilement: strings.Repeat("X", ReconcilementLength)}, "IRS"); err != nil {
+		t.Errorf("Unexpected IRS error: %v", err)
+	}
+}
+
+func TestAgencyRuleIDForwarded(t *testing.T) {
+	validator := NewValidator()
+
+	var seen []string
+	recordRuleID := RuleSet{
+		PaymentRules: []PaymentRule{
+			func(v *Validator, payment Payment, ruleID string) error {
+				seen = append(seen, ruleID)
+				return nil
+			},
+		},
+	}
+	validator.Rules.Register("DOL", recordRuleID)
+	validator.Rules.Register("DOL-A", recordRuleID)
+
+	for _, id := range []string{"DOL", "DOL-A"} {
+		if err := validator.ValidateAgencySpecific(&CheckPayment{}, id); err != nil {
+			t.Errorf("Unexpected error for %s: %v", id, err)
+		}
+	}
+
+	if !reflect.DeepEqual(seen, []string{"DOL", "DOL-A"}) {
+		t.Errorf("Expected rule IDs to be forwarded, got %v", seen)
+	}
+}
+
+func TestAgencyRulesReceiveValidator(t *testing.T) {
+	validator := NewValidator()
+	validator.ValidALCs["12345678"] = true
+
+	var seen []*Validator
+	validator.Rules.Register("DOL", RuleSet{
+		PaymentRules: []PaymentRule{
+			func(v *Validator, payment Payment, ruleID string) error {
+				seen = append(seen, v)
+				return nil
+			},
+		},
+		ScheduleRules: []ScheduleRule{
+			func(v *Validator, schedule Schedule, ruleID string) error {
+				seen = append(seen, v)
+				return nil
+			},
+		},
+		FileRules: []FileRule{
+			func(v *Validator, file *File, ruleID string) error {
+				seen = append(seen, v)
+				return nil
+			},
+		},
+	})
+
+	validator.CustomAgencyRuleID = "DOL"
+	validator.ValidateFile(createTestACHFile())
+
+	if len(seen) != 4 { // Two payments, the schedule and the file
+		t.Fatalf("Expected 4 rule calls, got %d", len(seen))
+	}
+	for _, v := range seen {
+		if v != validator || !v.ValidALCs["12345678"] {
+			t.Errorf("Expected the calling validator, got %p", v)
+		}
+	}
+}
+
+func TestRegisterCustomAgencyRules(t *testing.T) {
+	validator := NewValidator()
+
+	errMissingReference := errors.New("DOD payments require an agency account identifier")
+	validator.Rules.Register("DOD", RuleSet{
+		PaymentRules: []PaymentRule{
+			func(v *Validator, payment Payment, ruleID string) error {
+				if p, ok := payment.(*ACHPayment); ok && strings.TrimSpace(p.AgencyAccountIdentifier) == "" {
+					return errMissingReference
+				}
+				return nil
+			},
+		},
+		ScheduleRules: []ScheduleRule{
+			func(v *Validator, schedule Schedule, ruleID string) error {
+				if len(schedule.GetPayments()) > 1 {
+					return ValidationError{
+						Field:   "Payments",
+						Rule:    "dod_single_payment",
+						Message: "DOD schedules carry a single payment",
+						Code:    CodeInvalidRecord,
+						Scope:   RejectionSchedule,
+					}
+				}
+				return nil
+			},
+		},
+		FileRules: []FileRule{
+			func(v *Validator, file *File, ruleID string) error {
+				if file.Header.InputSystem != "DOD" {
+					return NewFieldFormatError("InputSystem", file.Header.InputSystem, "be DOD")
+				}
+				return nil
+			},
+		},
+	})
+
+	file := createTestACHFile()
+	schedule := file.Schedules[0].(*ACHSchedule)
+	schedule.Payments[0].(*ACHPayment).AgencyAccountIdentifier = ""
+
+	if err := validator.ValidateAgencySpecific(schedule.Payments[0], "DOD"); !errors.Is(err, errMissingReference) {
+		t.Errorf("Expected custom payment rule error, got %v", err)
+	}
+	if err := validator.ValidateAgencySchedule(schedule, "DOD"); err == nil {
+		t.Error("Expected custom schedule rule error")
+	}
+	if err := validator.ValidateAgencyFile(file, "DOD"); err == nil {
+		t.Error("Expected custom file rule error")
+	}
+
+	// ValidateFile runs every scope for the validator's rule ID
+	validator.CustomAgencyRuleID = "DOD"
+	report := validator.ValidateFile(file)
+
+	rules := make(map[string]bool)
+	for _, issue := range report.Issues {
+		rules[issue.Rule] = true
+		if issue.Err == errMissingReference && (issue.PaymentIndex != 0 || issue.LineNumber != 3) {
+			t.Errorf("Unexpected location for payment rule issue: %s", issue)
+		}
+	}
+	for _, rule := range []string{"dod_single_payment", "format"} {
+		if !rules[rule] {
+			t.Errorf("Expected %s issue in report", rule)
+		}
+	}
+
+	// Unknown rule IDs and unregistered agencies have no rules
+	validator.Rules.Unregister("DOD")
+	if err := validator.ValidateAgencySpecific(schedule.Payments[0], "DOD"); err != nil {
+		t.Errorf("Unexpected error after unregistering: %v", err)
+	}
+}
+
+func TestValidatorWithoutRegistry(t *testing.T) {
+	// A Validator literal still enforces the built-in agency rules
+	validator := &Validator{}
+	if err := validator.ValidateAgencySpecific(&ACHPayment{Reconcilement: "SHORT"}, "IRS"); err == nil {
+		t.Error("Expected the built-in IRS rules without a registry")
+	}
+	if err := validator.ValidateAgencySpecific(&ACHPayment{Reconcilement: "SHORT"}, "DOL"); err != nil {
+		t.Errorf("Expected no rules for an unregistered ID, got %v", err)
+	}
+}
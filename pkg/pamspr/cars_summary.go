@@ -0,0 +1,145 @@
+package pamspr
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CARSSummaryLine totals the CARS TAS/BETC records of one TAS and BETC
+type CARSSummaryLine struct {
+	// TAS is the component Treasury Account Symbol as returned by CARSTASBETC.TAS
+	TAS     string `json:"tas"`
+	BETC    string `json:"betc"`
+	Debits  int64  `json:"debits"`
+	Credits int64  `json:"credits"`
+	Net     int64  `json:"net"` // Debits less credits
+	Records int    `json:"records"`
+}
+
+// CARSSummary rolls CARS TAS/BETC amounts up by TAS and BETC, the totals
+// reconciled against the GTAS trial balance. Payments without CARS records
+// are counted as unclassified.
+type CARSSummary struct {
+	Lines   []CARSSummaryLine `json:"lines"` // Sorted by TAS, then BETC
+	Debits  int64             `json:"debits"`
+	Credits int64             `json:"credits"`
+	Net     int64             `json:"net"`
+	Records int               `json:"records"`
+	// Payments counts the payments with CARS records
+	Payments           int   `json:"payments"`
+	Unclassified       int   `json:"unclassified"`
+	UnclassifiedAmount int64 `json:"unclassifiedAmount"`
+}
+
+// NewCARSSummary returns an empty summary
+func NewCARSSummary() *CARSSummary {
+	return &CARSSummary{Lines: make([]CARSSummaryLine, 0)}
+}
+
+// SummarizeCARS rolls up the CARS TAS/BETC records of every payment in file
+func SummarizeCARS(file *File) *CARSSummary {
+	summary := NewCARSSummary()
+	if file == nil {
+		return summary
+	}
+	for _, schedule := range file.Schedules {
+		for _, payment := range schedule.GetPayments() {
+			summary.Add(payment)
+		}
+	}
+	return summary
+}
+
+// Add adds the CARS TAS/BETC records of payment to the summary
+func (s *CARSSummary) Add(payment Payment) {
+	records := carsRecords(payment)
+	if len(records) == 0 {
+		s.Unclassified++
+		s.UnclassifiedAmount += payment.GetAmount()
+		return
+	}
+
+	s.Payments++
+	for _, car := range records {
+		tas, betc := car.TAS(), car.BETC()
+		i := sort.Search(len(s.Lines), func(i int) bool {
+			line := s.Lines[i]
+			return line.TAS > tas || (line.TAS == tas && line.BETC >= betc)
+		})
+		if i == len(s.Lines) || s.Lines[i].TAS != tas || s.Lines[i].BETC != betc {
+			s.Lines = append(s.Lines, CARSSummaryLine{})
+			copy(s.Lines[i+1:], s.Lines[i:])
+			s.Lines[i] = CARSSummaryLine{TAS: tas, BETC: betc}
+		}
+
+		line := &s.Lines[i]
+		if car.IsCredit == carsCreditFlag {
+			line.Credits += car.AccountClassificationAmount
+			s.Credits += car.AccountClassificationAmount
+		} else {
+			line.Debits += car.AccountClassificationAmount
+			s.Debits += car.AccountClassificationAmount
+		}
+		line.Net += car.SignedAmount()
+		line.Records++
+		s.Net += car.SignedAmount()
+		s.Records++
+	}
+}
+
+// WriteText writes one line per TAS and BETC followed by the totals
+func (s *CARSSummary) WriteText(w io.Writer) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%-24s  %-8s  %16s  %16s  %16s  %7s\n", "TAS", "BETC", "DEBITS", "CREDITS", "NET", "RECORDS")
+	for _, line := range s.Lines {
+		fmt.Fprintf(&sb, "%-24s  %-8s  %16s  %16s  %16s  %7d\n",
+			line.TAS, line.BETC, FormatCents(line.Debits), FormatCents(line.Credits), FormatCents(line.Net), line.Records)
+	}
+	fmt.Fprintf(&sb, "%-24s  %-8s  %16s  %16s  %16s  %7d\n",
+		"TOTAL", "", FormatCents(s.Debits), FormatCents(s.Credits), FormatCents(s.Net), s.Records)
+
+	fmt.Fprintf(&sb, "%d payment(s) classified", s.Payments)
+	if s.Unclassified > 0 {
+		fmt.Fprintf(&sb, ", %d payment(s) without CARS records (%s)", s.Unclassified, FormatCents(s.UnclassifiedAmount))
+	}
+	sb.WriteString("\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the summary as indented JSON
+func (s *CARSSummary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteCSV writes a header row and one row per TAS and BETC, amounts in cents
+func (s *CARSSummary) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"tas", "betc", "debits", "credits", "net", "records"}); err != nil {
+		return err
+	}
+	for _, line := range s.Lines {
+		row := []string{
+			line.TAS,
+			line.BETC,
+			strconv.FormatInt(line.Debits, 10),
+			strconv.FormatInt(line.Credits, 10),
+			strconv.FormatInt(line.Net, 10),
+			strconv.Itoa(line.Records),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,96 @@
+package pamspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeCARS(t *testing.T) {
+	file := createTestMixedFile()
+
+	ach := file.Schedules[0].(*ACHSchedule).Payments
+	first := ach[0].(*ACHPayment)
+	first.CARSTASBETC = []*CARSTASBETC{
+		testCARS(first.PaymentID, 90000, false),
+		testCARS(first.PaymentID, 15000, false),
+		testCARS(first.PaymentID, 5000, true),
+	}
+	first.CARSTASBETC[1].BusinessEventTypeCode = "COLL"
+
+	check := file.Schedules[1].(*CheckSchedule).Payments[0].(*CheckPayment)
+	check.CARSTASBETC = []*CARSTASBETC{testCARS(check.PaymentID, check.Amount, false)}
+	check.CARSTASBETC[0].AgencyIdentifier = "012"
+
+	summary := SummarizeCARS(file)
+
+	want := []CARSSummaryLine{
+		{TAS: "     01220262026 1801   ", BETC: "DISB", Debits: 150000, Net: 150000, Records: 1},
+		{TAS: "     02020262026 1801   ", BETC: "COLL", Debits: 15000, Net: 15000, Records: 1},
+		{TAS: "     02020262026 1801   ", BETC: "DISB", Debits: 90000, Credits: 5000, Net: 85000, Records: 2},
+	}
+	if len(summary.Lines) != len(want) {
+		t.Fatalf("Expected %d lines, got %+v", len(want), summary.Lines)
+	}
+	for i := range want {
+		if summary.Lines[i] != want[i] {
+			t.Errorf("Line %d = %+v, want %+v", i, summary.Lines[i], want[i])
+		}
+	}
+
+	if summary.Debits != 255000 || summary.Credits != 5000 || summary.Net != 250000 || summary.Records != 4 {
+		t.Errorf("Unexpected totals: debits %d, credits %d, net %d, records %d",
+			summary.Debits, summary.Credits, summary.Net, summary.Records)
+	}
+	if summary.Payments != 2 || summary.Unclassified != 1 || summary.UnclassifiedAmount != 200000 {
+		t.Errorf("Expected 2 classified and 1 unclassified payment of 200000, got %d, %d, %d",
+			summary.Payments, summary.Unclassified, summary.UnclassifiedAmount)
+	}
+
+	var text bytes.Buffer
+	if err := summary.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	for _, s := range []string{"02020262026 1801     DISB", "TOTAL", "2500.00", "1 payment(s) without CARS records (2000.00)"} {
+		if !strings.Contains(text.String(), s) {
+			t.Errorf("Expected %q in text summary:\n%s", s, text.String())
+		}
+	}
+
+	var csv bytes.Buffer
+	if err := summary.WriteCSV(&csv); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	rows := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(rows) != 4 || rows[0] != "tas,betc,debits,credits,net,records" || rows[3] != `"     02020262026 1801   ",DISB,90000,5000,85000,2` {
+		t.Errorf("Unexpected CSV summary:\n%s", csv.String())
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded CARSSummary
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON summary: %v", err)
+	}
+	if len(decoded.Lines) != 3 || decoded.Net != summary.Net {
+		t.Errorf("JSON summary does not match: %+v", decoded)
+	}
+}
+
+func TestSummarizeCARSEmpty(t *testing.T) {
+	summary := SummarizeCARS(nil)
+	if len(summary.Lines) != 0 || summary.Records != 0 {
+		t.Errorf("Expected an empty summary, got %+v", summary)
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"lines": []`) {
+		t.Errorf("Expected an empty lines array, got %s", buf.String())
+	}
+}
@@ -83,6 +83,21 @@ var ValidACHTransactionCodes = map[string]bool{
 	"53": true, // Pre-note for credit to loan account
 }
 
+// PrenoteTransactionCodes are the ACH transaction codes that indicate a prenote
+var PrenoteTransactionCodes = map[string]bool{
+	"23": true,
+	"33": true,
+	"43": true,
+	"53": true,
+}
+
+// ZeroDollarCreditTransactionCodes are the ACH transaction codes for zero
+// dollar credits with remittance
+var ZeroDollarCreditTransactionCodes = map[string]bool{
+	"24": true,
+	"34": true,
+}
+
 // Valid Payment Types per SPR specification
 var ValidPaymentTypes = map[string]bool{
 	"Allotment":       true,
@@ -122,13 +137,21 @@ var ValidTOPOffsetIndicators = map[string]bool{
 	"1": true, // Subject to offset
 }
 
+// Check Payment Enclosure Codes
+const (
+	CheckEnclosureNameOnly = "nameonly" // Name only
+	CheckEnclosureLetter   = "letter"   // Letter, sent in a separate letter file
+	CheckEnclosureStub     = "stub"     // Stub, one "13" record per payment
+	CheckEnclosureInsert   = "insert"   // Insert
+)
+
 // Valid Check Payment Enclosure Codes
 var ValidCheckEnclosureCodes = map[string]bool{
-	"nameonly": true, // Name only
-	"letter":   true, // Letter
-	"stub":     true, // Stub
-	"insert":   true, // Insert
-	"":         true, // Blank (no enclosure)
+	CheckEnclosureNameOnly: true,
+	CheckEnclosureLetter:   true,
+	CheckEnclosureStub:     true,
+	CheckEnclosureInsert:   true,
+	"":                     true, // Blank (no enclosure)
 }
 
 // Same Day ACH Processing Time Windows (in hours)
@@ -170,7 +193,7 @@ const (
 // Routing Number Validation Constants
 const (
 	RoutingNumberModulus = 10   // Check digit modulus for routing number validation
-	MinHexCharacter      = 0x40 // Minimum valid hex character value
+	MinHexCharacter      = 0x40 // Minimum valid EBCDIC hex character value
 )
 
 // Payment Identification Constants
@@ -188,3 +211,10 @@ const (
 	CTXEDISegmentIdentifier = "ISA" // EDI segment identifier for CTX addenda
 	CTXEDISegmentLength     = 3     // Length of EDI segment identifier
 )
+
+// ACH Addendum limits per payment (section 1.2)
+const (
+	MaxPPDCCDAddenda = 1   // "03" records for a PPD or CCD payment
+	MaxIATAddenda    = 2   // "03" records for an IAT payment
+	MaxCTXAddenda    = 999 // "04" records for a CTX payment
+)
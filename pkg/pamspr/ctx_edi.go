@@ -0,0 +1,520 @@
+package pamspr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CTX addenda carry an ASC X12 820 remittance split across "04" records
+const (
+	CTXAddendaInformationLength = 800 // Addenda Information characters per "04" record
+	ctxISASegmentLength         = 106 // ISA segment length including the segment terminator
+)
+
+// Default X12 delimiters used when building a CTX remittance
+const (
+	DefaultEDIElementSeparator   = '*'
+	DefaultEDISegmentTerminator  = '~'
+	DefaultEDIComponentSeparator = '>'
+)
+
+var ediNumericPattern = regexp.MustCompile(`^-?(\d+\.?\d*|\.\d+)$`)
+
+// EDISegment is a single X12 segment: an identifier followed by its data elements
+type EDISegment struct {
+	ID       string
+	Elements []string
+}
+
+// Element returns the nth data element (1-based, as in BPR02), or "" when absent
+func (s EDISegment) Element(n int) string {
+	if n < 1 || n > len(s.Elements) {
+		return ""
+	}
+	return s.Elements[n-1]
+}
+
+// format joins the segment with the element separator, without a terminator
+func (s EDISegment) format(elementSeparator byte) string {
+	return strings.Join(append([]string{s.ID}, s.Elements...), string(elementSeparator))
+}
+
+// RemittanceLine is one RMR remittance advice line of a CTX 820
+type RemittanceLine struct {
+	ReferenceQualifier string // RMR01, e.g. "IV" for invoice number
+	ReferenceNumber    string // RMR02
+	PaymentActionCode  string // RMR03, e.g. "PO" for payment on account
+	AmountPaid         int64  // RMR04 in cents
+	InvoiceAmount      int64  // RMR05 in cents, omitted when zero
+	DiscountAmount     int64  // RMR06 in cents, omitted when zero
+}
+
+// CTXRemittance is the X12 820 envelope and remittance carried by a CTX payment.
+// Building uses the typed fields; parsing also fills Segments with every segment
+// in order, including ones without a typed field (N1, REF, DTM, ...).
+type CTXRemittance struct {
+	SenderID                    string    // ISA06 and GS02, max 15 chars
+	ReceiverID                  string    // ISA08 and GS03, max 15 chars
+	Date                        time.Time // ISA09/ISA10 and GS04/GS05
+	InterchangeControlNumber    int       // ISA13 and IEA02
+	GroupControlNumber          int       // GS06 and GE02
+	TransactionSetControlNumber int       // ST02 and SE02
+	UsageIndicator              string    // ISA15, "P" production or "T" test
+
+	Amount int64 // BPR02 in cents; when building, zero means the sum of the lines
+	Lines  []RemittanceLine
+
+	Segments []EDISegment
+
+	ElementSeparator   byte // ISA position 4
+	ComponentSeparator byte // ISA16
+	SegmentTerminator  byte // ISA position 106
+}
+
+// ctxError builds a Reason Group 5 Message 3 validation error for the addenda
+func ctxError(rule, value, format string, args ...interface{}) error {
+	return ValidationError{
+		Field:   "Addenda",
+		Value:   value,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+		Code:    CodeInvalidPaymentData,
+	}
+}
+
+// ParseCTXAddenda joins the Addenda Information of "04" records and splits it
+// into X12 segments, using the delimiters declared by the ISA segment
+func ParseCTXAddenda(addenda []*CTXAddendum) (*CTXRemittance, error) {
+	var data strings.Builder
+	data.Grow(len(addenda) * CTXAddendaInformationLength)
+	for _, addendum := range addenda {
+		if addendum.RecordCode != string(RecordTypeACHAddendumCTX) {
+			continue
+		}
+		data.WriteString(addendum.AddendaInformation)
+	}
+	return ParseCTXRemittance(data.String())
+}
+
+// ParseCTXRemittance parses an X12 820 interchange into a CTXRemittance
+func ParseCTXRemittance(data string) (*CTXRemittance, error) {
+	if !strings.HasPrefix(data, CTXEDISegmentIdentifier) {
+		return nil, ctxError("ctx_isa_required", firstN(data, CTXEDISegmentLength), "CTX addenda must start with an ISA segment")
+	}
+	if len(data) < ctxISASegmentLength {
+		return nil, ctxError("ctx_isa_length", "", "ISA segment must be %d characters, got %d", ctxISASegmentLength, len(data))
+	}
+
+	r := &CTXRemittance{
+		ElementSeparator:   data[CTXEDISegmentLength],
+		ComponentSeparator: data[ctxISASegmentLength-2],
+		SegmentTerminator:  data[ctxISASegmentLength-1],
+	}
+	if err := checkEDIDelimiters(r.ElementSeparator, r.SegmentTerminator); err != nil {
+		return nil, err
+	}
+
+	pieces := strings.Split(data, string(r.SegmentTerminator))
+	for i, piece := range pieces {
+		piece = strings.TrimLeft(piece, " \r\n")
+		if piece == "" {
+			continue
+		}
+		if i == len(pieces)-1 {
+			return nil, ctxError("ctx_segment_terminator", firstN(piece, 20), "segment %q is not terminated", firstN(piece, 20))
+		}
+		parts := strings.Split(piece, string(r.ElementSeparator))
+		r.Segments = append(r.Segments, EDISegment{ID: parts[0], Elements: parts[1:]})
+	}
+
+	if err := r.populate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// populate fills the typed fields from the first envelope, BPR and RMR segments
+func (r *CTXRemittance) populate() error {
+	seen := make(map[string]bool)
+	for _, seg := range r.Segments {
+		first := !seen[seg.ID]
+		seen[seg.ID] = true
+
+		var err error
+		switch {
+		case seg.ID == "ISA" && first:
+			r.SenderID = strings.TrimSpace(seg.Element(6))
+			r.ReceiverID = strings.TrimSpace(seg.Element(8))
+			r.UsageIndicator = seg.Element(15)
+			r.Date, _ = time.Parse("0601021504", seg.Element(9)+seg.Element(10))
+			r.InterchangeControlNumber, err = parseEDIControlNumber("ISA13", seg.Element(13))
+		case seg.ID == "GS" && first:
+			r.GroupControlNumber, err = parseEDIControlNumber("GS06", seg.Element(6))
+		case seg.ID == "ST" && first:
+			r.TransactionSetControlNumber, err = parseEDIControlNumber("ST02", seg.Element(2))
+		case seg.ID == "BPR" && first:
+			r.Amount, err = parseEDIAmount("BPR02", seg.Element(2))
+		case seg.ID == "RMR":
+			var line RemittanceLine
+			line, err = parseRemittanceLine(seg)
+			r.Lines = append(r.Lines, line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the envelope structure, segment counts and control numbers
+func (r *CTXRemittance) Validate() error {
+	if len(r.Segments) == 0 || r.Segments[0].ID != "ISA" {
+		return ctxError("ctx_isa_required", "", "first CTX segment must be ISA")
+	}
+	if err := checkEDIDelimiters(r.ElementSeparator, r.SegmentTerminator); err != nil {
+		return err
+	}
+
+	// The specification requires ISA, BPR and SE; a single BPR is expected per payment
+	var bprCount, seCount int
+	for _, seg := range r.Segments {
+		switch seg.ID {
+		case "BPR":
+			bprCount++
+		case "SE":
+			seCount++
+		}
+	}
+	if bprCount == 0 {
+		return ctxError("ctx_bpr_required", "", "CTX addenda must contain a BPR segment")
+	}
+	if bprCount > 1 {
+		return ctxError("ctx_bpr_count", strconv.Itoa(bprCount), "CTX addenda must contain one BPR segment, got %d", bprCount)
+	}
+	if seCount == 0 {
+		return ctxError("ctx_se_required", "", "CTX addenda must contain an SE segment")
+	}
+
+	var (
+		isa, gs, st               *EDISegment
+		groups, sets, setSegments int
+	)
+
+	for i := range r.Segments {
+		seg := &r.Segments[i]
+		if st != nil {
+			setSegments++
+		}
+
+		switch seg.ID {
+		case "ISA":
+			if isa != nil {
+				return ctxError("ctx_envelope", "", "ISA segment %d starts before IEA closes the interchange", i+1)
+			}
+			if len(seg.Elements) != 16 {
+				return ctxError("ctx_isa_elements", strconv.Itoa(len(seg.Elements)), "ISA segment must have 16 elements, got %d", len(seg.Elements))
+			}
+			isa, groups = seg, 0
+		case "GS":
+			if isa == nil || gs != nil {
+				return ctxError("ctx_envelope", "", "GS segment %d is outside an interchange or nested in a group", i+1)
+			}
+			gs, sets = seg, 0
+		case "ST":
+			if gs == nil || st != nil {
+				return ctxError("ctx_envelope", "", "ST segment %d is outside a group or nested in a transaction set", i+1)
+			}
+			st, setSegments = seg, 1
+		case "BPR":
+			if !ediNumericPattern.MatchString(seg.Element(2)) {
+				return ctxError("ctx_bpr_amount", seg.Element(2), "BPR02 must be numeric")
+			}
+		case "SE":
+			if st == nil {
+				return ctxError("ctx_envelope", "", "SE segment %d has no matching ST", i+1)
+			}
+			count, err := strconv.Atoi(seg.Element(1))
+			if err != nil {
+				return ctxError("ctx_se_count", seg.Element(1), "SE01 must be numeric")
+			}
+			if count != setSegments {
+				return ctxError("ctx_se_count", seg.Element(1), "SE01 segment count %d does not match %d segments from ST to SE", count, setSegments)
+			}
+			if seg.Element(2) != st.Element(2) {
+				return ctxError("ctx_control_number", seg.Element(2), "SE02 control number %q does not match ST02 %q", seg.Element(2), st.Element(2))
+			}
+			st = nil
+			sets++
+		case "GE":
+			if gs == nil || st != nil {
+				return ctxError("ctx_envelope", "", "GE segment %d has no matching GS or closes an open transaction set", i+1)
+			}
+			if seg.Element(1) != strconv.Itoa(sets) {
+				return ctxError("ctx_ge_count", seg.Element(1), "GE01 transaction set count %s does not match %d", seg.Element(1), sets)
+			}
+			if seg.Element(2) != gs.Element(6) {
+				return ctxError("ctx_control_number", seg.Element(2), "GE02 control number %q does not match GS06 %q", seg.Element(2), gs.Element(6))
+			}
+			gs = nil
+			groups++
+		case "IEA":
+			if isa == nil || gs != nil {
+				return ctxError("ctx_envelope", "", "IEA segment %d has no matching ISA or closes an open group", i+1)
+			}
+			if seg.Element(1) != strconv.Itoa(groups) {
+				return ctxError("ctx_iea_count", seg.Element(1), "IEA01 group count %s does not match %d", seg.Element(1), groups)
+			}
+			if seg.Element(2) != isa.Element(13) {
+				return ctxError("ctx_control_number", seg.Element(2), "IEA02 control number %q does not match ISA13 %q", seg.Element(2), isa.Element(13))
+			}
+			isa = nil
+		}
+	}
+
+	if st != nil || gs != nil || isa != nil {
+		return ctxError("ctx_envelope", "", "CTX addenda end before the ST, GS or ISA envelope is closed")
+	}
+	return nil
+}
+
+// BuildSegments returns the ISA/GS/ST/BPR/RMR/SE/GE/IEA segments for the typed fields
+func (r *CTXRemittance) BuildSegments() ([]EDISegment, error) {
+	if len(r.SenderID) > 15 || len(r.ReceiverID) > 15 {
+		return nil, fmt.Errorf("CTX sender and receiver IDs are limited to 15 characters")
+	}
+	if r.InterchangeControlNumber < 0 || r.InterchangeControlNumber > 999999999 {
+		return nil, fmt.Errorf("interchange control number must be 0-999999999, got %d", r.InterchangeControlNumber)
+	}
+	if r.GroupControlNumber < 0 || r.GroupControlNumber > 999999999 {
+		return nil, fmt.Errorf("group control number must be 0-999999999, got %d", r.GroupControlNumber)
+	}
+	if r.TransactionSetControlNumber < 0 || r.TransactionSetControlNumber > 999999999 {
+		return nil, fmt.Errorf("transaction set control number must be 0-999999999, got %d", r.TransactionSetControlNumber)
+	}
+
+	date := r.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	usage := r.UsageIndicator
+	if usage == "" {
+		usage = "P"
+	}
+	amount := r.Amount
+	if amount == 0 {
+		for _, line := range r.Lines {
+			amount += line.AmountPaid
+		}
+	}
+	isaControl := fmt.Sprintf("%09d", r.InterchangeControlNumber)
+	groupControl := strconv.Itoa(r.GroupControlNumber)
+	setControl := fmt.Sprintf("%04d", r.TransactionSetControlNumber)
+
+	segments := []EDISegment{
+		{ID: "ISA", Elements: []string{
+			"00", PadRight("", 10, ' '), "00", PadRight("", 10, ' '),
+			"ZZ", PadRight(r.SenderID, 15, ' '), "ZZ", PadRight(r.ReceiverID, 15, ' '),
+			date.Format("060102"), date.Format("1504"), "U", "00401", isaControl, "0", usage,
+			string(r.componentSeparator()),
+		}},
+		{ID: "GS", Elements: []string{"RA", r.SenderID, r.ReceiverID, date.Format("20060102"), date.Format("1504"), groupControl, "X", "004010"}},
+		{ID: "ST", Elements: []string{"820", setControl}},
+		{ID: "BPR", Elements: []string{"C", formatEDIAmount(amount), "C", "ACH", "CTX"}},
+	}
+	for _, line := range r.Lines {
+		elements := []string{line.ReferenceQualifier, line.ReferenceNumber, line.PaymentActionCode, formatEDIAmount(line.AmountPaid)}
+		if line.InvoiceAmount != 0 || line.DiscountAmount != 0 {
+			elements = append(elements, formatEDIAmount(line.InvoiceAmount))
+		}
+		if line.DiscountAmount != 0 {
+			elements = append(elements, formatEDIAmount(line.DiscountAmount))
+		}
+		segments = append(segments, EDISegment{ID: "RMR", Elements: elements})
+	}
+	// SE01 counts every segment from ST through SE
+	segments = append(segments,
+		EDISegment{ID: "SE", Elements: []string{strconv.Itoa(len(segments) - 1), setControl}},
+		EDISegment{ID: "GE", Elements: []string{"1", groupControl}},
+		EDISegment{ID: "IEA", Elements: []string{"1", isaControl}},
+	)
+	return segments, nil
+}
+
+// EDI returns the X12 820 interchange for the typed fields
+func (r *CTXRemittance) EDI() (string, error) {
+	element, terminator := r.elementSeparator(), r.segmentTerminator()
+	if err := checkEDIDelimiters(element, terminator); err != nil {
+		return "", err
+	}
+
+	segments, err := r.BuildSegments()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, seg := range segments {
+		for _, value := range seg.Elements {
+			if strings.IndexByte(value, element) >= 0 || strings.IndexByte(value, terminator) >= 0 {
+				return "", fmt.Errorf("%s element %q contains an EDI delimiter", seg.ID, value)
+			}
+		}
+		sb.WriteString(seg.format(element))
+		sb.WriteByte(terminator)
+	}
+	return sb.String(), nil
+}
+
+// Addenda builds the "04" addendum records for a payment, splitting the
+// interchange into 800 character Addenda Information blocks
+func (r *CTXRemittance) Addenda(paymentID string) ([]*CTXAddendum, error) {
+	edi, err := r.EDI()
+	if err != nil {
+		return nil, err
+	}
+
+	count := (len(edi) + CTXAddendaInformationLength - 1) / CTXAddendaInformationLength
+	if count > MaxCTXAddenda {
+		return nil, fmt.Errorf("CTX remittance needs %d addenda records, maximum is %d", count, MaxCTXAddenda)
+	}
+
+	addenda := make([]*CTXAddendum, 0, count)
+	for start := 0; start < len(edi); start += CTXAddendaInformationLength {
+		end := start + CTXAddendaInformationLength
+		if end > len(edi) {
+			end = len(edi)
+		}
+		addenda = append(addenda, &CTXAddendum{
+			RecordCode:         string(RecordTypeACHAddendumCTX),
+			PaymentID:          paymentID,
+			AddendaInformation: edi[start:end],
+		})
+	}
+	return addenda, nil
+}
+
+// EncodeCTXRemittance replaces the payment's CTX addenda with the remittance's
+// "04" records and marks the payment as CTX
+func (p *ACHPayment) EncodeCTXRemittance(r *CTXRemittance) error {
+	addenda, err := r.Addenda(p.PaymentID)
+	if err != nil {
+		return err
+	}
+	p.StandardEntryClassCode = string(SECCodeCTX)
+	p.CTXAddenda = addenda
+	return nil
+}
+
+// DecodeCTXRemittance parses and validates the payment's "04" addenda
+func (p *ACHPayment) DecodeCTXRemittance() (*CTXRemittance, error) {
+	r, err := ParseCTXAddenda(p.CTXAddenda)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Helper functions
+
+func (r *CTXRemittance) elementSeparator() byte {
+	if r.ElementSeparator == 0 {
+		return DefaultEDIElementSeparator
+	}
+	return r.ElementSeparator
+}
+
+func (r *CTXRemittance) segmentTerminator() byte {
+	if r.SegmentTerminator == 0 {
+		return DefaultEDISegmentTerminator
+	}
+	return r.SegmentTerminator
+}
+
+func (r *CTXRemittance) componentSeparator() byte {
+	if r.ComponentSeparator == 0 {
+		return DefaultEDIComponentSeparator
+	}
+	return r.ComponentSeparator
+}
+
+// checkEDIDelimiters rejects delimiters that are equal, data characters or control characters
+func checkEDIDelimiters(element, terminator byte) error {
+	if element == terminator {
+		return ctxError("ctx_delimiters", string(element), "element separator and segment terminator must differ")
+	}
+	for _, d := range []byte{element, terminator} {
+		r := rune(d)
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ' || (unicode.IsControl(r) && r != '\n' && r != '\r') {
+			return ctxError("ctx_delimiters", string(d), "invalid EDI delimiter 0x%02X", d)
+		}
+	}
+	return nil
+}
+
+// parseRemittanceLine reads the typed fields of an RMR segment
+func parseRemittanceLine(seg EDISegment) (RemittanceLine, error) {
+	line := RemittanceLine{
+		ReferenceQualifier: seg.Element(1),
+		ReferenceNumber:    seg.Element(2),
+		PaymentActionCode:  seg.Element(3),
+	}
+	var err error
+	if line.AmountPaid, err = parseEDIAmount("RMR04", seg.Element(4)); err != nil {
+		return line, err
+	}
+	if line.InvoiceAmount, err = parseEDIAmount("RMR05", seg.Element(5)); err != nil {
+		return line, err
+	}
+	line.DiscountAmount, err = parseEDIAmount("RMR06", seg.Element(6))
+	return line, err
+}
+
+// parseEDIAmount converts an X12 decimal (R) element to cents; blank is zero
+func parseEDIAmount(element, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if !ediNumericPattern.MatchString(value) {
+		return 0, ctxError("ctx_amount", value, "%s must be numeric", element)
+	}
+	cents, err := ParseAmount(strings.TrimPrefix(value, "-"))
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasPrefix(value, "-") {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// formatEDIAmount converts cents to an X12 decimal element
+func formatEDIAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// parseEDIControlNumber parses a numeric control number element
+func parseEDIControlNumber(element, value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, ctxError("ctx_control_number", value, "%s control number must be numeric", element)
+	}
+	return n, nil
+}
+
+func firstN(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
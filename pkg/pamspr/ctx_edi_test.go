@@ -0,0 +1,238 @@
+package pamspr
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleCTXEDI is a minimal X12 820 with one remittance line
+const sampleCTXEDI = "ISA*00*          *00*          *ZZ*123456789      *ZZ*987654321      *240101*1200*U*00401*000000001*0*P*>~" +
+	"GS*RA*123456789*987654321*20240101*1200*1*X*004010~" +
+	"ST*820*0001~" +
+	"BPR*C*100.00*C*ACH*CTX~" +
+	"RMR*IV*INV001*PO*100.00~" +
+	"SE*4*0001~" +
+	"GE*1*1~" +
+	"IEA*1*000000001~"
+
+func testCTXRemittance(lines int) *CTXRemittance {
+	r := &CTXRemittance{
+		SenderID:                    "123456789",
+		ReceiverID:                  "987654321",
+		Date:                        time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		InterchangeControlNumber:    1,
+		GroupControlNumber:          1,
+		TransactionSetControlNumber: 1,
+	}
+	for i := 0; i < lines; i++ {
+		r.Lines = append(r.Lines, RemittanceLine{
+			ReferenceQualifier: "IV",
+			ReferenceNumber:    fmt.Sprintf("INV%03d", i+1),
+			PaymentActionCode:  "PO",
+			AmountPaid:         10000,
+		})
+	}
+	return r
+}
+
+func TestCTXRemittanceBuild(t *testing.T) {
+	edi, err := testCTXRemittance(1).EDI()
+	if err != nil {
+		t.Fatalf("EDI failed: %v", err)
+	}
+	if edi != sampleCTXEDI {
+		t.Errorf("Unexpected EDI:\n got %q\nwant %q", edi, sampleCTXEDI)
+	}
+	if edi[ctxISASegmentLength-1] != DefaultEDISegmentTerminator {
+		t.Errorf("Expected segment terminator at ISA position %d", ctxISASegmentLength)
+	}
+}
+
+func TestCTXRemittanceRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   int
+		records int
+	}{
+		{"Single record", 1, 1},
+		{"Split across records", 40, 2},
+		{"Many records", 400, 13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := testCTXRemittance(tt.lines)
+			payment := &ACHPayment{PaymentID: "PAY001", Amount: int64(tt.lines) * 10000}
+			if err := payment.EncodeCTXRemittance(original); err != nil {
+				t.Fatalf("EncodeCTXRemittance failed: %v", err)
+			}
+			if len(payment.CTXAddenda) != tt.records {
+				t.Fatalf("Expected %d addenda records, got %d", tt.records, len(payment.CTXAddenda))
+			}
+			for i, addendum := range payment.CTXAddenda {
+				if addendum.RecordCode != "04" || addendum.PaymentID != "PAY001" {
+					t.Errorf("Addenda[%d] has record code %q and payment ID %q", i, addendum.RecordCode, addendum.PaymentID)
+				}
+				if i < len(payment.CTXAddenda)-1 && len(addendum.AddendaInformation) != CTXAddendaInformationLength {
+					t.Errorf("Addenda[%d] should be full, got %d characters", i, len(addendum.AddendaInformation))
+				}
+			}
+			if payment.StandardEntryClassCode != "CTX" {
+				t.Errorf("Expected SEC code CTX, got %q", payment.StandardEntryClassCode)
+			}
+			if err := NewValidator().ValidateCTXAddendum(payment); err != nil {
+				t.Errorf("Built addenda failed validation: %v", err)
+			}
+
+			// Write and read back so the records pick up their padding
+			file := createTestACHFile()
+			schedule := file.Schedules[0].(*ACHSchedule)
+			payment.PaymentID = schedule.Payments[0].GetPaymentID()
+			for _, addendum := range payment.CTXAddenda {
+				addendum.PaymentID = payment.PaymentID
+			}
+			schedule.Payments[0].(*ACHPayment).CTXAddenda = payment.CTXAddenda
+
+			var buf bytes.Buffer
+			if err := NewWriter(&buf).Write(file); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			readFile, err := NewReader(&buf).Read()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			readPayment := readFile.Schedules[0].(*ACHSchedule).Payments[0].(*ACHPayment)
+
+			decoded, err := readPayment.DecodeCTXRemittance()
+			if err != nil {
+				t.Fatalf("DecodeCTXRemittance failed: %v", err)
+			}
+			if decoded.SenderID != original.SenderID || decoded.ReceiverID != original.ReceiverID ||
+				!decoded.Date.Equal(original.Date) || decoded.InterchangeControlNumber != 1 ||
+				decoded.GroupControlNumber != 1 || decoded.TransactionSetControlNumber != 1 {
+				t.Errorf("Envelope mismatch: %+v", decoded)
+			}
+			if decoded.Amount != int64(tt.lines)*10000 {
+				t.Errorf("Expected BPR amount %d, got %d", int64(tt.lines)*10000, decoded.Amount)
+			}
+			if !reflect.DeepEqual(decoded.Lines, original.Lines) {
+				t.Errorf("Remittance lines mismatch")
+			}
+			if got := len(decoded.Segments); got != tt.lines+7 {
+				t.Errorf("Expected %d segments, got %d", tt.lines+7, got)
+			}
+		})
+	}
+}
+
+func TestParseCTXRemittanceLines(t *testing.T) {
+	edi := strings.Replace(sampleCTXEDI, "RMR*IV*INV001*PO*100.00~", "RMR*IV*INV001*PO*100.00*120.00*-20.00~", 1)
+	r, err := ParseCTXRemittance(edi)
+	if err != nil {
+		t.Fatalf("ParseCTXRemittance failed: %v", err)
+	}
+	expected := []RemittanceLine{{ReferenceQualifier: "IV", ReferenceNumber: "INV001", PaymentActionCode: "PO", AmountPaid: 10000, InvoiceAmount: 12000, DiscountAmount: -2000}}
+	if !reflect.DeepEqual(r.Lines, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, r.Lines)
+	}
+	if bpr := r.Segments[3]; bpr.ID != "BPR" || bpr.Element(2) != "100.00" || bpr.Element(9) != "" {
+		t.Errorf("Unexpected BPR segment: %+v", bpr)
+	}
+
+	// Delimiters come from the ISA segment
+	custom := strings.NewReplacer("*", "|", "~", "\n").Replace(sampleCTXEDI)
+	r, err = ParseCTXRemittance(custom)
+	if err != nil {
+		t.Fatalf("ParseCTXRemittance with custom delimiters failed: %v", err)
+	}
+	if r.ElementSeparator != '|' || r.SegmentTerminator != '\n' || len(r.Segments) != 8 {
+		t.Errorf("Unexpected parse with custom delimiters: %q %q %d segments", r.ElementSeparator, r.SegmentTerminator, len(r.Segments))
+	}
+	if err := r.Validate(); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+}
+
+func TestCTXRemittanceValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edi  string
+		rule string
+	}{
+		{"No ISA", "GS*RA~", "ctx_isa_required"},
+		{"Short ISA", "ISA*00*~", "ctx_isa_length"},
+		{"Same delimiters", strings.Replace(sampleCTXEDI, ">~GS", ">*GS", 1), "ctx_delimiters"},
+		{"Unterminated segment", strings.TrimSuffix(sampleCTXEDI, "~"), "ctx_segment_terminator"},
+		{"Missing BPR", strings.Replace(strings.Replace(sampleCTXEDI, "BPR*C*100.00*C*ACH*CTX~", "", 1), "SE*4", "SE*3", 1), "ctx_bpr_required"},
+		{"Two BPR", strings.Replace(strings.Replace(sampleCTXEDI, "RMR*", "BPR*C*1*C*ACH*CTX~RMR*", 1), "SE*4", "SE*5", 1), "ctx_bpr_count"},
+		{"Non-numeric BPR02", strings.Replace(sampleCTXEDI, "BPR*C*100.00", "BPR*C*ABC", 1), "ctx_amount"},
+		{"Missing SE", strings.Replace(sampleCTXEDI, "SE*4*0001~", "", 1), "ctx_se_required"},
+		{"Non-numeric SE01", strings.Replace(sampleCTXEDI, "SE*4", "SE*X", 1), "ctx_se_count"},
+		{"Wrong SE01", strings.Replace(sampleCTXEDI, "SE*4", "SE*5", 1), "ctx_se_count"},
+		{"SE02 mismatch", strings.Replace(sampleCTXEDI, "SE*4*0001", "SE*4*0002", 1), "ctx_control_number"},
+		{"GE01 mismatch", strings.Replace(sampleCTXEDI, "GE*1*1", "GE*2*1", 1), "ctx_ge_count"},
+		{"GE02 mismatch", strings.Replace(sampleCTXEDI, "GE*1*1", "GE*1*2", 1), "ctx_control_number"},
+		{"IEA01 mismatch", strings.Replace(sampleCTXEDI, "IEA*1", "IEA*0", 1), "ctx_iea_count"},
+		{"IEA02 mismatch", strings.Replace(sampleCTXEDI, "IEA*1*000000001", "IEA*1*000000002", 1), "ctx_control_number"},
+		{"Missing IEA", strings.Replace(sampleCTXEDI, "IEA*1*000000001~", "", 1), "ctx_envelope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseCTXRemittance(tt.edi)
+			if err == nil {
+				err = r.Validate()
+			}
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			ve, ok := err.(ValidationError)
+			if !ok || ve.Rule != tt.rule {
+				t.Fatalf("Expected %s error, got %v", tt.rule, err)
+			}
+			if ve.Code != CodeInvalidPaymentData || ve.Rejection() != RejectionPayment {
+				t.Errorf("Expected payment rejection with %s, got %v", CodeInvalidPaymentData, err)
+			}
+		})
+	}
+
+	r, err := ParseCTXRemittance(sampleCTXEDI)
+	if err != nil {
+		t.Fatalf("ParseCTXRemittance failed: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+}
+
+func TestCTXRemittanceBuildErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(r *CTXRemittance)
+	}{
+		{"Sender too long", func(r *CTXRemittance) { r.SenderID = strings.Repeat("X", 16) }},
+		{"Control number too large", func(r *CTXRemittance) { r.InterchangeControlNumber = 1000000000 }},
+		{"Delimiter in data", func(r *CTXRemittance) { r.Lines[0].ReferenceNumber = "INV*1" }},
+		{"Same delimiters", func(r *CTXRemittance) { r.ElementSeparator, r.SegmentTerminator = '|', '|' }},
+		{"Too many addenda", func(r *CTXRemittance) {
+			r.Lines = make([]RemittanceLine, 50000)
+			for i := range r.Lines {
+				r.Lines[i] = RemittanceLine{ReferenceQualifier: "IV", ReferenceNumber: "INV", AmountPaid: 1}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := testCTXRemittance(1)
+			tt.modify(r)
+			if _, err := r.Addenda("PAY001"); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
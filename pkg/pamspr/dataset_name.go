@@ -0,0 +1,314 @@
+package pamspr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Input Management (IM) dataset naming, FROXK.Agency.SPR.Unique
+const (
+	DatasetNamePrefix    = "FROXK" // First node of every SPR dataset name
+	DatasetNameSPRNode   = "SPR"   // Third node identifying the Standard Payment Request
+	MaxDatasetNameLength = 44      // Maximum dataset name length including delimiters
+	MaxDatasetNodeLength = 8       // Maximum characters per node
+	datasetLedgerDate    = "2006-01-02"
+)
+
+// DatasetName is an IM dataset name of the form FROXK.Agency.SPR.Unique
+type DatasetName struct {
+	Agency string // Second node, the agency identifier
+	Unique string // Fourth node, unique within a day
+}
+
+// NewDatasetName builds and validates a dataset name; nodes are upper-cased
+func NewDatasetName(agency, unique string) (DatasetName, error) {
+	name := DatasetName{
+		Agency: strings.ToUpper(strings.TrimSpace(agency)),
+		Unique: strings.ToUpper(strings.TrimSpace(unique)),
+	}
+	if err := name.Validate(); err != nil {
+		return DatasetName{}, err
+	}
+	return name, nil
+}
+
+// ParseDatasetName splits and validates a FROXK.Agency.SPR.Unique name
+func ParseDatasetName(name string) (DatasetName, error) {
+	nodes := strings.Split(name, ".")
+	if len(nodes) != 4 || nodes[0] != DatasetNamePrefix || nodes[2] != DatasetNameSPRNode {
+		return DatasetName{}, ValidationError{
+			Field:   "DatasetName",
+			Value:   name,
+			Rule:    "dataset_format",
+			Message: fmt.Sprintf("dataset name must have the form %s.Agency.%s.Unique", DatasetNamePrefix, DatasetNameSPRNode),
+		}
+	}
+
+	parsed := DatasetName{Agency: nodes[1], Unique: nodes[3]}
+	if err := parsed.Validate(); err != nil {
+		return DatasetName{}, err
+	}
+	return parsed, nil
+}
+
+// String returns the full dataset name
+func (d DatasetName) String() string {
+	return strings.Join([]string{DatasetNamePrefix, d.Agency, DatasetNameSPRNode, d.Unique}, ".")
+}
+
+// Validate checks each node and the total length of the dataset name
+func (d DatasetName) Validate() error {
+	if err := validateDatasetNode("Agency", d.Agency); err != nil {
+		return err
+	}
+	if err := validateDatasetNode("Unique", d.Unique); err != nil {
+		return err
+	}
+	if name := d.String(); len(name) > MaxDatasetNameLength {
+		return ValidationError{
+			Field:   "DatasetName",
+			Value:   name,
+			Rule:    "max_length",
+			Message: fmt.Sprintf("dataset name must be at most %d characters including delimiters, got %d", MaxDatasetNameLength, len(name)),
+		}
+	}
+	return nil
+}
+
+// validateDatasetNode checks a node is 1-8 characters, starts with a letter or
+// national character (#, @, $) and otherwise holds letters, digits, national
+// characters or hyphens
+func validateDatasetNode(field, node string) error {
+	if node == "" {
+		return ValidationError{
+			Field:   field,
+			Rule:    "required",
+			Message: "dataset name node cannot be blank",
+		}
+	}
+	if len(node) > MaxDatasetNodeLength {
+		return ValidationError{
+			Field:   field,
+			Value:   node,
+			Rule:    "max_length",
+			Message: fmt.Sprintf("dataset name node must be at most %d characters, got %d", MaxDatasetNodeLength, len(node)),
+		}
+	}
+	for i, r := range node {
+		national := r == '#' || r == '@' || r == '$'
+		letter := r >= 'A' && r <= 'Z'
+		digit := r >= '0' && r <= '9'
+		if i == 0 && !(letter || national) {
+			return NewFieldFormatError(field, node, "start with A-Z, #, @ or $")
+		}
+		if !(letter || national || digit || r == '-') {
+			return NewFieldFormatError(field, node, "contain only A-Z, 0-9, #, @, $ or -")
+		}
+	}
+	return nil
+}
+
+// DatasetTransmission records a dataset sent to IM
+type DatasetTransmission struct {
+	Name          string    `json:"name"`
+	Date          string    `json:"date"` // Transmission day, YYYY-MM-DD
+	TransmittedAt time.Time `json:"transmittedAt"`
+	ControlNumber string    `json:"controlNumber,omitempty"` // Cnnnnnn, assigned by IM
+	InputSystem   string    `json:"inputSystem,omitempty"`
+	TotalRecords  int64     `json:"totalRecords,omitempty"`
+	TotalPayments int64     `json:"totalPayments,omitempty"`
+	TotalAmount   int64     `json:"totalAmount,omitempty"`
+}
+
+// NewDatasetTransmission describes a file sent under name at the given time
+func NewDatasetTransmission(name DatasetName, file *File, at time.Time) DatasetTransmission {
+	t := DatasetTransmission{
+		Name:          name.String(),
+		Date:          at.Format(datasetLedgerDate),
+		TransmittedAt: at,
+	}
+	if file != nil && file.Header != nil {
+		t.InputSystem = strings.TrimSpace(file.Header.InputSystem)
+	}
+	if file != nil && file.Trailer != nil {
+		t.TotalRecords = file.Trailer.TotalCountRecords
+		t.TotalPayments = file.Trailer.TotalCountPayments
+		t.TotalAmount = file.Trailer.TotalAmountPayments
+	}
+	return t
+}
+
+// ErrDatasetNameCollision is matched by errors.Is for DatasetCollisionError
+var ErrDatasetNameCollision = errors.New("dataset name already used today")
+
+// DatasetCollisionError reports a dataset name already transmitted on the same day,
+// which IM would overwrite
+type DatasetCollisionError struct {
+	Name     string
+	Previous DatasetTransmission
+}
+
+func (e *DatasetCollisionError) Error() string {
+	return fmt.Sprintf("dataset %s was already transmitted on %s at %s; IM would overwrite it",
+		e.Name, e.Previous.Date, e.Previous.TransmittedAt.Format("15:04:05"))
+}
+
+// Is reports whether target is ErrDatasetNameCollision
+func (e *DatasetCollisionError) Is(target error) bool {
+	return target == ErrDatasetNameCollision
+}
+
+// DatasetLedger is a local record of transmitted dataset names used to catch
+// same-day collisions before a file is sent
+type DatasetLedger struct {
+	mu            sync.Mutex
+	Transmissions []DatasetTransmission `json:"transmissions"`
+}
+
+// NewDatasetLedger creates an empty ledger
+func NewDatasetLedger() *DatasetLedger {
+	return &DatasetLedger{}
+}
+
+// ReadDatasetLedger decodes a ledger written by WriteTo
+func ReadDatasetLedger(r io.Reader) (*DatasetLedger, error) {
+	ledger := NewDatasetLedger()
+	if err := json.NewDecoder(r).Decode(ledger); err != nil {
+		return nil, fmt.Errorf("reading dataset ledger: %w", err)
+	}
+	return ledger, nil
+}
+
+// OpenDatasetLedger loads a ledger file, returning an empty ledger if it does not exist
+func OpenDatasetLedger(path string) (*DatasetLedger, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewDatasetLedger(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadDatasetLedger(f)
+}
+
+// WriteTo encodes the ledger as JSON
+func (l *DatasetLedger) WriteTo(w io.Writer) (int64, error) {
+	l.mu.Lock()
+	data, err := json.MarshalIndent(l, "", "  ")
+	l.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// Save writes the ledger to path, replacing the previous contents
+func (l *DatasetLedger) Save(path string) error {
+	return saveFile(path, l)
+}
+
+// saveFile writes to a temporary file renamed over path once complete
+func saveFile(path string, w io.WriterTo) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := w.WriteTo(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Check returns a DatasetCollisionError if name was transmitted on the same day as at
+func (l *DatasetLedger) Check(name DatasetName, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.check(name.String(), at.Format(datasetLedgerDate))
+}
+
+func (l *DatasetLedger) check(name, date string) error {
+	for _, t := range l.Transmissions {
+		if t.Name == name && t.Date == date {
+			return &DatasetCollisionError{Name: name, Previous: t}
+		}
+	}
+	return nil
+}
+
+// Record adds a transmission to the ledger, refusing same-day name collisions
+func (l *DatasetLedger) Record(t DatasetTransmission) error {
+	if _, err := ParseDatasetName(t.Name); err != nil {
+		return err
+	}
+	if t.Date == "" {
+		t.Date = t.TransmittedAt.Format(datasetLedgerDate)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.check(t.Name, t.Date); err != nil {
+		return err
+	}
+	l.Transmissions = append(l.Transmissions, t)
+	return nil
+}
+
+// NextDatasetName returns a name for agency that is unused on the day of at.
+// The unique node is "D" followed by the day (MMDD) and a three digit sequence.
+func (l *DatasetLedger) NextDatasetName(agency string, at time.Time) (DatasetName, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	date := at.Format(datasetLedgerDate)
+	for seq := 1; seq <= 999; seq++ {
+		name, err := NewDatasetName(agency, fmt.Sprintf("D%s%03d", at.Format("0102"), seq))
+		if err != nil {
+			return DatasetName{}, err
+		}
+		if l.check(name.String(), date) == nil {
+			return name, nil
+		}
+	}
+	return DatasetName{}, fmt.Errorf("no unused dataset names left for %s on %s", agency, date)
+}
+
+// Collisions returns transmissions whose name was already used earlier the same day,
+// for auditing ledgers that were edited or merged by hand
+func (l *DatasetLedger) Collisions() []DatasetCollisionError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sorted := make([]DatasetTransmission, len(l.Transmissions))
+	copy(sorted, l.Transmissions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].TransmittedAt.Before(sorted[j].TransmittedAt)
+	})
+
+	var collisions []DatasetCollisionError
+	first := make(map[string]DatasetTransmission)
+	for _, t := range sorted {
+		key := t.Date + " " + t.Name
+		if previous, ok := first[key]; ok {
+			collisions = append(collisions, DatasetCollisionError{Name: t.Name, Previous: previous})
+			continue
+		}
+		first[key] = t
+	}
+	return collisions
+}
@@ -0,0 +1,163 @@
+package pamspr
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDatasetName(t *testing.T) {
+	tests := []struct {
+		name      string
+		agency    string
+		unique    string
+		expected  string
+		expectErr bool
+		errRule   string
+	}{
+		{"Valid", "IRS", "D1018001", "FROXK.IRS.SPR.D1018001", false, ""},
+		{"Upper-cased", "ssa", "run-1", "FROXK.SSA.SPR.RUN-1", false, ""},
+		{"National characters", "#VA", "$A@1", "FROXK.#VA.SPR.$A@1", false, ""},
+		{"Longest name", "ABCDEFGH", "ABCDEFGH", "FROXK.ABCDEFGH.SPR.ABCDEFGH", false, ""},
+		{"Blank agency", "", "D1", "", true, "required"},
+		{"Agency too long", "ABCDEFGHI", "D1", "", true, "max_length"},
+		{"Unique too long", "IRS", "D10180001", "", true, "max_length"},
+		{"Leading digit", "IRS", "1D", "", true, "format"},
+		{"Invalid character", "IRS", "D_1", "", true, "format"},
+		{"Embedded delimiter", "IRS", "D.1", "", true, "format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := NewDatasetName(tt.agency, tt.unique)
+			if tt.expectErr {
+				ve, ok := err.(ValidationError)
+				if !ok || ve.Rule != tt.errRule {
+					t.Fatalf("Expected %s error, got %v", tt.errRule, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if name.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, name)
+			}
+			if len(name.String()) > MaxDatasetNameLength {
+				t.Errorf("Name exceeds %d characters", MaxDatasetNameLength)
+			}
+		})
+	}
+}
+
+func TestParseDatasetName(t *testing.T) {
+	name, err := ParseDatasetName("FROXK.IRS.SPR.D1018001")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name.Agency != "IRS" || name.Unique != "D1018001" {
+		t.Errorf("Unexpected nodes: %+v", name)
+	}
+
+	for _, bad := range []string{
+		"FROXK.IRS.SPR",
+		"FROXX.IRS.SPR.D1",
+		"FROXK.IRS.ACH.D1",
+		"FROXK.IRS.SPR.D1.EXTRA",
+		"FROXK.IRS.SPR.",
+	} {
+		if _, err := ParseDatasetName(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestDatasetLedgerCollisions(t *testing.T) {
+	ledger := NewDatasetLedger()
+	name, _ := NewDatasetName("IRS", "D1018001")
+	morning := time.Date(2024, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	if err := ledger.Check(name, morning); err != nil {
+		t.Fatalf("Unexpected collision on empty ledger: %v", err)
+	}
+	file := createTestACHFile()
+	if err := ledger.Record(NewDatasetTransmission(name, file, morning)); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if got := ledger.Transmissions[0]; got.TotalPayments != file.Trailer.TotalCountPayments || got.Date != "2024-10-18" {
+		t.Errorf("Unexpected transmission: %+v", got)
+	}
+
+	// Same name later that day would overwrite the earlier dataset
+	afternoon := morning.Add(6 * time.Hour)
+	err := ledger.Check(name, afternoon)
+	if !errors.Is(err, ErrDatasetNameCollision) {
+		t.Fatalf("Expected collision, got %v", err)
+	}
+	var collision *DatasetCollisionError
+	if !errors.As(err, &collision) || !collision.Previous.TransmittedAt.Equal(morning) {
+		t.Errorf("Expected collision with the morning transmission, got %v", err)
+	}
+	if err := ledger.Record(NewDatasetTransmission(name, nil, afternoon)); !errors.Is(err, ErrDatasetNameCollision) {
+		t.Errorf("Expected Record to refuse the collision, got %v", err)
+	}
+
+	// The next day the name can be reused
+	if err := ledger.Check(name, morning.AddDate(0, 0, 1)); err != nil {
+		t.Errorf("Unexpected collision on the next day: %v", err)
+	}
+
+	next, err := ledger.NextDatasetName("IRS", afternoon)
+	if err != nil {
+		t.Fatalf("NextDatasetName failed: %v", err)
+	}
+	if next.String() != "FROXK.IRS.SPR.D1018002" {
+		t.Errorf("Expected the next unused sequence, got %s", next)
+	}
+
+	if err := ledger.Record(DatasetTransmission{Name: "FROXK.IRS.SPR.TOOLONGNAME"}); err == nil {
+		t.Error("Expected Record to reject an invalid name")
+	}
+}
+
+func TestDatasetLedgerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+
+	ledger, err := OpenDatasetLedger(path)
+	if err != nil {
+		t.Fatalf("OpenDatasetLedger on missing file failed: %v", err)
+	}
+	name, _ := NewDatasetName("VA", "RUN1")
+	at := time.Date(2024, 10, 18, 9, 0, 0, 0, time.UTC)
+	if err := ledger.Record(NewDatasetTransmission(name, nil, at)); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := ledger.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reloaded, err := OpenDatasetLedger(path)
+	if err != nil {
+		t.Fatalf("OpenDatasetLedger failed: %v", err)
+	}
+	if err := reloaded.Check(name, at.Add(time.Hour)); !errors.Is(err, ErrDatasetNameCollision) {
+		t.Errorf("Expected collision after reload, got %v", err)
+	}
+
+	// Hand-merged ledgers can already contain collisions
+	var buf bytes.Buffer
+	if _, err := reloaded.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	reloaded.Transmissions = append(reloaded.Transmissions, NewDatasetTransmission(name, nil, at.Add(time.Hour)))
+	if collisions := reloaded.Collisions(); len(collisions) != 1 || !collisions[0].Previous.TransmittedAt.Equal(at) {
+		t.Errorf("Expected one collision with the first transmission, got %+v", collisions)
+	}
+
+	if _, err := ReadDatasetLedger(strings.NewReader("not json")); err == nil {
+		t.Error("Expected error for invalid ledger")
+	}
+}
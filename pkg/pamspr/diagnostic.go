@@ -0,0 +1,276 @@
+package pamspr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// excerptWidth is the number of record columns shown in a diagnostic excerpt
+const excerptWidth = 72
+
+// RecordError is a parse error tied to the record it was found on
+type RecordError struct {
+	Line       int    // 1-based line number
+	RecordCode string // First two columns of the record
+	Record     string // Raw record text
+	Err        error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostic locates a problem in an SPR file down to the columns of the
+// field involved, the way a compiler reports a source error
+type Diagnostic struct {
+	Line        int       `json:"line,omitempty"`        // 1-based, 0 if unknown
+	ColumnStart int       `json:"columnStart,omitempty"` // 1-based first column of the field, 0 if unknown
+	ColumnEnd   int       `json:"columnEnd,omitempty"`   // 1-based last column of the field
+	Column      int       `json:"column,omitempty"`      // 1-based column of the offending character, 0 if the whole field
+	RecordCode  string    `json:"recordCode,omitempty"`
+	Field       string    `json:"field,omitempty"`
+	Value       string    `json:"value,omitempty"` // Raw field value
+	Message     string    `json:"message"`
+	Code        ErrorCode `json:"code"`
+
+	// Record is the raw record the excerpt is rendered from
+	Record string `json:"-"`
+}
+
+// NewDiagnostic builds a diagnostic from an error returned by the reader,
+// parsers or validator, using whatever location the error chain carries
+func NewDiagnostic(err error) Diagnostic {
+	d := Diagnostic{Message: err.Error()}
+
+	var recordErr *RecordError
+	if errors.As(err, &recordErr) {
+		d.Line = recordErr.Line
+		d.RecordCode = recordErr.RecordCode
+		d.Record = recordErr.Record
+		d.Message = recordErr.Err.Error()
+	}
+
+	var fieldErr *FieldError
+	var extractErr *FieldExtractionError
+	var ve ValidationError
+	switch {
+	case errors.As(err, &fieldErr):
+		d.RecordCode = fieldErr.RecordCode
+		d.Field = fieldErr.Field
+		d.Value = fieldErr.Value
+		d.ColumnStart, d.ColumnEnd, d.Column = fieldErr.Start, fieldErr.End, fieldErr.Column
+		d.Message = fieldErr.Message
+		d.Code = fieldErr.Code
+	case errors.As(err, &extractErr):
+		d.Field = extractErr.FieldName
+		d.ColumnStart, d.ColumnEnd = extractErr.Start, extractErr.End
+		d.Message = extractErr.Reason
+		if d.Record == "" {
+			d.Record = extractErr.Line
+		}
+	case errors.As(err, &ve):
+		d.Field = ve.Field
+		d.Value = ve.Value
+		d.Message = ve.Message
+		d.Code = ve.Code
+	}
+
+	d.locateField()
+	return d
+}
+
+// Diagnostic builds a diagnostic for the issue; record is the raw text of the
+// line the issue was found on and may be empty
+func (i ValidationIssue) Diagnostic(record string) Diagnostic {
+	d := Diagnostic{Message: i.Message}
+	if i.Err != nil {
+		d = NewDiagnostic(i.Err)
+	}
+
+	d.Line = i.LineNumber
+	d.RecordCode = i.RecordCode
+	d.Message = i.Message
+	d.Code = i.Code
+	if i.Field != "" {
+		d.Field = i.Field
+	}
+	if i.Value != "" && d.Value == "" {
+		d.Value = i.Value
+	}
+	if record != "" {
+		d.Record = record
+	}
+
+	d.locateField()
+	return d
+}
+
+// Diagnostics pairs every issue with its raw record read from source, which
+// must be the text the report was produced from, delimited by LF or CRLF or in
+// fixed blocks. Source is read once, so the file does not need to fit in memory.
+func (r *ValidationReport) Diagnostics(source io.Reader) ([]Diagnostic, error) {
+	wanted := make(map[int]string)
+	for _, issue := range r.Issues {
+		if issue.LineNumber > 0 {
+			wanted[issue.LineNumber] = ""
+		}
+	}
+
+	if len(wanted) > 0 && source != nil {
+		scanner := bufio.NewScanner(source)
+		scanner.Split((&recordSplitter{}).split) // Also finds fixed-block records
+		remaining := len(wanted)
+		for line := 1; remaining > 0 && scanner.Scan(); line++ {
+			if _, ok := wanted[line]; ok {
+				wanted[line] = scanner.Text()
+				remaining--
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading records for diagnostics: %w", err)
+		}
+	}
+
+	diagnostics := make([]Diagnostic, len(r.Issues))
+	for i, issue := range r.Issues {
+		diagnostics[i] = issue.Diagnostic(wanted[issue.LineNumber])
+	}
+	return diagnostics, nil
+}
+
+// locateField fills in the record code, columns and raw value from the
+// field definitions and record when the error did not carry them
+func (d *Diagnostic) locateField() {
+	if d.RecordCode == "" && d.Record != "" {
+		d.RecordCode = firstN(d.Record, RecordCodeLength)
+	}
+	if d.ColumnStart == 0 && d.Field != "" {
+		// Trailer balancing issues name their field as "ScheduleTrailer.ScheduleAmount"
+		name := d.Field[strings.LastIndex(d.Field, ".")+1:]
+		if def, ok := GetFieldDefinitions(d.RecordCode)[name]; ok {
+			d.ColumnStart, d.ColumnEnd = def.Start, def.End
+		}
+	}
+	if d.ColumnStart > 0 && d.ColumnStart <= len(d.Record) {
+		value := d.Record[d.ColumnStart-1 : min(d.ColumnEnd, len(d.Record))]
+		if d.Value == "" || strings.TrimSpace(value) == strings.TrimSpace(d.Value) {
+			d.Value = value
+		}
+	}
+}
+
+// String formats the diagnostic without a file name
+func (d Diagnostic) String() string {
+	return d.Format("")
+}
+
+// Format renders the diagnostic as "file:line:column: message" followed by an
+// excerpt of the record with a caret under the offending columns
+func (d Diagnostic) Format(filename string) string {
+	var parts []string
+
+	column := d.Column
+	if column == 0 {
+		column = d.ColumnStart
+	}
+	switch {
+	case filename != "" && d.Line > 0 && column > 0:
+		parts = append(parts, fmt.Sprintf("%s:%d:%d:", filename, d.Line, column))
+	case filename != "" && d.Line > 0:
+		parts = append(parts, fmt.Sprintf("%s:%d:", filename, d.Line))
+	case filename != "":
+		parts = append(parts, filename+":")
+	case d.Line > 0:
+		parts = append(parts, fmt.Sprintf("line %d:", d.Line))
+	}
+
+	if d.RecordCode != "" {
+		parts = append(parts, fmt.Sprintf("record %q", d.RecordCode))
+	}
+	if d.Field != "" {
+		parts = append(parts, "field "+d.Field)
+	}
+	if d.ColumnStart > 0 {
+		parts = append(parts, fmt.Sprintf("(columns %d-%d)", d.ColumnStart, d.ColumnEnd))
+	}
+	if len(parts) > 0 && !strings.HasSuffix(parts[len(parts)-1], ":") {
+		parts[len(parts)-1] += ":"
+	}
+
+	message := d.Message
+	if !d.Code.IsZero() {
+		message += fmt.Sprintf(" [%s]", d.Code)
+	}
+	parts = append(parts, message)
+
+	out := strings.Join(parts, " ")
+	if excerpt := d.Excerpt(); excerpt != "" {
+		out += "\n" + excerpt
+	}
+	return out
+}
+
+// Excerpt renders the part of the 850-column record around the field, with
+// "^" under the offending column and "~" under the rest of the field. It is
+// empty when the record text is not known.
+func (d Diagnostic) Excerpt() string {
+	if d.Record == "" {
+		return ""
+	}
+
+	// Window of columns to show, 1-based and inclusive
+	first := 1
+	if d.ColumnStart > 0 {
+		first = max(1, d.ColumnStart-8)
+		if focus := max(d.Column, d.ColumnStart); focus-first >= excerptWidth {
+			first = focus - excerptWidth/2
+		}
+	}
+	last := min(len(d.Record), first+excerptWidth-1)
+	first = max(1, min(first, last-excerptWidth+1))
+
+	gutter := fmt.Sprintf("%5d | ", d.Line)
+	if d.Line == 0 {
+		gutter = "      | "
+	}
+	blank := "      | "
+
+	var text, caret strings.Builder
+	if first > 1 {
+		text.WriteString("...")
+		caret.WriteString("   ")
+	}
+	for col := first; col <= last; col++ {
+		b := d.Record[col-1]
+		if b < 0x20 || b > 0x7E {
+			b = '?' // Keep the caret aligned under control and non-ASCII characters
+		}
+		text.WriteByte(b)
+
+		switch {
+		case d.ColumnStart == 0 || col < d.ColumnStart || col > d.ColumnEnd:
+			caret.WriteByte(' ')
+		case col == d.Column || (d.Column == 0 && col == d.ColumnStart):
+			caret.WriteByte('^')
+		default:
+			caret.WriteByte('~')
+		}
+	}
+	if last < len(d.Record) {
+		text.WriteString("...")
+	}
+
+	excerpt := gutter + text.String()
+	if marks := strings.TrimRight(caret.String(), " "); d.ColumnStart > 0 && marks != "" {
+		excerpt += "\n" + blank + marks
+	}
+	return excerpt
+}
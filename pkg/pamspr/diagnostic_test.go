@@ -0,0 +1,180 @@
+package pamspr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewDiagnostic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestACHFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	payment := lines[2]
+
+	validator := NewValidator()
+	bad := payment[:40] + "\t" + payment[41:]
+	fieldErrs := validator.ValidateRecordFields(bad)
+	if len(fieldErrs) != 1 {
+		t.Fatalf("Expected 1 field error, got %v", fieldErrs)
+	}
+
+	tests := []struct {
+		name        string
+		err         error
+		line        int
+		recordCode  string
+		field       string
+		start, end  int
+		column      int
+		hasExcerpt  bool
+		messagePart string
+	}{
+		{
+			name:       "Field error",
+			err:        fieldErrs[0],
+			recordCode: "02",
+			field:      "PayeeName",
+			start:      31, end: 65, column: 41,
+			messagePart: "not an allowed character",
+		},
+		{
+			name:        "Validation error",
+			err:         NewFieldRequiredError("PayeeName"),
+			field:       "PayeeName",
+			messagePart: "required",
+		},
+		{
+			name: "Record error",
+			err: &RecordError{Line: 3, RecordCode: "02", Record: bad,
+				Err: fieldErrs[0]},
+			line:       3,
+			recordCode: "02",
+			field:      "PayeeName",
+			start:      31, end: 65, column: 41,
+			hasExcerpt:  true,
+			messagePart: "not an allowed character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDiagnostic(tt.err)
+			if d.Line != tt.line || d.RecordCode != tt.recordCode || d.Field != tt.field {
+				t.Errorf("Unexpected location: %+v", d)
+			}
+			if d.ColumnStart != tt.start || d.ColumnEnd != tt.end || d.Column != tt.column {
+				t.Errorf("Expected columns %d-%d at %d, got %d-%d at %d",
+					tt.start, tt.end, tt.column, d.ColumnStart, d.ColumnEnd, d.Column)
+			}
+			if (d.Excerpt() != "") != tt.hasExcerpt {
+				t.Errorf("Unexpected excerpt %q", d.Excerpt())
+			}
+			if !strings.Contains(d.Message, tt.messagePart) {
+				t.Errorf("Expected message containing %q, got %q", tt.messagePart, d.Message)
+			}
+		})
+	}
+
+	// Reader errors carry the line and record they were found on
+	lines[3] = lines[3][:500]
+	_, err := NewReader(strings.NewReader(strings.Join(lines, "\n"))).Read()
+	if err == nil {
+		t.Fatal("Expected a read error for a short record")
+	}
+	d := NewDiagnostic(err)
+	if d.Line != 4 || d.RecordCode != "02" || len(d.Record) != 500 {
+		t.Errorf("Unexpected reader diagnostic: %+v", d)
+	}
+	if !strings.HasPrefix(d.Format("payments.spr"), "payments.spr:4: record \"02\":") {
+		t.Errorf("Unexpected format: %s", d.Format("payments.spr"))
+	}
+}
+
+func TestDiagnosticExcerpt(t *testing.T) {
+	record := strings.Repeat(" ", RecordLength)
+	record = "02" + record[2:30] + "JOHN\tDOE" + record[38:]
+
+	d := Diagnostic{
+		Line:        7,
+		RecordCode:  "02",
+		Field:       "PayeeName",
+		ColumnStart: 31,
+		ColumnEnd:   65,
+		Column:      35,
+		Message:     "bad character",
+		Code:        CodeInvalidHexCharacter,
+		Record:      record,
+	}
+
+	lines := strings.Split(d.Format("pay.spr"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a message, record and caret line, got %q", lines)
+	}
+	if lines[0] != `pay.spr:7:35: record "02" field PayeeName (columns 31-65): bad character [Error Reason Group 1 Message 5]` {
+		t.Errorf("Unexpected message line: %s", lines[0])
+	}
+
+	// The caret sits under the tab, which is shown as '?'
+	caret := strings.Index(lines[2], "^")
+	if caret < 0 || lines[1][caret] != '?' {
+		t.Errorf("Caret not under the offending column:\n%s\n%s", lines[1], lines[2])
+	}
+	if !strings.HasPrefix(lines[1], "    7 | ...") || !strings.HasSuffix(lines[1], "...") {
+		t.Errorf("Expected a clipped excerpt, got %q", lines[1])
+	}
+	if tildes := strings.Count(lines[2], "~"); tildes != 34 {
+		t.Errorf("Expected 34 '~' under the rest of the field, got %d", tildes)
+	}
+
+	// Fields near the end of the record keep the window full width
+	d.ColumnStart, d.ColumnEnd, d.Column = 840, 850, 0
+	excerpt := strings.Split(d.Excerpt(), "\n")
+	if strings.HasSuffix(excerpt[0], "...") || !strings.HasSuffix(excerpt[1], "^"+strings.Repeat("~", 10)) {
+		t.Errorf("Unexpected excerpt at end of record:\n%s", d.Excerpt())
+	}
+
+	if (Diagnostic{Message: "no record"}).Excerpt() != "" {
+		t.Error("Expected no excerpt without a record")
+	}
+}
+
+func TestValidationReportDiagnostics(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestACHFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	lines[3] = lines[3][:60] + "\t" + lines[3][61:]
+	data := strings.Join(lines, "\n")
+
+	reader := NewReader(strings.NewReader(data))
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	report := reader.GetValidationReport()
+	report.Add(ValidationIssue{ScheduleIndex: NoIndex, PaymentIndex: NoIndex, Message: "file level"})
+
+	diagnostics, err := report.Diagnostics(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Diagnostics failed: %v", err)
+	}
+	if len(diagnostics) != len(report.Issues) {
+		t.Fatalf("Expected %d diagnostics, got %d", len(report.Issues), len(diagnostics))
+	}
+
+	first := diagnostics[0]
+	if first.Line != 4 || first.Field != "PayeeName" || first.Column != 61 || first.Record != lines[3] {
+		t.Errorf("Unexpected diagnostic: %+v", first)
+	}
+	if first.Value != lines[3][30:65] {
+		t.Errorf("Expected raw field value, got %q", first.Value)
+	}
+
+	last := diagnostics[len(diagnostics)-1]
+	if last.Line != 0 || last.Excerpt() != "" || last.String() != "file level" {
+		t.Errorf("Unexpected file level diagnostic: %q", last.String())
+	}
+}
@@ -0,0 +1,389 @@
+package pamspr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DiffChange says how a schedule or payment differs between two files
+type DiffChange string
+
+const (
+	DiffAdded    DiffChange = "added"
+	DiffRemoved  DiffChange = "removed"
+	DiffModified DiffChange = "modified"
+)
+
+// FieldDiff is a field whose value differs between two records
+type FieldDiff struct {
+	Record string `json:"record"` // Record within its parent, e.g. "Payment", "Addenda[0]" or "Stub"
+	Field  string `json:"field"`
+	Before string `json:"before"` // Blank if the record was added
+	After  string `json:"after"`  // Blank if the record was removed
+}
+
+// String formats the field change as "Record.Field: before -> after"
+func (f FieldDiff) String() string {
+	return fmt.Sprintf("%s.%s: %q -> %q", f.Record, f.Field, f.Before, f.After)
+}
+
+// PaymentDiff is a payment added, removed or modified within a schedule
+type PaymentDiff struct {
+	Change    DiffChange  `json:"change"`
+	PaymentID string      `json:"paymentId"`
+	Amount    int64       `json:"amount"`           // In the second file, or the first if removed
+	Fields    []FieldDiff `json:"fields,omitempty"` // Modified payments only
+}
+
+// ScheduleDiff is a schedule added, removed or modified between two files
+type ScheduleDiff struct {
+	Change         DiffChange    `json:"change"`
+	ScheduleNumber string        `json:"scheduleNumber"`
+	PaymentType    string        `json:"paymentType"`      // "ACH" or "Check"
+	Fields         []FieldDiff   `json:"fields,omitempty"` // Schedule header and trailer
+	Payments       []PaymentDiff `json:"payments,omitempty"`
+}
+
+// TrailerDiff compares the file trailers
+type TrailerDiff struct {
+	Before   FileTrailer `json:"before"`
+	After    FileTrailer `json:"after"`
+	Records  int64       `json:"records"` // After minus before
+	Payments int64       `json:"payments"`
+	Amount   int64       `json:"amount"`
+}
+
+// FileDiff is the semantic difference between two files
+type FileDiff struct {
+	Header    []FieldDiff    `json:"header"`
+	Schedules []ScheduleDiff `json:"schedules"`
+	Trailer   TrailerDiff    `json:"trailer"`
+}
+
+// Diff compares two files record by record. Schedules are matched by schedule
+// number and payments by PaymentID within their schedule, so reordering alone
+// is not a difference. Modified payments list every field that changed,
+// including their addenda, CARS, stub and DNP records, which are compared in
+// order. Fields are compared as PAM stores them, after justification.
+func Diff(a, b *File) *FileDiff {
+	d := &differ{formatter: NewFieldFormatter(nil)}
+	diff := &FileDiff{
+		Header:    d.record("Header", "H ", headerOf(a), headerOf(b)),
+		Schedules: make([]ScheduleDiff, 0),
+	}
+	if diff.Header == nil {
+		diff.Header = make([]FieldDiff, 0)
+	}
+
+	var before, after []Schedule
+	if a != nil {
+		before = a.Schedules
+	}
+	if b != nil {
+		after = b.Schedules
+	}
+
+	matched := make(map[int]bool)
+	index := make(map[string]int, len(after))
+	for i, schedule := range after {
+		key := scheduleKey(writtenScheduleNumber(schedule))
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	for _, old := range before {
+		i, ok := index[scheduleKey(writtenScheduleNumber(old))]
+		if ok && !matched[i] && old.GetPaymentType() == after[i].GetPaymentType() {
+			matched[i] = true
+			if sd := d.schedule(old, after[i]); len(sd.Fields) > 0 || len(sd.Payments) > 0 {
+				diff.Schedules = append(diff.Schedules, sd)
+			}
+			continue
+		}
+		diff.Schedules = append(diff.Schedules, wholeSchedule(DiffRemoved, old))
+	}
+	for i, schedule := range after {
+		if !matched[i] {
+			diff.Schedules = append(diff.Schedules, wholeSchedule(DiffAdded, schedule))
+		}
+	}
+
+	diff.Trailer.Before = trailerOf(a)
+	diff.Trailer.After = trailerOf(b)
+	diff.Trailer.Records = diff.Trailer.After.TotalCountRecords - diff.Trailer.Before.TotalCountRecords
+	diff.Trailer.Payments = diff.Trailer.After.TotalCountPayments - diff.Trailer.Before.TotalCountPayments
+	diff.Trailer.Amount = diff.Trailer.After.TotalAmountPayments - diff.Trailer.Before.TotalAmountPayments
+
+	return diff
+}
+
+// Empty reports whether the files are the same
+func (d *FileDiff) Empty() bool {
+	return len(d.Header) == 0 && len(d.Schedules) == 0 && d.Trailer.Before == d.Trailer.After
+}
+
+// WriteText writes the differences as indented text followed by a summary line
+func (d *FileDiff) WriteText(w io.Writer) error {
+	var sb strings.Builder
+
+	for _, field := range d.Header {
+		fmt.Fprintf(&sb, "file %s\n", field)
+	}
+
+	var payments int
+	for _, schedule := range d.Schedules {
+		fmt.Fprintf(&sb, "schedule %s (%s) %s\n", schedule.ScheduleNumber, schedule.PaymentType, schedule.Change)
+		for _, field := range schedule.Fields {
+			fmt.Fprintf(&sb, "  %s\n", field)
+		}
+		for _, payment := range schedule.Payments {
+			if payment.Change == DiffModified {
+				fmt.Fprintf(&sb, "  payment %s modified\n", payment.PaymentID)
+			} else {
+				fmt.Fprintf(&sb, "  payment %s %s (%s)\n", payment.PaymentID, payment.Change, FormatCents(payment.Amount))
+			}
+			for _, field := range payment.Fields {
+				fmt.Fprintf(&sb, "    %s\n", field)
+			}
+		}
+		payments += len(schedule.Payments)
+	}
+
+	if t := d.Trailer; t.Before != t.After {
+		fmt.Fprintf(&sb, "file trailer: records %d -> %d (%+d), payments %d -> %d (%+d), amount %s -> %s (%s)\n",
+			t.Before.TotalCountRecords, t.After.TotalCountRecords, t.Records,
+			t.Before.TotalCountPayments, t.After.TotalCountPayments, t.Payments,
+			FormatCents(t.Before.TotalAmountPayments), FormatCents(t.After.TotalAmountPayments), signedCents(t.Amount))
+	}
+
+	if d.Empty() {
+		sb.WriteString("no differences\n")
+	} else {
+		fmt.Fprintf(&sb, "%d schedule(s) and %d payment(s) differ\n", len(d.Schedules), payments)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the differences as indented JSON
+func (d *FileDiff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+func signedCents(cents int64) string {
+	if cents < 0 {
+		return "-" + FormatCents(-cents)
+	}
+	return "+" + FormatCents(cents)
+}
+
+func headerOf(file *File) *FileHeader {
+	if file == nil {
+		return nil
+	}
+	return file.Header
+}
+
+// trailerOf returns the file's trailer, or the one it would be written with
+func trailerOf(file *File) FileTrailer {
+	switch {
+	case file == nil:
+		return FileTrailer{}
+	case file.Trailer != nil:
+		return *file.Trailer
+	default:
+		return *mergedTrailer(file)
+	}
+}
+
+func paymentTypeName(schedule Schedule) string {
+	if schedule.GetPaymentType() == PaymentTypeCheck {
+		return "Check"
+	}
+	return "ACH"
+}
+
+// wholeSchedule lists every payment of an added or removed schedule
+func wholeSchedule(change DiffChange, schedule Schedule) ScheduleDiff {
+	sd := ScheduleDiff{
+		Change:         change,
+		ScheduleNumber: strings.TrimSpace(writtenScheduleNumber(schedule)),
+		PaymentType:    paymentTypeName(schedule),
+	}
+	for _, payment := range schedule.GetPayments() {
+		sd.Payments = append(sd.Payments, PaymentDiff{
+			Change:    change,
+			PaymentID: strings.TrimSpace(payment.GetPaymentID()),
+			Amount:    payment.GetAmount(),
+		})
+	}
+	return sd
+}
+
+type differ struct {
+	formatter *FieldFormatter
+}
+
+// schedule compares two schedules with the same number and payment type
+func (d *differ) schedule(a, b Schedule) ScheduleDiff {
+	sd := ScheduleDiff{
+		Change:         DiffModified,
+		ScheduleNumber: strings.TrimSpace(writtenScheduleNumber(b)),
+		PaymentType:    paymentTypeName(b),
+	}
+
+	switch s := a.(type) {
+	case *ACHSchedule:
+		sd.Fields = d.record("Header", "01", s.Header, b.(*ACHSchedule).Header)
+	case *CheckSchedule:
+		sd.Fields = d.record("Header", "11", s.Header, b.(*CheckSchedule).Header)
+	}
+	sd.Fields = append(sd.Fields, d.record("Trailer", "T ", a.GetTrailer(), b.GetTrailer())...)
+
+	after := b.GetPayments()
+	afterKeys := paymentKeys(after)
+	index := make(map[string]int, len(after))
+	for i, key := range afterKeys {
+		index[key] = i
+	}
+
+	matched := make(map[int]bool)
+	for i, key := range paymentKeys(a.GetPayments()) {
+		old := a.GetPayments()[i]
+		j, ok := index[key]
+		if !ok {
+			sd.Payments = append(sd.Payments, PaymentDiff{
+				Change:    DiffRemoved,
+				PaymentID: strings.TrimSpace(old.GetPaymentID()),
+				Amount:    old.GetAmount(),
+			})
+			continue
+		}
+		matched[j] = true
+		if fields := d.payment(old, after[j]); len(fields) > 0 {
+			sd.Payments = append(sd.Payments, PaymentDiff{
+				Change:    DiffModified,
+				PaymentID: strings.TrimSpace(after[j].GetPaymentID()),
+				Amount:    after[j].GetAmount(),
+				Fields:    fields,
+			})
+		}
+	}
+	for j, payment := range after {
+		if !matched[j] {
+			sd.Payments = append(sd.Payments, PaymentDiff{
+				Change:    DiffAdded,
+				PaymentID: strings.TrimSpace(payment.GetPaymentID()),
+				Amount:    payment.GetAmount(),
+			})
+		}
+	}
+
+	return sd
+}
+
+// paymentKeys keys payments by PaymentID; repeated IDs are matched in order
+func paymentKeys(payments []Payment) []string {
+	seen := make(map[string]int, len(payments))
+	keys := make([]string, len(payments))
+	for i, payment := range payments {
+		id := strings.TrimSpace(payment.GetPaymentID())
+		keys[i] = id + "#" + strconv.Itoa(seen[id])
+		seen[id]++
+	}
+	return keys
+}
+
+// payment compares two payments of the same type and their associated records
+func (d *differ) payment(a, b Payment) []FieldDiff {
+	var fields []FieldDiff
+
+	switch p := a.(type) {
+	case *ACHPayment:
+		q := b.(*ACHPayment)
+		fields = d.record("Payment", "02", p, q)
+		for i := 0; i < max(len(p.Addenda), len(q.Addenda)); i++ {
+			fields = append(fields, d.record(fmt.Sprintf("Addenda[%d]", i), "03", at(p.Addenda, i), at(q.Addenda, i))...)
+		}
+		for i := 0; i < max(len(p.CTXAddenda), len(q.CTXAddenda)); i++ {
+			fields = append(fields, d.record(fmt.Sprintf("CTXAddenda[%d]", i), "04", at(p.CTXAddenda, i), at(q.CTXAddenda, i))...)
+		}
+		fields = append(fields, d.associated(p.CARSTASBETC, q.CARSTASBETC, p.DNP, q.DNP)...)
+
+	case *CheckPayment:
+		q := b.(*CheckPayment)
+		fields = d.record("Payment", "12", p, q)
+		fields = append(fields, d.record("Stub", "13", p.Stub, q.Stub)...)
+		fields = append(fields, d.associated(p.CARSTASBETC, q.CARSTASBETC, p.DNP, q.DNP)...)
+	}
+
+	return fields
+}
+
+func (d *differ) associated(a, b []*CARSTASBETC, aDNP, bDNP *DNPRecord) []FieldDiff {
+	var fields []FieldDiff
+	for i := 0; i < max(len(a), len(b)); i++ {
+		fields = append(fields, d.record(fmt.Sprintf("CARSTASBETC[%d]", i), "G ", at(a, i), at(b, i))...)
+	}
+	return append(fields, d.record("DNP", "DD", aDNP, bDNP)...)
+}
+
+// at returns records[i], or nil past the end
+func at[T any](records []*T, i int) *T {
+	if i < len(records) {
+		return records[i]
+	}
+	return nil
+}
+
+// record compares the defined fields of two record structs of the same type.
+// A nil record compares as blank, so an added record lists its fields.
+func (d *differ) record(name, recordCode string, a, b interface{}) []FieldDiff {
+	var fields []FieldDiff
+	for _, named := range GetOrderedFieldDefinitions(recordCode) {
+		if named.Type == FieldTypeFiller || named.Name == "RecordCode" {
+			continue
+		}
+		before := d.field(a, named)
+		after := d.field(b, named)
+		if before != after {
+			fields = append(fields, FieldDiff{Record: name, Field: named.Name, Before: before, After: after})
+		}
+	}
+	return fields
+}
+
+// field returns a record field as PAM stores it, without padding
+func (d *differ) field(record interface{}, named NamedField) string {
+	value := reflect.ValueOf(record)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return ""
+	}
+	value = value.Elem()
+
+	field := value.FieldByName(named.Name)
+	if line, ok := strings.CutPrefix(named.Name, "Line"); ok && !field.IsValid() {
+		// Check stub lines are held in an array
+		n, err := strconv.Atoi(line)
+		lines := value.FieldByName("PaymentIdentificationLines")
+		if err == nil && lines.Kind() == reflect.Array && n >= 1 && n <= lines.Len() {
+			field = lines.Index(n - 1)
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(d.formatter.NormalizeField(field.String(), named.FieldDefinition))
+	case reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10)
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package pamspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(file *File)
+		header    []FieldDiff
+		schedules []ScheduleDiff
+	}{
+		{
+			name:   "Identical",
+			change: func(file *File) {},
+		},
+		{
+			name: "Justification is not a difference",
+			change: func(file *File) {
+				file.Schedules[0].(*ACHSchedule).Header.ScheduleNumber = "1"
+				file.Schedules[0].(*ACHSchedule).Payments[0].(*ACHPayment).PayeeName = "  TEST PAYEE 1"
+			},
+		},
+		{
+			name: "Reordered payments",
+			change: func(file *File) {
+				payments := file.Schedules[0].(*ACHSchedule).Payments
+				payments[0], payments[1] = payments[1], payments[0]
+			},
+		},
+		{
+			name: "Header field",
+			change: func(file *File) {
+				file.Header.InputSystem = "OTHER SYSTEM"
+			},
+			header: []FieldDiff{{Record: "Header", Field: "InputSystem", Before: "TEST SYSTEM", After: "OTHER SYSTEM"}},
+		},
+		{
+			name: "Modified payment and associated records",
+			change: func(file *File) {
+				payment := file.Schedules[0].(*ACHSchedule).Payments[1].(*ACHPayment)
+				payment.RoutingNumber = "021000021"
+				payment.Addenda = append(payment.Addenda, &ACHAddendum{RecordCode: "03", PaymentID: "PAY002", AddendaInformation: "INVOICE 7"})
+				payment.DNP = &DNPRecord{RecordCode: "DD", PaymentID: "PAY002", DNPDetail: "DETAIL"}
+			},
+			schedules: []ScheduleDiff{{
+				Change: DiffModified, ScheduleNumber: "00000000000001", PaymentType: "ACH",
+				Payments: []PaymentDiff{{
+					Change: DiffModified, PaymentID: "PAY002", Amount: 200000,
+					Fields: []FieldDiff{
+						{Record: "Payment", Field: "RoutingNumber", Before: "122000247", After: "021000021"},
+						{Record: "Addenda[0]", Field: "PaymentID", After: "PAY002"},
+						{Record: "Addenda[0]", Field: "AddendaInformation", After: "INVOICE 7"},
+						{Record: "DNP", Field: "PaymentID", After: "PAY002"},
+						{Record: "DNP", Field: "DNPDetail", After: "DETAIL"},
+					},
+				}},
+			}},
+		},
+		{
+			name: "Added and removed payments",
+			change: func(file *File) {
+				schedule := file.Schedules[0].(*ACHSchedule)
+				added := *schedule.Payments[0].(*ACHPayment)
+				added.PaymentID = "PAY003"
+				added.Amount = 5000
+				schedule.Payments = []Payment{schedule.Payments[1], &added}
+				schedule.Trailer.ScheduleAmount = 205000
+			},
+			schedules: []ScheduleDiff{{
+				Change: DiffModified, ScheduleNumber: "00000000000001", PaymentType: "ACH",
+				Fields: []FieldDiff{{Record: "Trailer", Field: "ScheduleAmount", Before: "300000", After: "205000"}},
+				Payments: []PaymentDiff{
+					{Change: DiffRemoved, PaymentID: "PAY001", Amount: 100000},
+					{Change: DiffAdded, PaymentID: "PAY003", Amount: 5000},
+				},
+			}},
+		},
+		{
+			name: "Replaced schedule",
+			change: func(file *File) {
+				file.Schedules = createTestCheckFile().Schedules
+				file.Schedules[0].(*CheckSchedule).Header.ScheduleNumber = "00000000000002"
+			},
+			schedules: []ScheduleDiff{
+				{Change: DiffRemoved, ScheduleNumber: "00000000000001", PaymentType: "ACH", Payments: []PaymentDiff{
+					{Change: DiffRemoved, PaymentID: "PAY001", Amount: 100000},
+					{Change: DiffRemoved, PaymentID: "PAY002", Amount: 200000},
+				}},
+				{Change: DiffAdded, ScheduleNumber: "00000000000002", PaymentType: "Check", Payments: []PaymentDiff{
+					{Change: DiffAdded, PaymentID: "CHK001", Amount: 150000},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := createTestACHFile()
+			tt.change(changed)
+			diff := Diff(createTestACHFile(), changed)
+
+			if len(diff.Header) != len(tt.header) || (len(tt.header) > 0 && !reflect.DeepEqual(diff.Header, tt.header)) {
+				t.Errorf("Header:\n got %+v\nwant %+v", diff.Header, tt.header)
+			}
+			if len(diff.Schedules) != len(tt.schedules) || (len(tt.schedules) > 0 && !reflect.DeepEqual(diff.Schedules, tt.schedules)) {
+				t.Errorf("Schedules:\n got %+v\nwant %+v", diff.Schedules, tt.schedules)
+			}
+			if want := len(tt.header) == 0 && len(tt.schedules) == 0; diff.Empty() != want {
+				t.Errorf("Expected Empty() = %v", want)
+			}
+		})
+	}
+}
+
+func TestDiffOutput(t *testing.T) {
+	changed := createTestACHFile()
+	schedule := changed.Schedules[0].(*ACHSchedule)
+	schedule.Payments[0].(*ACHPayment).Amount = 150000
+	schedule.Payments = schedule.Payments[:1]
+	changed.Trailer.TotalCountRecords = 5
+	changed.Trailer.TotalCountPayments = 1
+	changed.Trailer.TotalAmountPayments = 150000
+
+	diff := Diff(createTestACHFile(), changed)
+	if diff.Trailer.Records != -1 || diff.Trailer.Payments != -1 || diff.Trailer.Amount != -150000 {
+		t.Errorf("Unexpected trailer deltas %+v", diff.Trailer)
+	}
+
+	var text bytes.Buffer
+	if err := diff.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	want := `schedule 00000000000001 (ACH) modified
+  payment PAY001 modified
+    Payment.Amount: "100000" -> "150000"
+  payment PAY002 removed (2000.00)
+file trailer: records 6 -> 5 (-1), payments 2 -> 1 (-1), amount 3000.00 -> 1500.00 (-1500.00)
+1 schedule(s) and 2 payment(s) differ
+`
+	if text.String() != want {
+		t.Errorf("WriteText:\n%s\nwant:\n%s", text.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := diff.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded FileDiff
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, diff) {
+		t.Errorf("JSON round trip:\n got %+v\nwant %+v", decoded, *diff)
+	}
+
+	text.Reset()
+	if err := Diff(createTestACHFile(), createTestACHFile()).WriteText(&text); err != nil || !strings.Contains(text.String(), "no differences") {
+		t.Errorf("Expected no differences, got %q (%v)", text.String(), err)
+	}
+}
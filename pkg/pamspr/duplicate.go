@@ -0,0 +1,453 @@
+package pamspr
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DuplicateKey is a payment field compared when looking for duplicate payments
+type DuplicateKey string
+
+const (
+	DuplicateKeyPaymentID               DuplicateKey = "PaymentID"
+	DuplicateKeyTIN                     DuplicateKey = "TIN"
+	DuplicateKeyAmount                  DuplicateKey = "Amount"
+	DuplicateKeyRoutingNumber           DuplicateKey = "RoutingNumber" // ACH only
+	DuplicateKeyAccountNumber           DuplicateKey = "AccountNumber" // ACH only
+	DuplicateKeyPayeeName               DuplicateKey = "PayeeName"
+	DuplicateKeyAgencyAccountIdentifier DuplicateKey = "AgencyAccountIdentifier"
+)
+
+// DefaultDuplicateHistoryWindow is how far back fingerprints from earlier files are matched
+const DefaultDuplicateHistoryWindow = 7 * 24 * time.Hour
+
+// DuplicateRule matches payments whose key fields are all equal. Payments
+// with a blank key field are not matched by the rule, so an ACH rule on
+// RoutingNumber never matches check payments.
+type DuplicateRule struct {
+	Name string         `json:"name"`
+	Keys []DuplicateKey `json:"keys"`
+	// History also matches payments in earlier files kept in the FingerprintStore
+	History bool `json:"history"`
+}
+
+// DefaultDuplicateRules matches repeated PaymentIDs within a file, and the
+// same TIN, amount and bank account within a file or a recent earlier file
+func DefaultDuplicateRules() []DuplicateRule {
+	return []DuplicateRule{
+		{Name: "payment-id", Keys: []DuplicateKey{DuplicateKeyPaymentID}},
+		{
+			Name:    "disbursement",
+			Keys:    []DuplicateKey{DuplicateKeyTIN, DuplicateKeyAmount, DuplicateKeyRoutingNumber, DuplicateKeyAccountNumber},
+			History: true,
+		},
+	}
+}
+
+// DuplicateConfig configures a DuplicateDetector
+type DuplicateConfig struct {
+	Rules []DuplicateRule
+	// Store holds fingerprints of earlier files for rules with History (nil = this file only)
+	Store *FingerprintStore
+	// HistoryWindow ignores stored fingerprints older than this (0 = no limit)
+	HistoryWindow time.Duration
+	// Source names the file being checked in fingerprints it records
+	Source string
+	// Now is when the file is checked (zero = time.Now)
+	Now time.Time
+}
+
+// DefaultDuplicateConfig returns the default rules without a fingerprint store
+func DefaultDuplicateConfig() *DuplicateConfig {
+	return &DuplicateConfig{
+		Rules:         DefaultDuplicateRules(),
+		HistoryWindow: DefaultDuplicateHistoryWindow,
+	}
+}
+
+// Duplicate is a payment matching an earlier payment under a rule
+type Duplicate struct {
+	Rule          string `json:"rule"`
+	ScheduleIndex int    `json:"scheduleIndex"`
+	PaymentIndex  int    `json:"paymentIndex"` // Zero-based within the schedule
+	PaymentID     string `json:"paymentId"`
+	Amount        int64  `json:"amount"`
+
+	// The earlier payment in the same file, NoIndex when matched in the store
+	MatchScheduleIndex int    `json:"matchScheduleIndex"`
+	MatchPaymentIndex  int    `json:"matchPaymentIndex"`
+	MatchPaymentID     string `json:"matchPaymentId,omitempty"`
+
+	// Previous is the stored fingerprint matched from an earlier file
+	Previous *PaymentFingerprint `json:"previous,omitempty"`
+}
+
+// String formats the duplicate as a single line of text
+func (d Duplicate) String() string {
+	where := fmt.Sprintf("schedule[%d] payment[%d] %s (%s)", d.ScheduleIndex, d.PaymentIndex, d.PaymentID, FormatCents(d.Amount))
+	if d.Previous != nil {
+		return fmt.Sprintf("%s: %s matches payment %s in %s seen %s", d.Rule, where,
+			d.Previous.PaymentID, d.Previous.Source, d.Previous.SeenAt.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s: %s matches schedule[%d] payment[%d] %s", d.Rule, where,
+		d.MatchScheduleIndex, d.MatchPaymentIndex, d.MatchPaymentID)
+}
+
+// DuplicateDetector finds payments that repeat earlier payments in the same
+// file or, through a FingerprintStore, in earlier files. Payments are checked
+// one at a time so files can be streamed.
+type DuplicateDetector struct {
+	config     DuplicateConfig
+	key        []byte                  // Store key the fingerprints are hashed with
+	seen       map[string]duplicateRef // Fingerprint to the first payment with it
+	pending    []PaymentFingerprint    // History fingerprints for Record
+	duplicates []Duplicate
+}
+
+type duplicateRef struct {
+	scheduleIndex int
+	paymentIndex  int
+	paymentID     string
+}
+
+// NewDuplicateDetector creates a detector, checking that every rule has a
+// unique name and known keys
+func NewDuplicateDetector(config *DuplicateConfig) (*DuplicateDetector, error) {
+	if config == nil {
+		config = DefaultDuplicateConfig()
+	}
+
+	names := make(map[string]bool, len(config.Rules))
+	for _, rule := range config.Rules {
+		if rule.Name == "" || names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule names must be unique and not blank: %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if len(rule.Keys) == 0 {
+			return nil, fmt.Errorf("duplicate rule %s has no keys", rule.Name)
+		}
+		for _, key := range rule.Keys {
+			if _, ok := duplicateKeyValue(&ACHPayment{}, key); !ok {
+				return nil, fmt.Errorf("duplicate rule %s: unknown key %q", rule.Name, key)
+			}
+		}
+	}
+
+	d := &DuplicateDetector{
+		config: *config,
+		seen:   make(map[string]duplicateRef),
+	}
+	if d.config.Now.IsZero() {
+		d.config.Now = time.Now()
+	}
+	if d.config.Store != nil {
+		d.key = d.config.Store.hashKey()
+	}
+	return d, nil
+}
+
+// Check compares a payment with every payment checked before it and with
+// the store, remembers it, and returns the duplicates it is part of
+func (d *DuplicateDetector) Check(payment Payment, scheduleIndex, paymentIndex int) []Duplicate {
+	var found []Duplicate
+	id := strings.TrimSpace(payment.GetPaymentID())
+
+	for _, rule := range d.config.Rules {
+		hash, ok := fingerprint(d.key, payment, rule)
+		if !ok {
+			continue
+		}
+
+		duplicate := Duplicate{
+			Rule:               rule.Name,
+			ScheduleIndex:      scheduleIndex,
+			PaymentIndex:       paymentIndex,
+			PaymentID:          id,
+			Amount:             payment.GetAmount(),
+			MatchScheduleIndex: NoIndex,
+			MatchPaymentIndex:  NoIndex,
+		}
+
+		if first, ok := d.seen[hash]; ok {
+			duplicate.MatchScheduleIndex = first.scheduleIndex
+			duplicate.MatchPaymentIndex = first.paymentIndex
+			duplicate.MatchPaymentID = first.paymentID
+			found = append(found, duplicate)
+			continue
+		}
+		d.seen[hash] = duplicateRef{scheduleIndex: scheduleIndex, paymentIndex: paymentIndex, paymentID: id}
+
+		if !rule.History || d.config.Store == nil {
+			continue
+		}
+		if previous, ok := d.config.Store.Lookup(hash); ok && d.inWindow(previous) {
+			duplicate.Previous = &previous
+			found = append(found, duplicate)
+		}
+		d.pending = append(d.pending, PaymentFingerprint{
+			Hash:      hash,
+			Rule:      rule.Name,
+			PaymentID: id,
+			Source:    d.config.Source,
+			SeenAt:    d.config.Now,
+		})
+	}
+
+	d.duplicates = append(d.duplicates, found...)
+	return found
+}
+
+func (d *DuplicateDetector) inWindow(previous PaymentFingerprint) bool {
+	return d.config.HistoryWindow <= 0 || !previous.SeenAt.Before(d.config.Now.Add(-d.config.HistoryWindow))
+}
+
+// Duplicates returns every duplicate found so far, in the order checked
+func (d *DuplicateDetector) Duplicates() []Duplicate {
+	return d.duplicates
+}
+
+// CheckFile checks every payment in file
+func (d *DuplicateDetector) CheckFile(file *File) []Duplicate {
+	var found []Duplicate
+	for i, schedule := range file.Schedules {
+		for j, payment := range schedule.GetPayments() {
+			found = append(found, d.Check(payment, i, j)...)
+		}
+	}
+	return found
+}
+
+// CheckReader streams the payments of a file through ProcessPaymentsOnly,
+// holding only their fingerprints in memory
+func (d *DuplicateDetector) CheckReader(reader *Reader) ([]Duplicate, error) {
+	var found []Duplicate
+	schedule, index := NoIndex, 0
+	err := reader.ProcessPaymentsOnly(func(payment Payment, scheduleIndex, _ int) bool {
+		if scheduleIndex != schedule {
+			schedule, index = scheduleIndex, 0
+		}
+		found = append(found, d.Check(payment, scheduleIndex, index)...)
+		index++
+		return true
+	})
+	return found, err
+}
+
+// Record adds the fingerprints of the payments checked since the last Record
+// to the store, so later files are checked against them. Call it once the
+// file is accepted for transmission.
+func (d *DuplicateDetector) Record() error {
+	if d.config.Store == nil {
+		return errors.New("no fingerprint store configured")
+	}
+	for _, fp := range d.pending {
+		d.config.Store.Add(fp)
+	}
+	d.pending = nil
+	return nil
+}
+
+// fingerprint hashes the rule name and the payment's key values with an
+// HMAC keyed by the store, so stored fingerprints cannot be matched against
+// guessed TINs or account numbers without the store's key
+func fingerprint(key []byte, payment Payment, rule DuplicateRule) (string, bool) {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(rule.Name))
+	for _, key := range rule.Keys {
+		value, _ := duplicateKeyValue(payment, key)
+		if value == "" {
+			return "", false
+		}
+		h.Write([]byte{0})
+		h.Write([]byte(value))
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// duplicateKeyValue returns a payment's key field, trimmed and upper-cased.
+// ok is false for keys the detector does not know.
+func duplicateKeyValue(payment Payment, key DuplicateKey) (value string, ok bool) {
+	switch key {
+	case DuplicateKeyPaymentID:
+		value = payment.GetPaymentID()
+	case DuplicateKeyAmount:
+		value = strconv.FormatInt(payment.GetAmount(), 10)
+	case DuplicateKeyPayeeName:
+		value = payment.GetPayeeName()
+	case DuplicateKeyTIN:
+		switch p := payment.(type) {
+		case *ACHPayment:
+			value = p.TIN
+		case *CheckPayment:
+			value = p.TIN
+		}
+	case DuplicateKeyAgencyAccountIdentifier:
+		switch p := payment.(type) {
+		case *ACHPayment:
+			value = p.AgencyAccountIdentifier
+		case *CheckPayment:
+			value = p.AgencyAccountIdentifier
+		}
+	case DuplicateKeyRoutingNumber:
+		if p, ok := payment.(*ACHPayment); ok {
+			value = p.RoutingNumber
+		}
+	case DuplicateKeyAccountNumber:
+		if p, ok := payment.(*ACHPayment); ok {
+			value = p.AccountNumber
+		}
+	default:
+		return "", false
+	}
+	return strings.ToUpper(strings.TrimSpace(value)), true
+}
+
+// PaymentFingerprint records a payment's match key from an earlier file
+type PaymentFingerprint struct {
+	Hash      string    `json:"hash"` // HMAC-SHA-256 of the rule name and key values
+	Rule      string    `json:"rule"`
+	PaymentID string    `json:"paymentId,omitempty"`
+	Source    string    `json:"source,omitempty"` // File the payment was in
+	SeenAt    time.Time `json:"seenAt"`
+}
+
+// FingerprintStore is a local record of payment fingerprints used to catch
+// payments repeated from earlier files. The store holds the key its
+// fingerprints are hashed with, so it must be kept as private as the files.
+type FingerprintStore struct {
+	mu           sync.Mutex
+	Key          string               `json:"key"` // Hex HMAC key, random per store
+	Fingerprints []PaymentFingerprint `json:"fingerprints"`
+	index        map[string]int       // Hash to its latest fingerprint
+}
+
+// NewFingerprintStore creates an empty store with a new random key
+func NewFingerprintStore() *FingerprintStore {
+	return &FingerprintStore{Key: newFingerprintKey()}
+}
+
+// ReadFingerprintStore decodes a store written by WriteTo. A store without
+// a key is given a new one, so fingerprints recorded before it never match.
+func ReadFingerprintStore(r io.Reader) (*FingerprintStore, error) {
+	store := &FingerprintStore{}
+	if err := json.NewDecoder(r).Decode(store); err != nil {
+		return nil, fmt.Errorf("reading fingerprint store: %w", err)
+	}
+	if store.Key == "" {
+		store.Key = newFingerprintKey()
+	}
+	if key, err := hex.DecodeString(store.Key); err != nil || len(key) == 0 {
+		return nil, errors.New("reading fingerprint store: key is not hex")
+	}
+	return store, nil
+}
+
+func newFingerprintKey() string {
+	key := make([]byte, sha256.Size)
+	rand.Read(key) // Never fails; it panics if the system source is broken
+	return hex.EncodeToString(key)
+}
+
+// hashKey returns the decoded key, creating one for a store built as a literal
+func (s *FingerprintStore) hashKey() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Key == "" {
+		s.Key = newFingerprintKey()
+	}
+	key, _ := hex.DecodeString(s.Key)
+	return key
+}
+
+// OpenFingerprintStore loads a store file, returning an empty store if it does not exist
+func OpenFingerprintStore(path string) (*FingerprintStore, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewFingerprintStore(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFingerprintStore(f)
+}
+
+// WriteTo encodes the store as JSON
+func (s *FingerprintStore) WriteTo(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// Save writes the store to path, replacing the previous contents
+func (s *FingerprintStore) Save(path string) error {
+	return saveFile(path, s)
+}
+
+// Lookup returns the latest fingerprint recorded with hash
+func (s *FingerprintStore) Lookup(hash string) (PaymentFingerprint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buildIndex()
+	i, ok := s.index[hash]
+	if !ok {
+		return PaymentFingerprint{}, false
+	}
+	return s.Fingerprints[i], true
+}
+
+// Add records a fingerprint
+func (s *FingerprintStore) Add(fp PaymentFingerprint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buildIndex()
+	s.Fingerprints = append(s.Fingerprints, fp)
+	if i, ok := s.index[fp.Hash]; !ok || !fp.SeenAt.Before(s.Fingerprints[i].SeenAt) {
+		s.index[fp.Hash] = len(s.Fingerprints) - 1
+	}
+}
+
+// Prune drops fingerprints seen before the given time and returns how many were dropped
+func (s *FingerprintStore) Prune(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.Fingerprints[:0]
+	for _, fp := range s.Fingerprints {
+		if !fp.SeenAt.Before(before) {
+			kept = append(kept, fp)
+		}
+	}
+	dropped := len(s.Fingerprints) - len(kept)
+	s.Fingerprints = kept
+	s.index = nil
+	return dropped
+}
+
+func (s *FingerprintStore) buildIndex() {
+	if s.index != nil {
+		return
+	}
+	s.index = make(map[string]int, len(s.Fingerprints))
+	for i, fp := range s.Fingerprints {
+		if j, ok := s.index[fp.Hash]; !ok || !fp.SeenAt.Before(s.Fingerprints[j].SeenAt) {
+			s.index[fp.Hash] = i
+		}
+	}
+}
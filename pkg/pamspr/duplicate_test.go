@@ -0,0 +1,275 @@
+package pamspr
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// duplicateTestFile returns an ACH file whose second payment repeats the
+// first payment's TIN, amount and bank account under another PaymentID
+func duplicateTestFile() *File {
+	file := createTestACHFile()
+	payments := file.Schedules[0].(*ACHSchedule).Payments
+	first := payments[0].(*ACHPayment)
+	first.TIN = "123456789"
+	second := *first
+	second.PaymentID = "PAY002"
+	payments[1] = &second
+	return file
+}
+
+func TestDuplicateDetector(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []DuplicateRule
+		change func(file *File)
+		want   []string // Rule and PaymentID of each duplicate
+	}{
+		{
+			name: "No duplicates",
+			change: func(file *File) {
+				file.Schedules[0].(*ACHSchedule).Payments[1].(*ACHPayment).AccountNumber = "555"
+			},
+		},
+		{
+			name: "Same disbursement",
+			want: []string{"disbursement PAY002"},
+		},
+		{
+			name: "Repeated PaymentID across schedules",
+			change: func(file *File) {
+				other := createTestACHFile().Schedules[0]
+				file.Schedules = append(file.Schedules, other)
+			},
+			want: []string{"disbursement PAY002", "payment-id PAY001", "payment-id PAY002"},
+		},
+		{
+			name: "Blank keys never match",
+			change: func(file *File) {
+				for _, payment := range file.Schedules[0].GetPayments() {
+					payment.(*ACHPayment).TIN = ""
+				}
+			},
+		},
+		{
+			name:  "Custom keys",
+			rules: []DuplicateRule{{Name: "payee", Keys: []DuplicateKey{DuplicateKeyPayeeName, DuplicateKeyAmount}}},
+			change: func(file *File) {
+				file.Schedules[0].(*ACHSchedule).Payments[1].(*ACHPayment).PayeeName = " test payee 1 "
+			},
+			want: []string{"payee PAY002"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := duplicateTestFile()
+			if tt.change != nil {
+				tt.change(file)
+			}
+			config := DefaultDuplicateConfig()
+			if tt.rules != nil {
+				config.Rules = tt.rules
+			}
+			detector, err := NewDuplicateDetector(config)
+			if err != nil {
+				t.Fatalf("NewDuplicateDetector failed: %v", err)
+			}
+
+			var got []string
+			for _, d := range detector.CheckFile(file) {
+				got = append(got, d.Rule+" "+d.PaymentID)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Expected [%s], got [%s]", strings.Join(tt.want, ", "), strings.Join(got, ", "))
+			}
+		})
+	}
+}
+
+func TestDuplicateDetectorMatch(t *testing.T) {
+	file := duplicateTestFile()
+	file.Schedules = append(file.Schedules, duplicateTestFile().Schedules[0])
+
+	detector, err := NewDuplicateDetector(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := detector.CheckFile(file)
+	if len(found) != 5 {
+		t.Fatalf("Expected 5 duplicates, got %v", found)
+	}
+
+	want := "payment-id: schedule[1] payment[0] PAY001 (1000.00) matches schedule[0] payment[0] PAY001"
+	if got := found[1].String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if len(detector.Duplicates()) != len(found) {
+		t.Errorf("Expected Duplicates to return every duplicate found")
+	}
+}
+
+func TestDuplicateDetectorHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+
+	check := func(at time.Time, source string, record bool) []Duplicate {
+		t.Helper()
+		store, err := OpenFingerprintStore(path)
+		if err != nil {
+			t.Fatalf("OpenFingerprintStore failed: %v", err)
+		}
+		config := DefaultDuplicateConfig()
+		config.Store, config.Source, config.Now = store, source, at
+		detector, err := NewDuplicateDetector(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := duplicateTestFile()
+		file.Schedules[0].(*ACHSchedule).Payments = file.Schedules[0].GetPayments()[:1]
+		found := detector.CheckFile(file)
+		if record {
+			if err := detector.Record(); err != nil {
+				t.Fatalf("Record failed: %v", err)
+			}
+			if err := store.Save(path); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+		return found
+	}
+
+	if found := check(monday, "monday.spr", true); len(found) != 0 {
+		t.Fatalf("Expected no duplicates in an empty store, got %v", found)
+	}
+
+	found := check(monday.Add(24*time.Hour), "tuesday.spr", false)
+	if len(found) != 1 || found[0].Previous == nil {
+		t.Fatalf("Expected the disbursement to match Monday's file, got %v", found)
+	}
+	if prev := found[0].Previous; prev.Source != "monday.spr" || prev.PaymentID != "PAY001" || !prev.SeenAt.Equal(monday) {
+		t.Errorf("Unexpected previous fingerprint %+v", *prev)
+	}
+	if !strings.Contains(found[0].String(), "in monday.spr seen 2026-10-12T09:00:00Z") {
+		t.Errorf("Unexpected description %q", found[0].String())
+	}
+
+	if found := check(monday.Add(8*24*time.Hour), "next-week.spr", false); len(found) != 0 {
+		t.Errorf("Expected fingerprints outside the window to be ignored, got %v", found)
+	}
+
+	store, err := OpenFingerprintStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := store.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "123456789") || strings.Contains(buf.String(), "1234567890") {
+		t.Errorf("Store holds payment data in clear:\n%s", buf.String())
+	}
+	if dropped := store.Prune(monday.Add(time.Hour)); dropped != 1 || len(store.Fingerprints) != 0 {
+		t.Errorf("Expected Prune to drop the fingerprint, dropped %d", dropped)
+	}
+}
+
+func TestFingerprintStoreKey(t *testing.T) {
+	hashes := func(store *FingerprintStore) string {
+		t.Helper()
+		config := DefaultDuplicateConfig()
+		config.Store = store
+		detector, err := NewDuplicateDetector(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		detector.CheckFile(duplicateTestFile())
+		var all []string
+		for _, fp := range detector.pending {
+			all = append(all, fp.Hash)
+		}
+		return strings.Join(all, ",")
+	}
+
+	first, second := NewFingerprintStore(), NewFingerprintStore()
+	if first.Key == "" || first.Key == second.Key {
+		t.Fatalf("Expected a random key per store, got %q and %q", first.Key, second.Key)
+	}
+	if hashes(first) == hashes(second) {
+		t.Error("Expected stores with different keys to hash payments differently")
+	}
+
+	var buf bytes.Buffer
+	if _, err := first.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := ReadFingerprintStore(&buf)
+	if err != nil {
+		t.Fatalf("ReadFingerprintStore failed: %v", err)
+	}
+	if hashes(reopened) != hashes(first) {
+		t.Error("Expected a reopened store to keep its key")
+	}
+
+	legacy, err := ReadFingerprintStore(strings.NewReader(`{"fingerprints":[]}`))
+	if err != nil || legacy.Key == "" {
+		t.Errorf("Expected a store without a key to get one, got %q, %v", legacy.Key, err)
+	}
+	if _, err := ReadFingerprintStore(strings.NewReader(`{"key":"not hex"}`)); err == nil {
+		t.Error("Expected an error for a key that is not hex")
+	}
+}
+
+func TestDuplicateDetectorCheckReader(t *testing.T) {
+	var buf bytes.Buffer
+	file := duplicateTestFile()
+	file.Schedules = append(file.Schedules, createTestCheckFile().Schedules[0])
+	file.Schedules[1].(*CheckSchedule).Header.ScheduleNumber = "00000000000002"
+	file.Schedules[1].(*CheckSchedule).Payments[0].(*CheckPayment).PaymentID = "PAY001"
+	file.Trailer = mergedTrailer(file)
+	if err := NewWriter(&buf).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	detector, err := NewDuplicateDetector(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := detector.CheckReader(NewReader(&buf))
+	if err != nil {
+		t.Fatalf("CheckReader failed: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 duplicates, got %v", found)
+	}
+	if d := found[1]; d.Rule != "payment-id" || d.ScheduleIndex != 1 || d.PaymentIndex != 0 || d.MatchScheduleIndex != 0 {
+		t.Errorf("Unexpected duplicate %s", d)
+	}
+}
+
+func TestNewDuplicateDetectorErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []DuplicateRule
+	}{
+		{"Blank name", []DuplicateRule{{Keys: []DuplicateKey{DuplicateKeyTIN}}}},
+		{"Repeated name", []DuplicateRule{{Name: "a", Keys: []DuplicateKey{DuplicateKeyTIN}}, {Name: "a", Keys: []DuplicateKey{DuplicateKeyAmount}}}},
+		{"No keys", []DuplicateRule{{Name: "a"}}},
+		{"Unknown key", []DuplicateRule{{Name: "a", Keys: []DuplicateKey{"Reconcilement"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDuplicateDetector(&DuplicateConfig{Rules: tt.rules}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	detector, _ := NewDuplicateDetector(nil)
+	if err := detector.Record(); err == nil {
+		t.Error("Expected Record to fail without a store")
+	}
+}
@@ -0,0 +1,215 @@
+package pamspr
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encoding selects the character set of an SPR file on disk
+type Encoding int
+
+const (
+	EncodingASCII  Encoding = iota // ASCII/ISO-8859-1, one byte per column (default)
+	EncodingCP037                  // EBCDIC code page 037 (US/Canada)
+	EncodingCP1047                 // EBCDIC code page 1047 (Latin-1 Open Systems)
+)
+
+// String returns the code page name
+func (e Encoding) String() string {
+	switch e {
+	case EncodingASCII:
+		return "ascii"
+	case EncodingCP037:
+		return "cp037"
+	case EncodingCP1047:
+		return "cp1047"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// IsEBCDIC reports whether the encoding is an EBCDIC code page
+func (e Encoding) IsEBCDIC() bool {
+	return e == EncodingCP037 || e == EncodingCP1047
+}
+
+// ParseEncoding parses an encoding name such as "ascii", "cp037" or "cp1047"
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "ascii", "latin1", "iso-8859-1":
+		return EncodingASCII, nil
+	case "cp037", "ibm037", "ebcdic":
+		return EncodingCP037, nil
+	case "cp1047", "ibm1047":
+		return EncodingCP1047, nil
+	default:
+		return EncodingASCII, fmt.Errorf("unknown encoding %q (want ascii, cp037 or cp1047)", name)
+	}
+}
+
+// cp037ToLatin1 maps each CP037 byte to its ISO-8859-1 byte
+var cp037ToLatin1 = [256]byte{
+	0x00, 0x01, 0x02, 0x03, 0x9C, 0x09, 0x86, 0x7F, 0x97, 0x8D, 0x8E, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, // 0x00
+	0x10, 0x11, 0x12, 0x13, 0x9D, 0x85, 0x08, 0x87, 0x18, 0x19, 0x92, 0x8F, 0x1C, 0x1D, 0x1E, 0x1F, // 0x10
+	0x80, 0x81, 0x82, 0x83, 0x84, 0x0A, 0x17, 0x1B, 0x88, 0x89, 0x8A, 0x8B, 0x8C, 0x05, 0x06, 0x07, // 0x20
+	0x90, 0x91, 0x16, 0x93, 0x94, 0x95, 0x96, 0x04, 0x98, 0x99, 0x9A, 0x9B, 0x14, 0x15, 0x9E, 0x1A, // 0x30
+	0x20, 0xA0, 0xE2, 0xE4, 0xE0, 0xE1, 0xE3, 0xE5, 0xE7, 0xF1, 0xA2, 0x2E, 0x3C, 0x28, 0x2B, 0x7C, // 0x40
+	0x26, 0xE9, 0xEA, 0xEB, 0xE8, 0xED, 0xEE, 0xEF, 0xEC, 0xDF, 0x21, 0x24, 0x2A, 0x29, 0x3B, 0xAC, // 0x50
+	0x2D, 0x2F, 0xC2, 0xC4, 0xC0, 0xC1, 0xC3, 0xC5, 0xC7, 0xD1, 0xA6, 0x2C, 0x25, 0x5F, 0x3E, 0x3F, // 0x60
+	0xF8, 0xC9, 0xCA, 0xCB, 0xC8, 0xCD, 0xCE, 0xCF, 0xCC, 0x60, 0x3A, 0x23, 0x40, 0x27, 0x3D, 0x22, // 0x70
+	0xD8, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0xAB, 0xBB, 0xF0, 0xFD, 0xFE, 0xB1, // 0x80
+	0xB0, 0x6A, 0x6B, 0x6C, 0x6D, 0x6E, 0x6F, 0x70, 0x71, 0x72, 0xAA, 0xBA, 0xE6, 0xB8, 0xC6, 0xA4, // 0x90
+	0xB5, 0x7E, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79, 0x7A, 0xA1, 0xBF, 0xD0, 0xDD, 0xDE, 0xAE, // 0xA0
+	0x5E, 0xA3, 0xA5, 0xB7, 0xA9, 0xA7, 0xB6, 0xBC, 0xBD, 0xBE, 0x5B, 0x5D, 0xAF, 0xA8, 0xB4, 0xD7, // 0xB0
+	0x7B, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0xAD, 0xF4, 0xF6, 0xF2, 0xF3, 0xF5, // 0xC0
+	0x7D, 0x4A, 0x4B, 0x4C, 0x4D, 0x4E, 0x4F, 0x50, 0x51, 0x52, 0xB9, 0xFB, 0xFC, 0xF9, 0xFA, 0xFF, // 0xD0
+	0x5C, 0xF7, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5A, 0xB2, 0xD4, 0xD6, 0xD2, 0xD3, 0xD5, // 0xE0
+	0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0xB3, 0xDB, 0xDC, 0xD9, 0xDA, 0x9F, // 0xF0
+}
+
+// cp1047ToLatin1 differs from CP037 in six positions: ^ ¬ [ ] Ý ¨
+var cp1047ToLatin1 = func() [256]byte {
+	t := cp037ToLatin1
+	t[0x5F] = 0x5E // ^
+	t[0xAD] = 0x5B // [
+	t[0xB0] = 0xAC // ¬
+	t[0xBA] = 0xDD // Ý
+	t[0xBB] = 0xA8 // ¨
+	t[0xBD] = 0x5D // ]
+	return t
+}()
+
+var (
+	latin1ToCP037  = invertCodePage(&cp037ToLatin1)
+	latin1ToCP1047 = invertCodePage(&cp1047ToLatin1)
+)
+
+func invertCodePage(decode *[256]byte) [256]byte {
+	var encode [256]byte
+	for ebcdic, latin1 := range decode {
+		encode[latin1] = byte(ebcdic)
+	}
+	return encode
+}
+
+// decodeTable returns the EBCDIC to ISO-8859-1 table, or nil for ASCII
+func (e Encoding) decodeTable() *[256]byte {
+	switch e {
+	case EncodingCP037:
+		return &cp037ToLatin1
+	case EncodingCP1047:
+		return &cp1047ToLatin1
+	default:
+		return nil
+	}
+}
+
+// encodeTable returns the ISO-8859-1 to EBCDIC table, or nil for ASCII
+func (e Encoding) encodeTable() *[256]byte {
+	switch e {
+	case EncodingCP037:
+		return &latin1ToCP037
+	case EncodingCP1047:
+		return &latin1ToCP1047
+	default:
+		return nil
+	}
+}
+
+// hexTable returns the table used for the hexadecimal character rule, which is
+// stated in EBCDIC. ASCII files are checked as CP037, the code page PAM stores them in.
+func (e Encoding) hexTable() *[256]byte {
+	if e == EncodingCP1047 {
+		return &latin1ToCP1047
+	}
+	return &latin1ToCP037
+}
+
+func (e Encoding) hexTableName() string {
+	if e == EncodingCP1047 {
+		return "CP1047"
+	}
+	return "CP037"
+}
+
+// EncodeEBCDIC converts ISO-8859-1 data to the encoding's EBCDIC bytes.
+// ASCII data is returned unchanged.
+func (e Encoding) EncodeEBCDIC(data []byte) []byte {
+	out := make([]byte, len(data))
+	transcode(out, data, e.encodeTable())
+	return out
+}
+
+// DecodeEBCDIC converts EBCDIC bytes to ISO-8859-1, one byte per column.
+// ASCII data is returned unchanged.
+func (e Encoding) DecodeEBCDIC(data []byte) []byte {
+	out := make([]byte, len(data))
+	transcode(out, data, e.decodeTable())
+	return out
+}
+
+func transcode(dst, src []byte, table *[256]byte) {
+	if table == nil {
+		copy(dst, src)
+		return
+	}
+	for i, b := range src {
+		dst[i] = table[b]
+	}
+}
+
+// ebcdicReader decodes an EBCDIC stream to ISO-8859-1. NEL (0x15), the z/OS
+// UNIX line end, is read as a newline along with LF (0x25).
+type ebcdicReader struct {
+	r     io.Reader
+	table *[256]byte
+}
+
+// NewReader returns a reader that decodes r to the text the Reader works
+// with; ASCII input is returned unchanged
+func (e Encoding) NewReader(r io.Reader) io.Reader {
+	return newDecodingReader(r, e)
+}
+
+func newDecodingReader(r io.Reader, e Encoding) io.Reader {
+	if table := e.decodeTable(); table != nil {
+		return &ebcdicReader{r: r, table: table}
+	}
+	return r
+}
+
+func (er *ebcdicReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	for i, b := range p[:n] {
+		if b == 0x15 {
+			p[i] = '\n'
+			continue
+		}
+		p[i] = er.table[b]
+	}
+	return n, err
+}
+
+// ebcdicWriter encodes ISO-8859-1 output to EBCDIC
+type ebcdicWriter struct {
+	w     io.Writer
+	table *[256]byte
+	buf   []byte
+}
+
+func newEncodingWriter(w io.Writer, e Encoding) io.Writer {
+	if table := e.encodeTable(); table != nil {
+		return &ebcdicWriter{w: w, table: table}
+	}
+	return w
+}
+
+func (ew *ebcdicWriter) Write(p []byte) (int, error) {
+	if cap(ew.buf) < len(p) {
+		ew.buf = make([]byte, len(p))
+	}
+	buf := ew.buf[:len(p)]
+	transcode(buf, p, ew.table)
+	return ew.w.Write(buf)
+}
@@ -0,0 +1,167 @@
+package pamspr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  Encoding
+		expectErr bool
+	}{
+		{"", EncodingASCII, false},
+		{"ascii", EncodingASCII, false},
+		{"CP037", EncodingCP037, false},
+		{"ebcdic", EncodingCP037, false},
+		{"cp1047", EncodingCP1047, false},
+		{"utf-16", EncodingASCII, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEncoding(tt.name)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("ParseEncoding(%q) = %v, %v; want %v", tt.name, got, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodeEBCDIC(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding Encoding
+		data     string
+		expected []byte
+	}{
+		{"Letters", EncodingCP037, "Aa", []byte{0xC1, 0x81}},
+		{"Space and digits", EncodingCP037, " 09", []byte{0x40, 0xF0, 0xF9}},
+		{"Newline", EncodingCP037, "\n", []byte{0x25}},
+		{"CP037 brackets", EncodingCP037, "[]^", []byte{0xBA, 0xBB, 0xB0}},
+		{"CP1047 brackets", EncodingCP1047, "[]^", []byte{0xAD, 0xBD, 0x5F}},
+		{"CP1047 shared", EncodingCP1047, "H 1", []byte{0xC8, 0x40, 0xF1}},
+		{"ASCII unchanged", EncodingASCII, "H 1", []byte("H 1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.encoding.EncodeEBCDIC([]byte(tt.data))
+			if !bytes.Equal(got, tt.expected) {
+				t.Errorf("Expected % X, got % X", tt.expected, got)
+			}
+			if back := tt.encoding.DecodeEBCDIC(got); string(back) != tt.data {
+				t.Errorf("Round trip: expected %q, got %q", tt.data, back)
+			}
+		})
+	}
+
+	// Every byte survives a round trip in both code pages
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	for _, e := range []Encoding{EncodingCP037, EncodingCP1047} {
+		if back := e.DecodeEBCDIC(e.EncodeEBCDIC(all)); !bytes.Equal(back, all) {
+			t.Errorf("%s is not a one-to-one mapping", e)
+		}
+	}
+}
+
+func TestEBCDICReadWrite(t *testing.T) {
+	file := createTestACHFile()
+
+	var ascii bytes.Buffer
+	if err := NewWriter(&ascii).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	for _, e := range []Encoding{EncodingCP037, EncodingCP1047} {
+		t.Run(e.String(), func(t *testing.T) {
+			config := DefaultWriterConfig()
+			config.Encoding = e
+			var buf bytes.Buffer
+			if err := NewWriterWithConfig(&buf, config).Write(file); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+
+			data := buf.Bytes()
+			if !bytes.Equal(data, e.EncodeEBCDIC(ascii.Bytes())) {
+				t.Fatal("EBCDIC output does not match the transcoded ASCII output")
+			}
+			if data[0] != 0xC8 || data[RecordLength] != 0x25 {
+				t.Errorf("Expected EBCDIC 'H' and LF, got 0x%02X and 0x%02X", data[0], data[RecordLength])
+			}
+
+			readerConfig := DefaultConfig()
+			readerConfig.Encoding = e
+			reader := NewReaderWithConfig(bytes.NewReader(data), readerConfig)
+			read, err := reader.Read()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if read.Trailer.TotalAmountPayments != file.Trailer.TotalAmountPayments {
+				t.Errorf("Expected amount %d, got %d", file.Trailer.TotalAmountPayments, read.Trailer.TotalAmountPayments)
+			}
+			for _, issue := range reader.GetValidationReport().Issues {
+				if issue.Code == CodeInvalidHexCharacter {
+					t.Errorf("Unexpected hex issue: %s", issue)
+				}
+			}
+
+			// z/OS UNIX files end lines with NEL (0x15)
+			nel := bytes.ReplaceAll(data, []byte{0x25}, []byte{0x15})
+			reader = NewReaderWithConfig(bytes.NewReader(nel), readerConfig)
+			if _, err := reader.Read(); err != nil {
+				t.Errorf("Read with NEL line ends failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestReaderHexCharacters(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestACHFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// A tab is EBCDIC 0x05 once the file reaches PAM
+	lines := strings.Split(buf.String(), "\n")
+	lines[2] = lines[2][:40] + "\t" + lines[2][41:]
+
+	reader := NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	if err := reader.ProcessFile(nil, nil, nil); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	var found bool
+	for _, issue := range reader.GetValidationReport().Issues {
+		if issue.Code == CodeInvalidHexCharacter {
+			found = true
+			if issue.LineNumber != 3 || issue.RecordCode != "02" {
+				t.Errorf("Unexpected hex issue location: %s", issue)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected an invalid hex character issue")
+	}
+
+	// The writer refuses to produce the same record
+	file := createTestACHFile()
+	file.Schedules[0].GetPayments()[0].(*ACHPayment).PayeeName = "TEST\tPAYEE"
+	err := NewWriter(&bytes.Buffer{}).Write(file)
+	var ve ValidationError
+	if !errors.As(err, &ve) || ve.Code != CodeInvalidHexCharacter {
+		t.Errorf("Expected hex character error from writer, got %v", err)
+	}
+}
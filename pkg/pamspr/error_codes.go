@@ -0,0 +1,134 @@
+package pamspr
+
+import "fmt"
+
+// ErrorCode identifies a Treasury PAM "Error Reason Group / Message" pair.
+// PAM reports these codes on the rejection reports it sends back to agencies,
+// so matching them lets pre-submission errors be lined up with Treasury's.
+type ErrorCode struct {
+	Group   int `json:"group"`
+	Message int `json:"message"`
+}
+
+// String returns the code in the wording used by the specification
+func (c ErrorCode) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("Error Reason Group %d Message %d", c.Group, c.Message)
+}
+
+// IsZero reports whether the code is unset
+func (c ErrorCode) IsZero() bool {
+	return c.Group == 0 && c.Message == 0
+}
+
+// RejectionScope describes what PAM rejects when a rule fails
+type RejectionScope int
+
+const (
+	RejectionUnknown  RejectionScope = iota // No PAM result is defined for the rule
+	RejectionPayment                        // Payment is marked invalid
+	RejectionSchedule                       // Schedule is rejected
+	RejectionFile                           // Entire file is rejected
+)
+
+// String returns a readable name for the rejection scope
+func (s RejectionScope) String() string {
+	switch s {
+	case RejectionPayment:
+		return "payment"
+	case RejectionSchedule:
+		return "schedule"
+	case RejectionFile:
+		return "file"
+	case RejectionUnknown:
+		return "unknown"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the scope by name
+func (s RejectionScope) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a scope name produced by MarshalText
+func (s *RejectionScope) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "payment":
+		*s = RejectionPayment
+	case "schedule":
+		*s = RejectionSchedule
+	case "file":
+		*s = RejectionFile
+	case "unknown", "":
+		*s = RejectionUnknown
+	default:
+		return fmt.Errorf("unknown rejection scope %q", text)
+	}
+	return nil
+}
+
+// Error codes defined by the SPR specification (sections 1.3 - 1.6 and the record layouts)
+var (
+	CodeRecordMissingOrOutOfOrder     = ErrorCode{Group: 1, Message: 4}
+	CodeInvalidHexCharacter           = ErrorCode{Group: 1, Message: 5}
+	CodeInvalidRecord                 = ErrorCode{Group: 1, Message: 6}
+	CodeRoutingNumberOrder            = ErrorCode{Group: 1, Message: 7}
+	CodeDuplicateScheduleInFile       = ErrorCode{Group: 2, Message: 1}
+	CodeDuplicateScheduleInFiscalYear = ErrorCode{Group: 2, Message: 2}
+	CodeFileAmountMismatch            = ErrorCode{Group: 3, Message: 1}
+	CodeFileCountMismatch             = ErrorCode{Group: 3, Message: 2}
+	CodeCheckScheduleAmountMismatch   = ErrorCode{Group: 3, Message: 3}
+	CodeCheckScheduleCountMismatch    = ErrorCode{Group: 3, Message: 4}
+	CodeACHScheduleAmountMismatch     = ErrorCode{Group: 3, Message: 5}
+	CodeACHScheduleCountMismatch      = ErrorCode{Group: 3, Message: 6}
+	CodeCTXZeroDollarCredit           = ErrorCode{Group: 4, Message: 3}
+	CodeZeroAmountNotPrenote          = ErrorCode{Group: 4, Message: 4}
+	CodePrenoteNonZeroAmount          = ErrorCode{Group: 4, Message: 5}
+	CodeSDANonACHSchedule             = ErrorCode{Group: 4, Message: 7}
+	CodeSDAAmountExceeded             = ErrorCode{Group: 4, Message: 8}
+	CodeSDAPaymentType                = ErrorCode{Group: 4, Message: 9}
+	CodeSDAIATNotAllowed              = ErrorCode{Group: 4, Message: 10}
+	CodeInvalidPaymentData            = ErrorCode{Group: 5, Message: 3}
+)
+
+// ErrorCatalogEntry describes a Treasury error code and its default result
+type ErrorCatalogEntry struct {
+	Code        ErrorCode
+	Scope       RejectionScope
+	Description string
+}
+
+// ErrorCatalog lists every PAM error code referenced by the specification.
+// Scope is the default result; some Group 1 Message 6 rules reject only the
+// schedule and say so on the individual ValidationError.
+var ErrorCatalog = map[ErrorCode]ErrorCatalogEntry{
+	CodeRecordMissingOrOutOfOrder:     {CodeRecordMissingOrOutOfOrder, RejectionFile, "record is missing or out of order"},
+	CodeInvalidHexCharacter:           {CodeInvalidHexCharacter, RejectionFile, "data contains invalid hexadecimal characters"},
+	CodeInvalidRecord:                 {CodeInvalidRecord, RejectionFile, "record or field value is invalid"},
+	CodeRoutingNumberOrder:            {CodeRoutingNumberOrder, RejectionFile, "ACH payments are not in routing number order"},
+	CodeDuplicateScheduleInFile:       {CodeDuplicateScheduleInFile, RejectionSchedule, "schedule number is duplicated within the file"},
+	CodeDuplicateScheduleInFiscalYear: {CodeDuplicateScheduleInFiscalYear, RejectionSchedule, "schedule number is duplicated within the fiscal year"},
+	CodeFileAmountMismatch:            {CodeFileAmountMismatch, RejectionFile, "file trailer amount does not match payments"},
+	CodeFileCountMismatch:             {CodeFileCountMismatch, RejectionFile, "file trailer counts do not match records or payments"},
+	CodeCheckScheduleAmountMismatch:   {CodeCheckScheduleAmountMismatch, RejectionSchedule, "check schedule trailer amount does not match payments"},
+	CodeCheckScheduleCountMismatch:    {CodeCheckScheduleCountMismatch, RejectionSchedule, "check schedule trailer count does not match payments"},
+	CodeACHScheduleAmountMismatch:     {CodeACHScheduleAmountMismatch, RejectionSchedule, "ACH schedule trailer amount does not match payments"},
+	CodeACHScheduleCountMismatch:      {CodeACHScheduleCountMismatch, RejectionSchedule, "ACH schedule trailer count does not match payments"},
+	CodeCTXZeroDollarCredit:           {CodeCTXZeroDollarCredit, RejectionFile, "CTX payment with an amount uses a zero dollar credit transaction code"},
+	CodeZeroAmountNotPrenote:          {CodeZeroAmountNotPrenote, RejectionFile, "zero dollar payment is neither a prenote nor CTX"},
+	CodePrenoteNonZeroAmount:          {CodePrenoteNonZeroAmount, RejectionFile, "prenote schedule contains a non-zero payment"},
+	CodeSDANonACHSchedule:             {CodeSDANonACHSchedule, RejectionFile, "Same Day ACH file contains a non-ACH schedule"},
+	CodeSDAAmountExceeded:             {CodeSDAAmountExceeded, RejectionFile, "payment exceeds the Same Day ACH maximum amount"},
+	CodeSDAPaymentType:                {CodeSDAPaymentType, RejectionFile, "payment type is not allowed for Same Day ACH"},
+	CodeSDAIATNotAllowed:              {CodeSDAIATNotAllowed, RejectionFile, "IAT is not allowed for Same Day ACH"},
+	CodeInvalidPaymentData:            {CodeInvalidPaymentData, RejectionPayment, "payment data is invalid"},
+}
+
+// LookupErrorCode returns the catalog entry for a group and message
+func LookupErrorCode(group, message int) (ErrorCatalogEntry, bool) {
+	entry, ok := ErrorCatalog[ErrorCode{Group: group, Message: message}]
+	return entry, ok
+}
@@ -0,0 +1,241 @@
+package pamspr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrorCodeString(t *testing.T) {
+	if got := CodeACHScheduleAmountMismatch.String(); got != "Error Reason Group 3 Message 5" {
+		t.Errorf("Unexpected code string: %q", got)
+	}
+	if got := (ErrorCode{}).String(); got != "" {
+		t.Errorf("Expected empty string for zero code, got %q", got)
+	}
+}
+
+func TestLookupErrorCode(t *testing.T) {
+	entry, ok := LookupErrorCode(1, 7)
+	if !ok {
+		t.Fatal("Expected Group 1 Message 7 in catalog")
+	}
+	if entry.Code != CodeRoutingNumberOrder || entry.Scope != RejectionFile {
+		t.Errorf("Unexpected catalog entry: %+v", entry)
+	}
+
+	if _, ok := LookupErrorCode(9, 9); ok {
+		t.Error("Expected unknown code to be missing from catalog")
+	}
+
+	for code, entry := range ErrorCatalog {
+		if entry.Code != code {
+			t.Errorf("Catalog key %s does not match entry code %s", code, entry.Code)
+		}
+		if entry.Scope == RejectionUnknown {
+			t.Errorf("Catalog entry %s has no rejection scope", code)
+		}
+	}
+}
+
+func TestValidationErrorRejection(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      ValidationError
+		expected RejectionScope
+	}{
+		{"No code", ValidationError{}, RejectionUnknown},
+		{"Catalog scope", ValidationError{Code: CodeFileAmountMismatch}, RejectionFile},
+		{"Scope override", ValidationError{Code: CodeInvalidRecord, Scope: RejectionSchedule}, RejectionSchedule},
+		{"Payment scope", ValidationError{Code: CodeInvalidPaymentData}, RejectionPayment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Rejection(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	err := ValidationError{Field: "Amount", Code: CodeSDAAmountExceeded}
+	if !strings.Contains(err.Error(), "Error Reason Group 4 Message 8, file rejected") {
+		t.Errorf("Expected code in error message, got %q", err.Error())
+	}
+}
+
+func TestValidatorErrorCodes(t *testing.T) {
+	validator := NewValidator()
+
+	balancedACH := func() *File {
+		return &File{
+			Header: &FileHeader{RecordCode: "H ", StandardPaymentVersion: "502"},
+			Schedules: []Schedule{
+				&ACHSchedule{
+					Header: &ACHScheduleHeader{},
+					BaseSchedule: BaseSchedule{
+						Payments: []Payment{&ACHPayment{Amount: 100000}},
+						Trailer:  &ScheduleTrailer{ScheduleCount: 1, ScheduleAmount: 100000},
+					},
+				},
+			},
+			Trailer: &FileTrailer{TotalCountRecords: 5, TotalCountPayments: 1, TotalAmountPayments: 100000},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		run   func() error
+		code  ErrorCode
+		scope RejectionScope
+	}{
+		{
+			name: "File header version",
+			run: func() error {
+				return validator.ValidateFileHeader(&FileHeader{RecordCode: "H ", StandardPaymentVersion: "400"})
+			},
+			code:  CodeInvalidRecord,
+			scope: RejectionFile,
+		},
+		{
+			name:  "Blank payment ID rejects schedule",
+			run:   func() error { return validator.ValidateCheckPayment(&CheckPayment{Amount: 100, PayeeName: "JOHN"}) },
+			code:  CodeInvalidRecord,
+			scope: RejectionSchedule,
+		},
+		{
+			name: "Bad routing number marks payment invalid",
+			run: func() error {
+				return validator.ValidateACHPayment(&ACHPayment{PayeeName: "JOHN", RoutingNumber: "123"})
+			},
+			code:  CodeInvalidPaymentData,
+			scope: RejectionPayment,
+		},
+		{
+			name:  "Invalid hex character",
+			run:   func() error { return validator.ValidateHexCharacters("ABC\x01") },
+			code:  CodeInvalidHexCharacter,
+			scope: RejectionFile,
+		},
+		{
+			name:  "Blank schedule number",
+			run:   func() error { return validator.ValidateScheduleNumber("") },
+			code:  CodeInvalidRecord,
+			scope: RejectionSchedule,
+		},
+		{
+			name:  "Agency reconcilement",
+			run:   func() error { return validator.ValidateAgencySpecific(&ACHPayment{Reconcilement: "SHORT"}, "IRS") },
+			code:  CodeInvalidPaymentData,
+			scope: RejectionPayment,
+		},
+		{
+			name:  "CTX without addenda",
+			run:   func() error { return validator.ValidateCTXAddendum(&ACHPayment{StandardEntryClassCode: "CTX"}) },
+			code:  CodeInvalidPaymentData,
+			scope: RejectionPayment,
+		},
+		{
+			name: "ACH schedule amount",
+			run: func() error {
+				file := balancedACH()
+				file.Schedules[0].GetTrailer().ScheduleAmount = 1
+				return validator.ValidateBalancing(file)
+			},
+			code:  CodeACHScheduleAmountMismatch,
+			scope: RejectionSchedule,
+		},
+		{
+			name: "Check schedule count",
+			run: func() error {
+				file := balancedACH()
+				file.Schedules = []Schedule{&CheckSchedule{
+					Header: &CheckScheduleHeader{},
+					BaseSchedule: BaseSchedule{
+						Payments: []Payment{&CheckPayment{Amount: 100000}},
+						Trailer:  &ScheduleTrailer{ScheduleCount: 2, ScheduleAmount: 100000},
+					},
+				}}
+				return validator.ValidateBalancing(file)
+			},
+			code:  CodeCheckScheduleCountMismatch,
+			scope: RejectionSchedule,
+		},
+		{
+			name: "File amount",
+			run: func() error {
+				file := balancedACH()
+				file.Trailer.TotalAmountPayments = 1
+				return validator.ValidateBalancing(file)
+			},
+			code:  CodeFileAmountMismatch,
+			scope: RejectionFile,
+		},
+		{
+			name: "File record count",
+			run: func() error {
+				file := balancedACH()
+				file.Trailer.TotalCountRecords = 99
+				return validator.ValidateBalancing(file)
+			},
+			code:  CodeFileCountMismatch,
+			scope: RejectionFile,
+		},
+		{
+			name: "Missing file trailer",
+			run: func() error {
+				file := balancedACH()
+				file.Trailer = nil
+				return validator.ValidateFileStructure(file)
+			},
+			code:  CodeRecordMissingOrOutOfOrder,
+			scope: RejectionFile,
+		},
+		{
+			name: "Routing number order",
+			run: func() error {
+				file := balancedACH()
+				file.Schedules[0].(*ACHSchedule).Payments = []Payment{
+					&ACHPayment{RoutingNumber: "222222222"},
+					&ACHPayment{RoutingNumber: "111111111"},
+				}
+				return validator.ValidateFileStructure(file)
+			},
+			code:  CodeRoutingNumberOrder,
+			scope: RejectionFile,
+		},
+		{
+			name: "Same Day ACH with check schedule",
+			run: func() error {
+				file := balancedACH()
+				file.Header.IsRequestedForSameDayACH = SDAFlagEnabled
+				file.Schedules = append(file.Schedules, &CheckSchedule{Header: &CheckScheduleHeader{}})
+				return validator.ValidateSameDayACH(file)
+			},
+			code:  CodeSDANonACHSchedule,
+			scope: RejectionFile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+
+			// Codes must survive wrapping by callers such as the Reader
+			var ve ValidationError
+			if !errors.As(fmt.Errorf("line 1: %w", err), &ve) {
+				t.Fatalf("Expected ValidationError, got %T", err)
+			}
+			if ve.Code != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, ve.Code)
+			}
+			if ve.Rejection() != tt.scope {
+				t.Errorf("Expected %s rejection, got %s", tt.scope, ve.Rejection())
+			}
+		})
+	}
+}
@@ -1,6 +1,59 @@
 package pamspr
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
+
+// FieldType is the spec's data type for a field
+type FieldType string
+
+const (
+	FieldTypeAlphabetic   FieldType = "A"  // Letters, blanks and special characters
+	FieldTypeNumeric      FieldType = "N"  // Digits only
+	FieldTypeAlphanumeric FieldType = "AN" // Any allowed character
+	FieldTypeFiller       FieldType = ""   // Untyped filler
+)
+
+// CharacterSet is the set of characters allowed in a field
+type CharacterSet int
+
+const (
+	CharsetAlphanumeric CharacterSet = iota // Any character
+	CharsetAlphabetic                       // Any character except digits
+	CharsetNumeric                          // 0-9
+)
+
+// String returns the character set name
+func (c CharacterSet) String() string {
+	switch c {
+	case CharsetAlphanumeric:
+		return "alphanumeric"
+	case CharsetAlphabetic:
+		return "alphabetic"
+	case CharsetNumeric:
+		return "numeric"
+	default:
+		return fmt.Sprintf("CharacterSet(%d)", int(c))
+	}
+}
+
+// Allows reports whether b fits the field's data type: digits for numeric,
+// anything but digits for alphabetic and any character for alphanumeric.
+// Which characters may appear at all is the hexadecimal character rule of
+// section 1.4, every character above HEX "3F", checked against the file's
+// code page by Validator.ValidateHexCharacters.
+func (c CharacterSet) Allows(b byte) bool {
+	digit := b >= '0' && b <= '9'
+	switch c {
+	case CharsetNumeric:
+		return digit
+	case CharsetAlphabetic:
+		return !digit
+	default:
+		return true
+	}
+}
 
 // FieldDefinition defines the position and properties of a field in a fixed-width record
 type FieldDefinition struct {
@@ -8,16 +61,106 @@ type FieldDefinition struct {
 	End      int  // 1-based end position (inclusive)
 	Length   int  // Calculated field length
 	Required bool // Whether field is required
+
+	Type          FieldType          // Spec data type (A, N or AN)
+	Justification FieldJustification // Left (blank fill), right (zero fill) or none (as received)
+	Charset       CharacterSet       // Allowed characters
+	DefaultZero   bool               // Non-numeric values are read as zero rather than rejected
 }
 
-// NewFieldDef creates a new field definition
+// NewFieldDef creates a new left justified alphanumeric field definition
 func NewFieldDef(start, length int, required bool) FieldDefinition {
 	return FieldDefinition{
-		Start:    start,
-		End:      start + length - 1,
-		Length:   length,
-		Required: required,
+		Start:         start,
+		End:           start + length - 1,
+		Length:        length,
+		Required:      required,
+		Type:          FieldTypeAlphanumeric,
+		Justification: JustifyLeft,
+		Charset:       CharsetAlphanumeric,
+	}
+}
+
+// NewFillerDef creates an untyped filler field definition
+func NewFillerDef(start, length int) FieldDefinition {
+	f := NewFieldDef(start, length, false)
+	f.Type = FieldTypeFiller
+	return f
+}
+
+// Numeric returns f as a right justified, zero filled numeric field
+func (f FieldDefinition) Numeric() FieldDefinition {
+	f.Type = FieldTypeNumeric
+	f.Justification = JustifyRight
+	f.Charset = CharsetNumeric
+	return f
+}
+
+// Alphabetic returns f as an alphabetic field
+func (f FieldDefinition) Alphabetic() FieldDefinition {
+	f.Type = FieldTypeAlphabetic
+	f.Charset = CharsetAlphabetic
+	return f
+}
+
+// DefaultsToZero returns f marked as read as zero when not numeric, as PAM
+// does for the CARS amount (G.12), so the type check does not reject it
+func (f FieldDefinition) DefaultsToZero() FieldDefinition {
+	f.DefaultZero = true
+	return f
+}
+
+// ZeroFilled returns f right justified and zero filled, keeping its type
+func (f FieldDefinition) ZeroFilled() FieldDefinition {
+	f.Justification = JustifyRight
+	return f
+}
+
+// AsReceived returns f stored as received, without justification
+func (f FieldDefinition) AsReceived() FieldDefinition {
+	f.Justification = JustifyNone
+	return f
+}
+
+// NamedField is a field definition with its name
+type NamedField struct {
+	Name string
+	FieldDefinition
+}
+
+// orderedFieldDefinitions caches GetOrderedFieldDefinitions by record code,
+// as records are validated, normalized and compared field by field
+var orderedFieldDefinitions = make(map[string][]NamedField)
+
+func init() {
+	for _, code := range []RecordType{
+		RecordTypeFileHeader, RecordTypeACHScheduleHeader, RecordTypeACHPayment,
+		RecordTypeACHAddendum, RecordTypeACHAddendumCTX, RecordTypeCheckScheduleHeader,
+		RecordTypeCheckPayment, RecordTypeCheckStub, RecordTypeCARSTASBETC,
+		RecordTypeDNP, RecordTypeScheduleTrailer, RecordTypeFileTrailer,
+	} {
+		orderedFieldDefinitions[string(code)] = orderFieldDefinitions(GetFieldDefinitions(string(code)))
+	}
+}
+
+// GetOrderedFieldDefinitions returns a record's field definitions in column
+// order. The slice is shared and must not be modified.
+func GetOrderedFieldDefinitions(recordCode string) []NamedField {
+	return orderedFieldDefinitions[recordCode]
+}
+
+func orderFieldDefinitions(defs map[string]FieldDefinition) []NamedField {
+	if defs == nil {
+		return nil
+	}
+	fields := make([]NamedField, 0, len(defs))
+	for name, def := range defs {
+		fields = append(fields, NamedField{Name: name, FieldDefinition: def})
 	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Start < fields[j].Start
+	})
+	return fields
 }
 
 // GetFieldDefinitions returns field definitions for a given record type
@@ -29,27 +172,27 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"InputSystem":              NewFieldDef(3, 40, true),
 			"StandardPaymentVersion":   NewFieldDef(43, 3, true),
 			"IsRequestedForSameDayACH": NewFieldDef(46, 1, false),
-			"Filler":                   NewFieldDef(47, 804, false),
+			"Filler":                   NewFillerDef(47, 804),
 		}
 
 	case "01": // ACH Schedule Header
 		return map[string]FieldDefinition{
 			"RecordCode":              NewFieldDef(1, 2, true),
 			"AgencyACHText":           NewFieldDef(3, 4, false),
-			"ScheduleNumber":          NewFieldDef(7, 14, true),
+			"ScheduleNumber":          NewFieldDef(7, 14, true).ZeroFilled(),
 			"PaymentTypeCode":         NewFieldDef(21, 25, false),
-			"StandardEntryClassCode":  NewFieldDef(46, 3, true),
-			"AgencyLocationCode":      NewFieldDef(49, 8, true),
-			"Filler1":                 NewFieldDef(57, 1, false),
+			"StandardEntryClassCode":  NewFieldDef(46, 3, true).Alphabetic(),
+			"AgencyLocationCode":      NewFieldDef(49, 8, true).Numeric(),
+			"Filler1":                 NewFillerDef(57, 1),
 			"FederalEmployerIDNumber": NewFieldDef(58, 10, false),
-			"Filler2":                 NewFieldDef(68, 783, false),
+			"Filler2":                 NewFillerDef(68, 783),
 		}
 
 	case "02": // ACH Payment
 		return map[string]FieldDefinition{
 			"RecordCode":                   NewFieldDef(1, 2, true),
 			"AgencyAccountIdentifier":      NewFieldDef(3, 16, true),
-			"Amount":                       NewFieldDef(19, 10, true),
+			"Amount":                       NewFieldDef(19, 10, true).Numeric(),
 			"AgencyPaymentTypeCode":        NewFieldDef(29, 1, false),
 			"IsTOP_Offset":                 NewFieldDef(30, 1, false),
 			"PayeeName":                    NewFieldDef(31, 35, true),
@@ -61,13 +204,13 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"PostalCode":                   NewFieldDef(175, 5, false),
 			"PostalCodeExtension":          NewFieldDef(180, 5, false),
 			"CountryCodeText":              NewFieldDef(185, 2, false),
-			"RoutingNumber":                NewFieldDef(187, 9, true),
+			"RoutingNumber":                NewFieldDef(187, 9, true).Numeric(),
 			"AccountNumber":                NewFieldDef(196, 17, true),
-			"ACH_TransactionCode":          NewFieldDef(213, 2, true),
+			"ACH_TransactionCode":          NewFieldDef(213, 2, true).Numeric(),
 			"PayeeIdentifierAdditional":    NewFieldDef(215, 9, false),
 			"PayeeNameAdditional":          NewFieldDef(224, 35, false),
 			"PaymentID":                    NewFieldDef(259, 20, true),
-			"Reconcilement":                NewFieldDef(279, 100, false),
+			"Reconcilement":                NewFieldDef(279, 100, false).AsReceived(),
 			"TIN":                          NewFieldDef(379, 9, false),
 			"PaymentRecipientTINIndicator": NewFieldDef(388, 1, false),
 			"AdditionalPayeeTINIndicator":  NewFieldDef(389, 1, false),
@@ -79,7 +222,7 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"SubPaymentTypeCode":           NewFieldDef(513, 32, false),
 			"PayerMechanism":               NewFieldDef(545, 20, false),
 			"PaymentDescriptionCode":       NewFieldDef(565, 2, false),
-			"Filler":                       NewFieldDef(567, 284, false),
+			"Filler":                       NewFillerDef(567, 284),
 		}
 
 	case "03": // ACH Addendum (CCD/PPD)
@@ -87,7 +230,7 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"RecordCode":         NewFieldDef(1, 2, true),
 			"PaymentID":          NewFieldDef(3, 20, true),
 			"AddendaInformation": NewFieldDef(23, 80, false),
-			"Filler":             NewFieldDef(103, 748, false),
+			"Filler":             NewFillerDef(103, 748),
 		}
 
 	case "04": // ACH Addendum (CTX)
@@ -95,25 +238,25 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"RecordCode":         NewFieldDef(1, 2, true),
 			"PaymentID":          NewFieldDef(3, 20, true),
 			"AddendaInformation": NewFieldDef(23, 800, false),
-			"Filler":             NewFieldDef(823, 28, false),
+			"Filler":             NewFillerDef(823, 28),
 		}
 
 	case "11": // Check Schedule Header
 		return map[string]FieldDefinition{
 			"RecordCode":                NewFieldDef(1, 2, true),
-			"ScheduleNumber":            NewFieldDef(3, 14, true),
+			"ScheduleNumber":            NewFieldDef(3, 14, true).ZeroFilled(),
 			"PaymentTypeCode":           NewFieldDef(17, 25, false),
-			"AgencyLocationCode":        NewFieldDef(42, 8, true),
-			"Filler1":                   NewFieldDef(50, 9, false),
-			"CheckPaymentEnclosureCode": NewFieldDef(59, 10, false),
-			"Filler2":                   NewFieldDef(69, 782, false),
+			"AgencyLocationCode":        NewFieldDef(42, 8, true).Numeric(),
+			"Filler1":                   NewFillerDef(50, 9),
+			"CheckPaymentEnclosureCode": NewFieldDef(59, 10, false).Alphabetic(),
+			"Filler2":                   NewFillerDef(69, 782),
 		}
 
 	case "12": // Check Payment
 		return map[string]FieldDefinition{
 			"RecordCode":                   NewFieldDef(1, 2, true),
 			"AgencyAccountIdentifier":      NewFieldDef(3, 16, true),
-			"Amount":                       NewFieldDef(19, 10, true),
+			"Amount":                       NewFieldDef(19, 10, true).Numeric(),
 			"AgencyPaymentTypeCode":        NewFieldDef(29, 1, false),
 			"IsTOP_Offset":                 NewFieldDef(30, 1, false),
 			"PayeeName":                    NewFieldDef(31, 35, true),
@@ -127,7 +270,7 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"PostalCode":                   NewFieldDef(245, 5, false),
 			"PostalCodeExtension":          NewFieldDef(250, 5, false),
 			"PostNetBarcodeDeliveryPoint":  NewFieldDef(255, 3, false),
-			"Filler1":                      NewFieldDef(258, 14, false),
+			"Filler1":                      NewFillerDef(258, 14),
 			"CountryName":                  NewFieldDef(272, 40, false),
 			"ConsularCode":                 NewFieldDef(312, 3, false),
 			"CheckLegendText1":             NewFieldDef(315, 55, false),
@@ -135,7 +278,7 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"PayeeIdentifier_Secondary":    NewFieldDef(425, 9, false),
 			"PartyName_Secondary":          NewFieldDef(434, 35, false),
 			"PaymentID":                    NewFieldDef(469, 20, true),
-			"Reconcilement":                NewFieldDef(489, 100, false),
+			"Reconcilement":                NewFieldDef(489, 100, false).AsReceived(),
 			"SpecialHandling":              NewFieldDef(589, 50, false),
 			"TIN":                          NewFieldDef(639, 9, false),
 			"USPSIntelligentMailBarcode":   NewFieldDef(648, 50, false),
@@ -145,7 +288,7 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"SubPaymentTypeCode":           NewFieldDef(710, 32, false),
 			"PayerMechanism":               NewFieldDef(742, 20, false),
 			"PaymentDescriptionCode":       NewFieldDef(762, 2, false),
-			"Filler2":                      NewFieldDef(764, 87, false),
+			"Filler2":                      NewFillerDef(764, 87),
 		}
 
 	case "13": // Check Stub
@@ -166,7 +309,7 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"Line12":     NewFieldDef(628, 55, false),
 			"Line13":     NewFieldDef(683, 55, false),
 			"Line14":     NewFieldDef(738, 55, false),
-			"Filler":     NewFieldDef(793, 58, false),
+			"Filler":     NewFillerDef(793, 58),
 		}
 
 	case "G ": // CARS TAS/BETC
@@ -182,36 +325,36 @@ func GetFieldDefinitions(recordCode string) map[string]FieldDefinition {
 			"MainAccountCode":               NewFieldDef(40, 4, false),
 			"SubAccountCode":                NewFieldDef(44, 3, false),
 			"BusinessEventTypeCode":         NewFieldDef(47, 8, false),
-			"AccountClassificationAmount":   NewFieldDef(55, 10, false),
+			"AccountClassificationAmount":   NewFieldDef(55, 10, false).Numeric().DefaultsToZero(),
 			"IsCredit":                      NewFieldDef(65, 1, false),
-			"Filler":                        NewFieldDef(66, 785, false),
+			"Filler":                        NewFillerDef(66, 785),
 		}
 
 	case "DD": // DNP Record
 		return map[string]FieldDefinition{
 			"RecordCode": NewFieldDef(1, 2, true),
 			"PaymentID":  NewFieldDef(3, 20, true),
-			"DNPDetail":  NewFieldDef(23, 766, false),
-			"Filler":     NewFieldDef(789, 62, false),
+			"DNPDetail":  NewFieldDef(23, 766, false).AsReceived(),
+			"Filler":     NewFillerDef(789, 62),
 		}
 
 	case "T ": // Schedule Trailer
 		return map[string]FieldDefinition{
 			"RecordCode":     NewFieldDef(1, 2, true),
-			"Filler1":        NewFieldDef(3, 10, false),
-			"ScheduleCount":  NewFieldDef(13, 8, true),
-			"Filler2":        NewFieldDef(21, 3, false),
-			"ScheduleAmount": NewFieldDef(24, 15, true),
-			"Filler3":        NewFieldDef(39, 812, false),
+			"Filler1":        NewFillerDef(3, 10),
+			"ScheduleCount":  NewFieldDef(13, 8, true).Numeric(),
+			"Filler2":        NewFillerDef(21, 3),
+			"ScheduleAmount": NewFieldDef(24, 15, true).Numeric(),
+			"Filler3":        NewFillerDef(39, 812),
 		}
 
 	case "E ": // File Trailer
 		return map[string]FieldDefinition{
 			"RecordCode":          NewFieldDef(1, 2, true),
-			"TotalCountRecords":   NewFieldDef(3, 18, true),
-			"TotalCountPayments":  NewFieldDef(21, 18, true),
-			"TotalAmountPayments": NewFieldDef(39, 18, true),
-			"Filler":              NewFieldDef(57, 794, false),
+			"TotalCountRecords":   NewFieldDef(3, 18, true).Numeric(),
+			"TotalCountPayments":  NewFieldDef(21, 18, true).Numeric(),
+			"TotalAmountPayments": NewFieldDef(39, 18, true).Numeric(),
+			"Filler":              NewFillerDef(57, 794),
 		}
 
 	default:
@@ -219,3 +219,94 @@ func TestNewFieldDef(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldTypeMetadata(t *testing.T) {
+	tests := []struct {
+		recordCode    string
+		field         string
+		fieldType     FieldType
+		justification FieldJustification
+		charset       CharacterSet
+	}{
+		{"H ", "InputSystem", FieldTypeAlphanumeric, JustifyLeft, CharsetAlphanumeric},
+		{"01", "ScheduleNumber", FieldTypeAlphanumeric, JustifyRight, CharsetAlphanumeric},
+		{"01", "StandardEntryClassCode", FieldTypeAlphabetic, JustifyLeft, CharsetAlphabetic},
+		{"01", "AgencyLocationCode", FieldTypeNumeric, JustifyRight, CharsetNumeric},
+		{"02", "Amount", FieldTypeNumeric, JustifyRight, CharsetNumeric},
+		{"02", "RoutingNumber", FieldTypeNumeric, JustifyRight, CharsetNumeric},
+		{"02", "PayeeName", FieldTypeAlphanumeric, JustifyLeft, CharsetAlphanumeric},
+		{"02", "Reconcilement", FieldTypeAlphanumeric, JustifyNone, CharsetAlphanumeric},
+		{"11", "CheckPaymentEnclosureCode", FieldTypeAlphabetic, JustifyLeft, CharsetAlphabetic},
+		{"12", "Reconcilement", FieldTypeAlphanumeric, JustifyNone, CharsetAlphanumeric},
+		{"G ", "AccountClassificationAmount", FieldTypeNumeric, JustifyRight, CharsetNumeric},
+		{"DD", "DNPDetail", FieldTypeAlphanumeric, JustifyNone, CharsetAlphanumeric},
+		{"T ", "Filler1", FieldTypeFiller, JustifyLeft, CharsetAlphanumeric},
+		{"E ", "TotalAmountPayments", FieldTypeNumeric, JustifyRight, CharsetNumeric},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recordCode+"_"+tt.field, func(t *testing.T) {
+			def, ok := GetFieldDefinitions(tt.recordCode)[tt.field]
+			if !ok {
+				t.Fatalf("Field %s not defined", tt.field)
+			}
+			if def.Type != tt.fieldType || def.Justification != tt.justification || def.Charset != tt.charset {
+				t.Errorf("Expected %q/%s/%s, got %q/%s/%s", tt.fieldType, tt.justification, tt.charset,
+					def.Type, def.Justification, def.Charset)
+			}
+		})
+	}
+
+	// Every record is fully described in column order
+	for _, code := range []string{"H ", "01", "02", "03", "04", "11", "12", "13", "G ", "DD", "T ", "E "} {
+		next := 1
+		for _, field := range GetOrderedFieldDefinitions(code) {
+			if field.Start != next {
+				t.Errorf("Record %q: field %s starts at %d, expected %d", code, field.Name, field.Start, next)
+			}
+			next = field.End + 1
+		}
+		if next != RecordLength+1 {
+			t.Errorf("Record %q: fields end at %d", code, next-1)
+		}
+	}
+}
+
+func TestCharacterSetAllows(t *testing.T) {
+	tests := []struct {
+		charset CharacterSet
+		allowed string
+		denied  string
+	}{
+		{CharsetAlphanumeric, " !\"#$%&'()*+,-./09:;<=>?@AZ[\\]^_`az{|}~\xC9", ""},
+		{CharsetAlphabetic, " AZaz@-&\xC9", "09"},
+		{CharsetNumeric, "0123456789", " A-."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.charset.String(), func(t *testing.T) {
+			for i := 0; i < len(tt.allowed); i++ {
+				if !tt.charset.Allows(tt.allowed[i]) {
+					t.Errorf("Expected %q to be allowed", tt.allowed[i])
+				}
+			}
+			for i := 0; i < len(tt.denied); i++ {
+				if tt.charset.Allows(tt.denied[i]) {
+					t.Errorf("Expected %q to be denied", tt.denied[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetOrderedFieldDefinitionsCached(t *testing.T) {
+	for _, code := range []string{"H ", "01", "02", "03", "04", "11", "12", "13", "G ", "DD", "T ", "E "} {
+		fields := GetOrderedFieldDefinitions(code)
+		if len(fields) != len(GetFieldDefinitions(code)) {
+			t.Errorf("Record %q: %d ordered fields, %d definitions", code, len(fields), len(GetFieldDefinitions(code)))
+		}
+	}
+	if GetOrderedFieldDefinitions("ZZ") != nil {
+		t.Error("Expected no fields for an unknown record code")
+	}
+}
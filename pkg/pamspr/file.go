@@ -175,88 +175,89 @@ func (s *CheckSchedule) Validate() error {
 
 // FileHeader represents the file header record
 type FileHeader struct {
-	RecordCode               string `pamspr:"RecordCode"`
-	InputSystem              string `pamspr:"InputSystem"`
-	StandardPaymentVersion   string `pamspr:"StandardPaymentVersion"`
-	IsRequestedForSameDayACH string `pamspr:"IsRequestedForSameDayACH"`
-	Filler                   string `pamspr:"Filler"`
+	RecordCode               string `pamspr:"RecordCode" json:"recordCode"`
+	InputSystem              string `pamspr:"InputSystem" json:"inputSystem"`
+	StandardPaymentVersion   string `pamspr:"StandardPaymentVersion" json:"standardPaymentVersion"`
+	IsRequestedForSameDayACH string `pamspr:"IsRequestedForSameDayACH" json:"isRequestedForSameDayAch"`
+	Filler                   string `pamspr:"Filler" json:"filler"`
 }
 
 // FileTrailer represents the file trailer record
 type FileTrailer struct {
-	RecordCode          string `pamspr:"RecordCode"`
-	TotalCountRecords   int64  `pamspr:"TotalCountRecords" format:"numeric"`
-	TotalCountPayments  int64  `pamspr:"TotalCountPayments" format:"numeric"`
-	TotalAmountPayments int64  `pamspr:"TotalAmountPayments" format:"numeric"`
+	RecordCode          string `pamspr:"RecordCode" json:"recordCode"`
+	TotalCountRecords   int64  `pamspr:"TotalCountRecords" format:"numeric" json:"totalCountRecords"`
+	TotalCountPayments  int64  `pamspr:"TotalCountPayments" format:"numeric" json:"totalCountPayments"`
+	TotalAmountPayments int64  `pamspr:"TotalAmountPayments" format:"numeric" json:"totalAmountPayments"`
 }
 
 // ACHScheduleHeader represents ACH schedule header record
 type ACHScheduleHeader struct {
-	RecordCode              string `pamspr:"RecordCode"`
-	AgencyACHText           string `pamspr:"AgencyACHText"`
-	ScheduleNumber          string `pamspr:"ScheduleNumber" format:"numeric"`
-	PaymentTypeCode         string `pamspr:"PaymentTypeCode"`
-	StandardEntryClassCode  string `pamspr:"StandardEntryClassCode"`
-	AgencyLocationCode      string `pamspr:"AgencyLocationCode" format:"numeric"`
-	Filler1                 string `pamspr:"Filler1"`
-	FederalEmployerIDNumber string `pamspr:"FederalEmployerIDNumber"`
-	Filler2                 string `pamspr:"Filler2"`
+	RecordCode              string `pamspr:"RecordCode" json:"recordCode"`
+	AgencyACHText           string `pamspr:"AgencyACHText" json:"agencyAchText"`
+	ScheduleNumber          string `pamspr:"ScheduleNumber" format:"numeric" json:"scheduleNumber"`
+	PaymentTypeCode         string `pamspr:"PaymentTypeCode" json:"paymentTypeCode"`
+	StandardEntryClassCode  string `pamspr:"StandardEntryClassCode" json:"standardEntryClassCode"`
+	AgencyLocationCode      string `pamspr:"AgencyLocationCode" format:"numeric" json:"agencyLocationCode"`
+	Filler1                 string `pamspr:"Filler1" json:"filler1"`
+	FederalEmployerIDNumber string `pamspr:"FederalEmployerIDNumber" json:"federalEmployerIdNumber"`
+	Filler2                 string `pamspr:"Filler2" json:"filler2"`
 }
 
 // CheckScheduleHeader represents check schedule header record
 type CheckScheduleHeader struct {
-	RecordCode                string // "11"
-	ScheduleNumber            string // 14 chars
-	PaymentTypeCode           string // 25 chars
-	AgencyLocationCode        string // 8 digits
-	Filler1                   string // 9 chars
-	CheckPaymentEnclosureCode string // 10 chars: "nameonly", "letter", "stub", "insert", or blank
-	Filler2                   string // 782 chars
+	RecordCode                string `json:"recordCode"`                // "11"
+	ScheduleNumber            string `json:"scheduleNumber"`            // 14 chars
+	PaymentTypeCode           string `json:"paymentTypeCode"`           // 25 chars
+	AgencyLocationCode        string `json:"agencyLocationCode"`        // 8 digits
+	Filler1                   string `json:"filler1"`                   // 9 chars
+	CheckPaymentEnclosureCode string `json:"checkPaymentEnclosureCode"` // 10 chars: "nameonly", "letter", "stub", "insert", or blank
+	Filler2                   string `json:"filler2"`                   // 782 chars
 }
 
 // ACHPayment represents an ACH payment data record
 type ACHPayment struct {
-	RecordCode                   string // "02"
-	AgencyAccountIdentifier      string // 16 chars
-	Amount                       int64  // 10 digits, amount in cents
-	AgencyPaymentTypeCode        string // 1 char
-	IsTOP_Offset                 string // "0" or "1"
-	PayeeName                    string // 35 chars
-	PayeeAddressLine1            string // 35 chars
-	PayeeAddressLine2            string // 35 chars
-	CityName                     string // 27 chars
-	StateName                    string // 10 chars
-	StateCodeText                string // 2 chars
-	PostalCode                   string // 5 chars
-	PostalCodeExtension          string // 5 chars
-	CountryCodeText              string // 2 chars
-	RoutingNumber                string // 9 digits
-	AccountNumber                string // 17 chars
-	ACH_TransactionCode          string // 2 digits
-	PayeeIdentifierAdditional    string // 9 chars (Secondary TIN)
-	PayeeNameAdditional          string // 35 chars (Secondary Name)
-	PaymentID                    string // 20 chars
-	Reconcilement                string // 100 chars
-	TIN                          string // 9 chars
-	PaymentRecipientTINIndicator string // 1 char: "1"=SSN, "2"=EIN, "3"=ITIN
-	AdditionalPayeeTINIndicator  string // 1 char
-	AmountEligibleForOffset      string // 10 digits
-	PayeeAddressLine3            string // 35 chars
-	PayeeAddressLine4            string // 35 chars
-	CountryName                  string // 40 chars
-	ConsularCode                 string // 3 chars (Geo Code)
-	SubPaymentTypeCode           string // 32 chars
-	PayerMechanism               string // 20 chars
-	PaymentDescriptionCode       string // 2 chars
-	Filler                       string // 284 chars
+	RecordCode                   string `json:"recordCode"`                   // "02"
+	AgencyAccountIdentifier      string `json:"agencyAccountIdentifier"`      // 16 chars
+	Amount                       int64  `json:"amount"`                       // 10 digits, amount in cents
+	AgencyPaymentTypeCode        string `json:"agencyPaymentTypeCode"`        // 1 char
+	IsTOP_Offset                 string `json:"isTopOffset"`                  // "0" or "1"
+	PayeeName                    string `json:"payeeName"`                    // 35 chars
+	PayeeAddressLine1            string `json:"payeeAddressLine1"`            // 35 chars
+	PayeeAddressLine2            string `json:"payeeAddressLine2"`            // 35 chars
+	CityName                     string `json:"cityName"`                     // 27 chars
+	StateName                    string `json:"stateName"`                    // 10 chars
+	StateCodeText                string `json:"stateCodeText"`                // 2 chars
+	PostalCode                   string `json:"postalCode"`                   // 5 chars
+	PostalCodeExtension          string `json:"postalCodeExtension"`          // 5 chars
+	CountryCodeText              string `json:"countryCodeText"`              // 2 chars
+	RoutingNumber                string `json:"routingNumber"`                // 9 digits
+	AccountNumber                string `json:"accountNumber"`                // 17 chars
+	ACH_TransactionCode          string `json:"achTransactionCode"`           // 2 digits
+	PayeeIdentifierAdditional    string `json:"payeeIdentifierAdditional"`    // 9 chars (Secondary TIN)
+	PayeeNameAdditional          string `json:"payeeNameAdditional"`          // 35 chars (Secondary Name)
+	PaymentID                    string `json:"paymentId"`                    // 20 chars
+	Reconcilement                string `json:"reconcilement"`                // 100 chars
+	TIN                          string `json:"tin"`                          // 9 chars
+	PaymentRecipientTINIndicator string `json:"paymentRecipientTinIndicator"` // 1 char: "1"=SSN, "2"=EIN, "3"=ITIN
+	AdditionalPayeeTINIndicator  string `json:"additionalPayeeTinIndicator"`  // 1 char
+	AmountEligibleForOffset      string `json:"amountEligibleForOffset"`      // 10 digits
+	PayeeAddressLine3            string `json:"payeeAddressLine3"`            // 35 chars
+	PayeeAddressLine4            string `json:"payeeAddressLine4"`            // 35 chars
+	CountryName                  string `json:"countryName"`                  // 40 chars
+	ConsularCode                 string `json:"consularCode"`                 // 3 chars (Geo Code)
+	SubPaymentTypeCode           string `json:"subPaymentTypeCode"`           // 32 chars
+	PayerMechanism               string `json:"payerMechanism"`               // 20 chars
+	PaymentDescriptionCode       string `json:"paymentDescriptionCode"`       // 2 chars
+	Filler                       string `json:"filler"`                       // 284 chars
 
 	// Associated records
-	Addenda     []*ACHAddendum
-	CARSTASBETC []*CARSTASBETC
-	DNP         *DNPRecord
+	Addenda     []*ACHAddendum `json:"addenda,omitempty"`    // "03" records for PPD, CCD and IAT payments
+	CTXAddenda  []*CTXAddendum `json:"ctxAddenda,omitempty"` // "04" records for CTX payments
+	CARSTASBETC []*CARSTASBETC `json:"carsTasBetc,omitempty"`
+	DNP         *DNPRecord     `json:"dnp,omitempty"`
 
 	// StandardEntryClassCode is set from the schedule header
-	StandardEntryClassCode string
+	StandardEntryClassCode string `json:"standardEntryClassCode"`
 }
 
 // GetPaymentID returns the payment ID
@@ -296,7 +297,7 @@ func (p *ACHPayment) GetReconcilement() string {
 
 // Use direct field access for type-specific fields:
 // - p.RoutingNumber, p.AccountNumber, p.StandardEntryClassCode
-// - p.Addenda, p.CARSTASBETC, p.DNP
+// - p.Addenda, p.CTXAddenda, p.CARSTASBETC, p.DNP
 //
 // Helper methods for common operations:
 
@@ -305,6 +306,16 @@ func (p *ACHPayment) AddAddendum(addendum *ACHAddendum) {
 	p.Addenda = append(p.Addenda, addendum)
 }
 
+// AddCTXAddendum appends a CTX addendum to the payment
+func (p *ACHPayment) AddCTXAddendum(addendum *CTXAddendum) {
+	p.CTXAddenda = append(p.CTXAddenda, addendum)
+}
+
+// AddendaCount returns the number of "03" and "04" records for the payment
+func (p *ACHPayment) AddendaCount() int {
+	return len(p.Addenda) + len(p.CTXAddenda)
+}
+
 // AddCARSTASBETC appends a CARS/TAS/BETC record to the payment
 func (p *ACHPayment) AddCARSTASBETC(car *CARSTASBETC) {
 	p.CARSTASBETC = append(p.CARSTASBETC, car)
@@ -326,46 +337,46 @@ func (p *ACHPayment) Validate() error {
 
 // CheckPayment represents a check payment data record
 type CheckPayment struct {
-	RecordCode                   string // "12"
-	AgencyAccountIdentifier      string // 16 chars
-	Amount                       int64  // 10 digits, amount in cents
-	AgencyPaymentTypeCode        string // 1 char
-	IsTOP_Offset                 string // "0" or "1"
-	PayeeName                    string // 35 chars
-	PayeeAddressLine1            string // 35 chars
-	PayeeAddressLine2            string // 35 chars
-	PayeeAddressLine3            string // 35 chars
-	PayeeAddressLine4            string // 35 chars
-	CityName                     string // 27 chars
-	StateName                    string // 10 chars
-	StateCodeText                string // 2 chars
-	PostalCode                   string // 5 chars
-	PostalCodeExtension          string // 5 chars
-	PostNetBarcodeDeliveryPoint  string // 3 chars
-	Filler1                      string // 14 chars
-	CountryName                  string // 40 chars
-	ConsularCode                 string // 3 chars (Geo Code)
-	CheckLegendText1             string // 55 chars
-	CheckLegendText2             string // 55 chars
-	PayeeIdentifier_Secondary    string // 9 chars
-	PartyName_Secondary          string // 35 chars
-	PaymentID                    string // 20 chars
-	Reconcilement                string // 100 chars
-	SpecialHandling              string // 50 chars
-	TIN                          string // 9 chars
-	USPSIntelligentMailBarcode   string // 50 chars
-	PaymentRecipientTINIndicator string // 1 char
-	SecondaryPayeeTINIndicator   string // 1 char
-	AmountEligibleForOffset      string // 10 digits
-	SubPaymentTypeCode           string // 32 chars
-	PayerMechanism               string // 20 chars
-	PaymentDescriptionCode       string // 2 chars
-	Filler2                      string // 87 chars
+	RecordCode                   string `json:"recordCode"`                   // "12"
+	AgencyAccountIdentifier      string `json:"agencyAccountIdentifier"`      // 16 chars
+	Amount                       int64  `json:"amount"`                       // 10 digits, amount in cents
+	AgencyPaymentTypeCode        string `json:"agencyPaymentTypeCode"`        // 1 char
+	IsTOP_Offset                 string `json:"isTopOffset"`                  // "0" or "1"
+	PayeeName                    string `json:"payeeName"`                    // 35 chars
+	PayeeAddressLine1            string `json:"payeeAddressLine1"`            // 35 chars
+	PayeeAddressLine2            string `json:"payeeAddressLine2"`            // 35 chars
+	PayeeAddressLine3            string `json:"payeeAddressLine3"`            // 35 chars
+	PayeeAddressLine4            string `json:"payeeAddressLine4"`            // 35 chars
+	CityName                     string `json:"cityName"`                     // 27 chars
+	StateName                    string `json:"stateName"`                    // 10 chars
+	StateCodeText                string `json:"stateCodeText"`                // 2 chars
+	PostalCode                   string `json:"postalCode"`                   // 5 chars
+	PostalCodeExtension          string `json:"postalCodeExtension"`          // 5 chars
+	PostNetBarcodeDeliveryPoint  string `json:"postNetBarcodeDeliveryPoint"`  // 3 chars
+	Filler1                      string `json:"filler1"`                      // 14 chars
+	CountryName                  string `json:"countryName"`                  // 40 chars
+	ConsularCode                 string `json:"consularCode"`                 // 3 chars (Geo Code)
+	CheckLegendText1             string `json:"checkLegendText1"`             // 55 chars
+	CheckLegendText2             string `json:"checkLegendText2"`             // 55 chars
+	PayeeIdentifier_Secondary    string `json:"payeeIdentifierSecondary"`     // 9 chars
+	PartyName_Secondary          string `json:"partyNameSecondary"`           // 35 chars
+	PaymentID                    string `json:"paymentId"`                    // 20 chars
+	Reconcilement                string `json:"reconcilement"`                // 100 chars
+	SpecialHandling              string `json:"specialHandling"`              // 50 chars
+	TIN                          string `json:"tin"`                          // 9 chars
+	USPSIntelligentMailBarcode   string `json:"uspsIntelligentMailBarcode"`   // 50 chars
+	PaymentRecipientTINIndicator string `json:"paymentRecipientTinIndicator"` // 1 char
+	SecondaryPayeeTINIndicator   string `json:"secondaryPayeeTinIndicator"`   // 1 char
+	AmountEligibleForOffset      string `json:"amountEligibleForOffset"`      // 10 digits
+	SubPaymentTypeCode           string `json:"subPaymentTypeCode"`           // 32 chars
+	PayerMechanism               string `json:"payerMechanism"`               // 20 chars
+	PaymentDescriptionCode       string `json:"paymentDescriptionCode"`       // 2 chars
+	Filler2                      string `json:"filler2"`                      // 87 chars
 
 	// Associated records
-	Stub        *CheckStub
-	CARSTASBETC []*CARSTASBETC
-	DNP         *DNPRecord
+	Stub        *CheckStub     `json:"stub,omitempty"`
+	CARSTASBETC []*CARSTASBETC `json:"carsTasBetc,omitempty"`
+	DNP         *DNPRecord     `json:"dnp,omitempty"`
 }
 
 // GetPaymentID returns the payment ID
@@ -427,49 +438,56 @@ func (p *CheckPayment) Validate() error {
 	return nil
 }
 
-// ACHAddendum represents an ACH addendum record
+// ACHAddendum represents an ACH addendum record for PPD, CCD and IAT payments
 type ACHAddendum struct {
-	RecordCode         string // "03" or "04"
-	PaymentID          string // 20 chars
-	AddendaInformation string // 80 chars for "03", 800 chars for "04" (CTX)
+	RecordCode         string `json:"recordCode"`         // "03"
+	PaymentID          string `json:"paymentId"`          // 20 chars
+	AddendaInformation string `json:"addendaInformation"` // 80 chars
+}
+
+// CTXAddendum represents an ACH addendum record for CTX payments
+type CTXAddendum struct {
+	RecordCode         string `json:"recordCode"`         // "04"
+	PaymentID          string `json:"paymentId"`          // 20 chars
+	AddendaInformation string `json:"addendaInformation"` // 800 chars of X12 820 data
 }
 
 // CARSTASBETC represents a CARS TAS/BETC record
 type CARSTASBETC struct {
-	RecordCode                    string // "G "
-	PaymentID                     string // 20 chars
-	SubLevelPrefixCode            string // 2 chars
-	AllocationTransferAgencyID    string // 3 chars
-	AgencyIdentifier              string // 3 chars
-	BeginningPeriodOfAvailability string // 4 chars
-	EndingPeriodOfAvailability    string // 4 chars
-	AvailabilityTypeCode          string // 1 char
-	MainAccountCode               string // 4 chars
-	SubAccountCode                string // 3 chars
-	BusinessEventTypeCode         string // 8 chars
-	AccountClassificationAmount   int64  // 10 digits
-	IsCredit                      string // 1 char: "0" or "1"
+	RecordCode                    string `json:"recordCode"`                    // "G "
+	PaymentID                     string `json:"paymentId"`                     // 20 chars
+	SubLevelPrefixCode            string `json:"subLevelPrefixCode"`            // 2 chars
+	AllocationTransferAgencyID    string `json:"allocationTransferAgencyId"`    // 3 chars
+	AgencyIdentifier              string `json:"agencyIdentifier"`              // 3 chars
+	BeginningPeriodOfAvailability string `json:"beginningPeriodOfAvailability"` // 4 chars
+	EndingPeriodOfAvailability    string `json:"endingPeriodOfAvailability"`    // 4 chars
+	AvailabilityTypeCode          string `json:"availabilityTypeCode"`          // 1 char
+	MainAccountCode               string `json:"mainAccountCode"`               // 4 chars
+	SubAccountCode                string `json:"subAccountCode"`                // 3 chars
+	BusinessEventTypeCode         string `json:"businessEventTypeCode"`         // 8 chars
+	AccountClassificationAmount   int64  `json:"accountClassificationAmount"`   // 10 digits
+	IsCredit                      string `json:"isCredit"`                      // 1 char: "0" or "1"
 }
 
 // CheckStub represents a check stub record
 type CheckStub struct {
-	RecordCode                 string     // "13"
-	PaymentID                  string     // 20 chars
-	PaymentIdentificationLines [14]string // 14 lines of 55 chars each
+	RecordCode                 string     `json:"recordCode"`                 // "13"
+	PaymentID                  string     `json:"paymentId"`                  // 20 chars
+	PaymentIdentificationLines [14]string `json:"paymentIdentificationLines"` // 14 lines of 55 chars each
 }
 
 // DNPRecord represents a DNP record
 type DNPRecord struct {
-	RecordCode string // "DD"
-	PaymentID  string // 20 chars
-	DNPDetail  string // 766 chars
+	RecordCode string `json:"recordCode"` // "DD"
+	PaymentID  string `json:"paymentId"`  // 20 chars
+	DNPDetail  string `json:"dnpDetail"`  // 766 chars
 }
 
 // ScheduleTrailer represents a schedule trailer record
 type ScheduleTrailer struct {
-	RecordCode     string // "T "
-	ScheduleCount  int64  // 8 digits
-	ScheduleAmount int64  // 15 digits, amount in cents
+	RecordCode     string `json:"recordCode"`     // "T "
+	ScheduleCount  int64  `json:"scheduleCount"`  // 8 digits
+	ScheduleAmount int64  `json:"scheduleAmount"` // 15 digits, amount in cents
 }
 
 // PaymentType represents the type of payment
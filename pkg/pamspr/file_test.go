@@ -193,7 +193,7 @@ func TestACHAddendum(t *testing.T) {
 	}
 
 	// Test CTX addendum (04)
-	ctxAddendum := &ACHAddendum{
+	ctxAddendum := &CTXAddendum{
 		RecordCode:         "04",
 		PaymentID:          "PAY002",
 		AddendaInformation: "ISA*00*          *00*          *ZZ*123456789      *ZZ*987654321      *210101*1200*U*00401*000000001*0*P*>",
@@ -3,6 +3,8 @@ package pamspr
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -367,7 +369,7 @@ func createTestACHWithAddenda() *File {
 	if achSchedule, ok := AsACHSchedule(file.Schedules[0]); ok {
 		if achPayment, ok := AsACHPayment(achSchedule.GetPayments()[1]); ok {
 			achPayment.StandardEntryClassCode = "CTX"
-			achPayment.Addenda = []*ACHAddendum{
+			achPayment.CTXAddenda = []*CTXAddendum{
 				{
 					RecordCode:         "04",
 					PaymentID:          "PAY002              ",
@@ -536,7 +538,7 @@ func createCTXWithoutAddendum() *File {
 	file := createTestACHFile()
 	payment := file.Schedules[0].(*ACHSchedule).Payments[0].(*ACHPayment)
 	payment.StandardEntryClassCode = "CTX"
-	payment.Addenda = nil // CTX requires addendum
+	payment.CTXAddenda = nil // CTX requires addendum
 	return file
 }
 
@@ -622,3 +624,108 @@ func padLeft(s string, length int, pad rune) string {
 	}
 	return strings.Repeat(string(pad), length-len(s)) + s
 }
+
+// TestIntegrationTestdataAddenda round-trips the synthetic addenda files and checks the per-SEC limits
+func TestIntegrationTestdataAddenda(t *testing.T) {
+	tests := []struct {
+		path       string
+		addenda    []int // "03" records per payment, in file order
+		ctxAddenda []int // "04" records per payment, in file order
+		wantRule   string
+	}{
+		{"../../testdata/synthetic/valid/synthetic_ach_addenda.spr", []int{1, 0, 1, 2}, []int{0, 0, 0, 0}, ""},
+		{"../../testdata/synthetic/valid/synthetic_ctx_addenda.spr", []int{0}, []int{2}, ""},
+		{"../../testdata/synthetic/invalid/synthetic_ppd_extra_addenda.spr", []int{2}, []int{0}, "addenda_count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			data, err := os.ReadFile(tt.path)
+			if err != nil {
+				t.Fatalf("Failed to read testdata: %v", err)
+			}
+
+			file, err := NewReader(bytes.NewReader(data)).Read()
+			if err != nil {
+				t.Fatalf("Failed to parse file: %v", err)
+			}
+
+			var addenda, ctxAddenda []int
+			for _, schedule := range file.Schedules {
+				for _, payment := range schedule.GetPayments() {
+					achPayment := payment.(*ACHPayment)
+					addenda = append(addenda, len(achPayment.Addenda))
+					ctxAddenda = append(ctxAddenda, len(achPayment.CTXAddenda))
+				}
+			}
+			if fmt.Sprint(addenda) != fmt.Sprint(tt.addenda) || fmt.Sprint(ctxAddenda) != fmt.Sprint(tt.ctxAddenda) {
+				t.Errorf("Expected addenda %v and CTX addenda %v, got %v and %v", tt.addenda, tt.ctxAddenda, addenda, ctxAddenda)
+			}
+
+			// Writing the parsed file must reproduce the input byte for byte
+			var buf bytes.Buffer
+			if err := NewWriter(&buf).Write(file); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), data) {
+				t.Error("Round trip changed the file contents")
+			}
+
+			report := NewValidator().ValidateFile(file)
+			streamed := NewReader(bytes.NewReader(data))
+			if err := streamed.ProcessFile(nil, nil, nil); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+
+			for name, r := range map[string]*ValidationReport{"ValidateFile": report, "ProcessFile": streamed.GetValidationReport()} {
+				if tt.wantRule == "" {
+					if r.HasIssues() {
+						t.Errorf("%s: unexpected issues:\n%s", name, r.Error())
+					}
+					continue
+				}
+				if !r.HasIssues() || r.Issues[0].Rule != tt.wantRule || r.Issues[0].Code != CodeRecordMissingOrOutOfOrder {
+					t.Errorf("%s: expected %s issue, got %v", name, tt.wantRule, r.Issues)
+				}
+			}
+		})
+	}
+}
+
+// TestIntegrationTestdataValid checks that every synthetic file meant to be
+// valid passes ValidateFile and the reader's field checks, as the CLI's -validate does
+func TestIntegrationTestdataValid(t *testing.T) {
+	paths, err := filepath.Glob("../../testdata/synthetic/valid/*.spr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agency, err := filepath.Glob("../../testdata/synthetic/agency/*/*.spr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, agency...)
+	if len(paths) == 0 {
+		t.Fatal("No synthetic testdata found")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read testdata: %v", err)
+			}
+
+			reader := NewReader(bytes.NewReader(data))
+			file, err := reader.Read()
+			if err != nil {
+				t.Fatalf("Failed to parse file: %v", err)
+			}
+			for _, issue := range reader.GetValidationReport().Issues {
+				t.Errorf("Reader: %s", issue)
+			}
+			for _, issue := range NewValidator().ValidateFile(file).Issues {
+				t.Errorf("ValidateFile: %s", issue)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package pamspr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSON discriminator values for the Schedule and Payment interfaces
+const (
+	JSONTypeACH   = "ach"
+	JSONTypeCheck = "check"
+)
+
+// jsonFile is the wire representation of a File
+type jsonFile struct {
+	Header    *FileHeader       `json:"header"`
+	Schedules []json.RawMessage `json:"schedules"`
+	Trailer   *FileTrailer      `json:"trailer"`
+}
+
+// jsonTypeProbe reads only the discriminator of a schedule or payment object
+type jsonTypeProbe struct {
+	Type string `json:"type"`
+}
+
+// Alias types drop the JSON methods so the default encoding can be reused
+type achPaymentAlias ACHPayment
+type checkPaymentAlias CheckPayment
+
+// jsonSchedule is the wire representation shared by ACH and check schedules
+type jsonSchedule struct {
+	Type           string            `json:"type"`
+	ScheduleNumber string            `json:"scheduleNumber,omitempty"`
+	PaymentType    string            `json:"paymentType,omitempty"`
+	ALC            string            `json:"alc,omitempty"`
+	Header         json.RawMessage   `json:"header"`
+	Payments       []json.RawMessage `json:"payments"`
+	Trailer        *ScheduleTrailer  `json:"trailer"`
+}
+
+// MarshalJSON encodes the file with a "type" discriminator on every schedule
+func (f *File) MarshalJSON() ([]byte, error) {
+	out := jsonFile{
+		Header:    f.Header,
+		Schedules: make([]json.RawMessage, 0, len(f.Schedules)),
+		Trailer:   f.Trailer,
+	}
+
+	for i, schedule := range f.Schedules {
+		data, err := json.Marshal(schedule)
+		if err != nil {
+			return nil, fmt.Errorf("encoding schedule %d: %w", i, err)
+		}
+		out.Schedules = append(out.Schedules, data)
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a file, restoring concrete schedule and payment types
+func (f *File) UnmarshalJSON(data []byte) error {
+	var in jsonFile
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	f.Header = in.Header
+	f.Trailer = in.Trailer
+	f.Schedules = make([]Schedule, 0, len(in.Schedules))
+
+	for i, raw := range in.Schedules {
+		schedule, err := unmarshalScheduleJSON(raw)
+		if err != nil {
+			return fmt.Errorf("decoding schedule %d: %w", i, err)
+		}
+		f.Schedules = append(f.Schedules, schedule)
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes an ACH schedule with the "ach" discriminator
+func (s *ACHSchedule) MarshalJSON() ([]byte, error) {
+	header, err := json.Marshal(s.Header)
+	if err != nil {
+		return nil, err
+	}
+	return marshalScheduleJSON(JSONTypeACH, &s.BaseSchedule, header)
+}
+
+// UnmarshalJSON decodes an ACH schedule
+func (s *ACHSchedule) UnmarshalJSON(data []byte) error {
+	in, payments, err := unmarshalScheduleBody(data, JSONTypeACH)
+	if err != nil {
+		return err
+	}
+
+	var header *ACHScheduleHeader
+	if err := json.Unmarshal(in.Header, &header); err != nil {
+		return fmt.Errorf("decoding ACH schedule header: %w", err)
+	}
+
+	// Payments inherit the SEC code from the header, as they do when read from SPR
+	if header != nil {
+		for _, payment := range payments {
+			if achPayment, ok := payment.(*ACHPayment); ok && achPayment.StandardEntryClassCode == "" {
+				achPayment.StandardEntryClassCode = header.StandardEntryClassCode
+			}
+		}
+	}
+
+	s.Header = header
+	s.BaseSchedule = newBaseScheduleFromJSON(in, payments)
+	return nil
+}
+
+// MarshalJSON encodes a check schedule with the "check" discriminator
+func (s *CheckSchedule) MarshalJSON() ([]byte, error) {
+	header, err := json.Marshal(s.Header)
+	if err != nil {
+		return nil, err
+	}
+	return marshalScheduleJSON(JSONTypeCheck, &s.BaseSchedule, header)
+}
+
+// UnmarshalJSON decodes a check schedule
+func (s *CheckSchedule) UnmarshalJSON(data []byte) error {
+	in, payments, err := unmarshalScheduleBody(data, JSONTypeCheck)
+	if err != nil {
+		return err
+	}
+
+	var header *CheckScheduleHeader
+	if err := json.Unmarshal(in.Header, &header); err != nil {
+		return fmt.Errorf("decoding check schedule header: %w", err)
+	}
+
+	s.Header = header
+	s.BaseSchedule = newBaseScheduleFromJSON(in, payments)
+	return nil
+}
+
+// MarshalJSON encodes an ACH payment with the "ach" discriminator
+func (p *ACHPayment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*achPaymentAlias
+	}{
+		Type:            JSONTypeACH,
+		achPaymentAlias: (*achPaymentAlias)(p),
+	})
+}
+
+// UnmarshalJSON decodes an ACH payment, ignoring the discriminator
+func (p *ACHPayment) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*achPaymentAlias)(p))
+}
+
+// MarshalJSON encodes a check payment with the "check" discriminator
+func (p *CheckPayment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*checkPaymentAlias
+	}{
+		Type:              JSONTypeCheck,
+		checkPaymentAlias: (*checkPaymentAlias)(p),
+	})
+}
+
+// UnmarshalJSON decodes a check payment, ignoring the discriminator
+func (p *CheckPayment) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*checkPaymentAlias)(p))
+}
+
+// EncodeJSON writes a file to w as indented JSON
+func EncodeJSON(w io.Writer, file *File) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+// DecodeJSON reads a file previously produced by EncodeJSON (or an equivalent producer)
+func DecodeJSON(r io.Reader) (*File, error) {
+	file := &File{}
+	if err := json.NewDecoder(r).Decode(file); err != nil {
+		return nil, fmt.Errorf("decoding JSON: %w", err)
+	}
+	return file, nil
+}
+
+// Helper functions
+
+func marshalScheduleJSON(scheduleType string, base *BaseSchedule, header json.RawMessage) ([]byte, error) {
+	out := jsonSchedule{
+		Type:           scheduleType,
+		ScheduleNumber: base.ScheduleNumber,
+		PaymentType:    base.PaymentType,
+		ALC:            base.ALC,
+		Header:         header,
+		Payments:       make([]json.RawMessage, 0, len(base.Payments)),
+		Trailer:        base.Trailer,
+	}
+
+	for i, payment := range base.Payments {
+		data, err := json.Marshal(payment)
+		if err != nil {
+			return nil, fmt.Errorf("encoding payment %d: %w", i, err)
+		}
+		out.Payments = append(out.Payments, data)
+	}
+
+	return json.Marshal(out)
+}
+
+func unmarshalScheduleBody(data []byte, expectedType string) (*jsonSchedule, []Payment, error) {
+	var in jsonSchedule
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, nil, err
+	}
+
+	if in.Type != "" && !strings.EqualFold(in.Type, expectedType) {
+		return nil, nil, fmt.Errorf("schedule type %q does not match %q", in.Type, expectedType)
+	}
+
+	payments := make([]Payment, 0, len(in.Payments))
+	for i, raw := range in.Payments {
+		payment, err := unmarshalPaymentJSON(raw, expectedType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("decoding payment %d: %w", i, err)
+		}
+		payments = append(payments, payment)
+	}
+
+	return &in, payments, nil
+}
+
+func newBaseScheduleFromJSON(in *jsonSchedule, payments []Payment) BaseSchedule {
+	return BaseSchedule{
+		ScheduleNumber: in.ScheduleNumber,
+		PaymentType:    in.PaymentType,
+		ALC:            in.ALC,
+		Payments:       payments,
+		Trailer:        in.Trailer,
+	}
+}
+
+func unmarshalScheduleJSON(data []byte) (Schedule, error) {
+	var probe jsonTypeProbe
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(probe.Type) {
+	case JSONTypeACH:
+		schedule := &ACHSchedule{}
+		if err := json.Unmarshal(data, schedule); err != nil {
+			return nil, err
+		}
+		return schedule, nil
+	case JSONTypeCheck:
+		schedule := &CheckSchedule{}
+		if err := json.Unmarshal(data, schedule); err != nil {
+			return nil, err
+		}
+		return schedule, nil
+	case "":
+		return nil, fmt.Errorf("schedule is missing the \"type\" discriminator")
+	default:
+		return nil, fmt.Errorf("unknown schedule type %q", probe.Type)
+	}
+}
+
+// unmarshalPaymentJSON decodes a payment of a schedule; a missing discriminator
+// falls back to the schedule type, and a different one is an error
+func unmarshalPaymentJSON(data []byte, scheduleType string) (Payment, error) {
+	var probe jsonTypeProbe
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+
+	if probe.Type != "" && !strings.EqualFold(probe.Type, scheduleType) {
+		return nil, fmt.Errorf("payment type %q does not match schedule type %q", probe.Type, scheduleType)
+	}
+	paymentType := strings.ToLower(scheduleType)
+
+	switch paymentType {
+	case JSONTypeACH:
+		payment := &ACHPayment{}
+		if err := json.Unmarshal(data, payment); err != nil {
+			return nil, err
+		}
+		return payment, nil
+	case JSONTypeCheck:
+		payment := &CheckPayment{}
+		if err := json.Unmarshal(data, payment); err != nil {
+			return nil, err
+		}
+		return payment, nil
+	default:
+		return nil, fmt.Errorf("unknown payment type %q", probe.Type)
+	}
+}
@@ -0,0 +1,208 @@
+package pamspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		createFile func() *File
+	}{
+		{"ACH only file", createTestACHFile},
+		{"Check only file", createTestCheckFile},
+		{"Mixed ACH and Check file", createTestMixedFile},
+		{"ACH with addenda", createTestACHWithAddenda},
+		{"File with CARS/TAS/BETC records", createTestFileWithCARS},
+		{"File with DNP records", createTestFileWithDNP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.createFile()
+
+			var sprBuf bytes.Buffer
+			if err := NewWriter(&sprBuf).Write(original); err != nil {
+				t.Fatalf("Failed to write original file: %v", err)
+			}
+
+			var jsonBuf bytes.Buffer
+			if err := EncodeJSON(&jsonBuf, original); err != nil {
+				t.Fatalf("Failed to encode JSON: %v", err)
+			}
+
+			decoded, err := DecodeJSON(&jsonBuf)
+			if err != nil {
+				t.Fatalf("Failed to decode JSON: %v", err)
+			}
+
+			validateFileStructure(t, original, decoded)
+
+			var roundTrip bytes.Buffer
+			if err := NewWriter(&roundTrip).Write(decoded); err != nil {
+				t.Fatalf("Failed to write decoded file: %v", err)
+			}
+
+			if sprBuf.String() != roundTrip.String() {
+				t.Error("JSON round trip produced different SPR output")
+			}
+		})
+	}
+}
+
+func TestJSONDiscriminators(t *testing.T) {
+	data, err := json.Marshal(createTestMixedFile())
+	if err != nil {
+		t.Fatalf("Failed to marshal file: %v", err)
+	}
+
+	var raw struct {
+		Schedules []struct {
+			Type     string `json:"type"`
+			Payments []struct {
+				Type string `json:"type"`
+			} `json:"payments"`
+		} `json:"schedules"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to inspect JSON: %v", err)
+	}
+
+	if len(raw.Schedules) != 2 {
+		t.Fatalf("Expected 2 schedules, got %d", len(raw.Schedules))
+	}
+	if raw.Schedules[0].Type != JSONTypeACH || raw.Schedules[0].Payments[0].Type != JSONTypeACH {
+		t.Errorf("Expected ACH discriminators on first schedule, got %+v", raw.Schedules[0])
+	}
+	if raw.Schedules[1].Type != JSONTypeCheck || raw.Schedules[1].Payments[0].Type != JSONTypeCheck {
+		t.Errorf("Expected check discriminators on second schedule, got %+v", raw.Schedules[1])
+	}
+}
+
+func TestJSONKeyNames(t *testing.T) {
+	file := createTestMixedFile()
+	ach := file.Schedules[0].(*ACHSchedule).Payments[0].(*ACHPayment)
+	ach.Addenda = []*ACHAddendum{{RecordCode: "03", PaymentID: ach.PaymentID, AddendaInformation: "INVOICE 1"}}
+	ach.CARSTASBETC = []*CARSTASBETC{{RecordCode: "G ", PaymentID: ach.PaymentID, AccountClassificationAmount: ach.Amount}}
+	ach.DNP = &DNPRecord{RecordCode: "DD", PaymentID: ach.PaymentID, DNPDetail: "DETAIL"}
+	for _, payment := range file.Schedules[0].GetPayments() {
+		payment.(*ACHPayment).StandardEntryClassCode = "PPD" // Inherited from the header when decoded
+	}
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("Failed to marshal file: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to unmarshal file: %v", err)
+	}
+
+	// Every key is camelCase, from the envelope down to the associated records
+	var walk func(path string, value interface{})
+	walk = func(path string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				if key[0] < 'a' || key[0] > 'z' || strings.Contains(key, "_") {
+					t.Errorf("Key %s.%s is not camelCase", path, key)
+				}
+				walk(path+"."+key, child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(path+"[]", child)
+			}
+		}
+	}
+	walk("file", doc)
+
+	schedules := doc["schedules"].([]interface{})
+	payment := schedules[0].(map[string]interface{})["payments"].([]interface{})[0].(map[string]interface{})
+	check := schedules[1].(map[string]interface{})["payments"].([]interface{})[0].(map[string]interface{})
+	tests := []struct {
+		record map[string]interface{}
+		key    string
+	}{
+		{doc["header"].(map[string]interface{}), "recordCode"},
+		{doc["trailer"].(map[string]interface{}), "totalAmountPayments"},
+		{payment, "paymentId"},
+		{payment, "achTransactionCode"},
+		{payment, "isTopOffset"},
+		{payment, "addenda"},
+		{payment, "carsTasBetc"},
+		{payment, "dnp"},
+		{check, "stub"},
+		{check, "payeeIdentifierSecondary"},
+	}
+	for _, tt := range tests {
+		if _, ok := tt.record[tt.key]; !ok {
+			t.Errorf("Expected key %q", tt.key)
+		}
+	}
+
+	decoded, err := DecodeJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	roundTrip, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("Failed to marshal decoded file: %v", err)
+	}
+	if !bytes.Equal(data, roundTrip) {
+		t.Errorf("JSON round trip changed the document:\n%s\n%s", data, roundTrip)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Malformed JSON", `{"header":`},
+		{"Missing schedule type", `{"schedules":[{"payments":[]}]}`},
+		{"Unknown schedule type", `{"schedules":[{"type":"wire","payments":[]}]}`},
+		{"Unknown payment type", `{"schedules":[{"type":"ach","payments":[{"type":"wire"}]}]}`},
+		{"Check payment in ACH schedule", `{"schedules":[{"type":"ach","header":{},"payments":[{"type":"check"}]}]}`},
+		{"ACH payment in check schedule", `{"schedules":[{"type":"check","header":{},"payments":[{"type":"ach"}]}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeJSON(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestDecodeJSONDefaultsPaymentType(t *testing.T) {
+	input := `{
+		"header": {"recordCode": "H ", "standardPaymentVersion": "502"},
+		"schedules": [{
+			"type": "ach",
+			"header": {"recordCode": "01", "standardEntryClassCode": "CCD"},
+			"payments": [{"recordCode": "02", "paymentId": "PAY001", "amount": 1500}]
+		}]
+	}`
+
+	file, err := DecodeJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	payment, ok := file.Schedules[0].GetPayments()[0].(*ACHPayment)
+	if !ok {
+		t.Fatalf("Expected *ACHPayment, got %T", file.Schedules[0].GetPayments()[0])
+	}
+	if payment.StandardEntryClassCode != "CCD" {
+		t.Errorf("Expected SEC code inherited from header, got %q", payment.StandardEntryClassCode)
+	}
+	if payment.Amount != 1500 {
+		t.Errorf("Expected amount 1500, got %d", payment.Amount)
+	}
+}
@@ -0,0 +1,166 @@
+package pamspr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// LineTerminator selects how records are delimited in an SPR file
+type LineTerminator int
+
+const (
+	LineTerminatorAuto LineTerminator = iota // Reader: LF or CRLF, fixed blocks detected; Writer: LF (default)
+	LineTerminatorLF                         // Records end in LF; a CR before it is record data
+	LineTerminatorCRLF                       // Records end in CR LF
+	LineTerminatorNone                       // Fixed 850-byte blocks with no delimiters
+)
+
+// String returns the terminator name
+func (t LineTerminator) String() string {
+	switch t {
+	case LineTerminatorAuto:
+		return "auto"
+	case LineTerminatorLF:
+		return "lf"
+	case LineTerminatorCRLF:
+		return "crlf"
+	case LineTerminatorNone:
+		return "none"
+	default:
+		return fmt.Sprintf("LineTerminator(%d)", int(t))
+	}
+}
+
+// ParseLineTerminator parses a terminator name such as "auto", "lf", "crlf" or "none"
+func ParseLineTerminator(name string) (LineTerminator, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "auto":
+		return LineTerminatorAuto, nil
+	case "lf", "unix":
+		return LineTerminatorLF, nil
+	case "crlf", "windows", "dos":
+		return LineTerminatorCRLF, nil
+	case "none", "fixed", "fixed-block":
+		return LineTerminatorNone, nil
+	default:
+		return LineTerminatorAuto, fmt.Errorf("unknown line terminator %q (want auto, lf, crlf or none)", name)
+	}
+}
+
+// bytes returns the terminator the writer appends to each record
+func (t LineTerminator) bytes() string {
+	switch t {
+	case LineTerminatorCRLF:
+		return "\r\n"
+	case LineTerminatorNone:
+		return ""
+	default:
+		return "\n"
+	}
+}
+
+// ReaderWarning describes input the reader accepted after adjusting it
+type ReaderWarning struct {
+	LineNumber int    `json:"lineNumber,omitempty"` // 1-based record number, 0 for the whole file
+	Message    string `json:"message"`
+}
+
+// String formats the warning as a single line of text
+func (w ReaderWarning) String() string {
+	if w.LineNumber > 0 {
+		return fmt.Sprintf("line %d: warning: %s", w.LineNumber, w.Message)
+	}
+	return "warning: " + w.Message
+}
+
+// recordSplitter is a bufio.SplitFunc that applies the reader's terminator settings
+type recordSplitter struct {
+	terminator LineTerminator
+	warn       func(lineNumber int, message string) // Optional
+	records    int64                                // Records returned so far
+	consumed   int64                                // Input bytes consumed so far
+	decided    bool                                 // Fixed-block detection has run
+	fixedBlock bool
+	warnedCR   bool
+	warnedLF   bool
+}
+
+func (s *recordSplitter) split(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if !s.decided {
+		newline := bytes.IndexByte(data, '\n')
+		if newline < 0 && len(data) < 2*RecordLength && !atEOF {
+			return 0, nil, nil // Need more data to tell blocks from lines
+		}
+		s.decided = true
+		if newline < 0 {
+			newline = len(data)
+		}
+		switch {
+		case s.terminator == LineTerminatorNone:
+			s.fixedBlock = true
+		case s.terminator == LineTerminatorAuto && newline >= 2*RecordLength:
+			s.fixedBlock = true
+			s.warning(0, fmt.Sprintf("no line delimiters found, reading fixed %d-byte records", RecordLength))
+		}
+	}
+
+	if s.fixedBlock {
+		if len(data) >= RecordLength {
+			s.records++
+			s.consumed += RecordLength
+			return RecordLength, data[:RecordLength], nil
+		}
+		if !atEOF {
+			return 0, nil, nil
+		}
+		// A final newline after the last block is not a record
+		if rest := bytes.TrimRight(data, "\r\n"); len(rest) > 0 {
+			s.records++
+			s.consumed += int64(len(data))
+			return len(data), rest, nil
+		}
+		s.consumed += int64(len(data))
+		return len(data), nil, nil
+	}
+
+	line, advance := data, len(data)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line, advance = data[:i], i+1
+	} else if !atEOF {
+		return 0, nil, nil
+	}
+
+	s.records++
+	hasCR := len(line) > 0 && line[len(line)-1] == '\r'
+	switch s.terminator {
+	case LineTerminatorAuto:
+		if hasCR {
+			line = line[:len(line)-1]
+			if !s.warnedCR {
+				s.warnedCR = true
+				s.warning(int(s.records), "records end in CRLF, carriage returns removed")
+			}
+		}
+	case LineTerminatorCRLF:
+		if hasCR {
+			line = line[:len(line)-1]
+		} else if advance > len(line) && !s.warnedLF {
+			s.warnedLF = true
+			s.warning(int(s.records), "record ends in LF without CR")
+		}
+	}
+
+	s.consumed += int64(advance)
+	return advance, line, nil
+}
+
+func (s *recordSplitter) warning(lineNumber int, message string) {
+	if s.warn != nil {
+		s.warn(lineNumber, message)
+	}
+}
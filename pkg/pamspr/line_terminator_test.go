@@ -0,0 +1,143 @@
+package pamspr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseLineTerminator(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  LineTerminator
+		expectErr bool
+	}{
+		{"", LineTerminatorAuto, false},
+		{"LF", LineTerminatorLF, false},
+		{"crlf", LineTerminatorCRLF, false},
+		{"fixed", LineTerminatorNone, false},
+		{"cr", LineTerminatorAuto, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLineTerminator(tt.name)
+			if (err != nil) != tt.expectErr || got != tt.expected {
+				t.Errorf("ParseLineTerminator(%q) = %v, %v; want %v", tt.name, got, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReaderLineTerminators(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestACHFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	records := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	stripped := make([]string, len(records))
+	for i, record := range records {
+		stripped[i] = strings.TrimRight(record, " ")
+	}
+
+	tests := []struct {
+		name       string
+		data       string
+		terminator LineTerminator
+		pad        bool
+		expectErr  bool
+		warnings   []string
+	}{
+		{"LF", strings.Join(records, "\n") + "\n", LineTerminatorAuto, false, false, nil},
+		{"CRLF detected", strings.Join(records, "\r\n") + "\r\n", LineTerminatorAuto, false, false,
+			[]string{"line 1: warning: records end in CRLF"}},
+		{"CRLF expected", strings.Join(records, "\r\n") + "\r\n", LineTerminatorCRLF, false, false, nil},
+		{"LF when CRLF expected", strings.Join(records, "\n"), LineTerminatorCRLF, false, false,
+			[]string{"line 1: warning: record ends in LF without CR"}},
+		{"CRLF when LF expected", strings.Join(records, "\r\n"), LineTerminatorLF, false, true, nil},
+		{"Fixed block detected", strings.Join(records, ""), LineTerminatorAuto, false, false,
+			[]string{"warning: no line delimiters found"}},
+		{"Fixed block with final newline", strings.Join(records, "") + "\n", LineTerminatorAuto, false, false,
+			[]string{"warning: no line delimiters found"}},
+		{"Fixed block expected", strings.Join(records, ""), LineTerminatorNone, false, false, nil},
+		{"Stripped trailing spaces", strings.Join(stripped, "\n"), LineTerminatorAuto, false, true, nil},
+		{"Stripped trailing spaces padded", strings.Join(stripped, "\r\n"), LineTerminatorAuto, true, false,
+			[]string{"records end in CRLF", "line 1: warning: record padded from", "line 2: warning", "line 3: warning",
+				"line 4: warning", "line 5: warning", "line 6: warning"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.LineTerminator = tt.terminator
+			config.PadShortRecords = tt.pad
+			reader := NewReaderWithConfig(strings.NewReader(tt.data), config)
+
+			file, err := reader.Read()
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if len(file.Schedules) != 1 || file.Trailer.TotalAmountPayments != 300000 {
+				t.Errorf("Unexpected file read: %d schedules, trailer %+v", len(file.Schedules), file.Trailer)
+			}
+
+			warnings := reader.GetWarnings()
+			if len(warnings) != len(tt.warnings) {
+				t.Fatalf("Expected %d warnings, got %v", len(tt.warnings), warnings)
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(warnings[i].String(), want) {
+					t.Errorf("Warning %d: expected %q in %q", i, want, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriterLineTerminator(t *testing.T) {
+	tests := []struct {
+		terminator LineTerminator
+		suffix     string
+	}{
+		{LineTerminatorAuto, "\n"},
+		{LineTerminatorLF, "\n"},
+		{LineTerminatorCRLF, "\r\n"},
+		{LineTerminatorNone, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.terminator.String(), func(t *testing.T) {
+			config := DefaultWriterConfig()
+			config.LineTerminator = tt.terminator
+			var buf bytes.Buffer
+			if err := NewWriterWithConfig(&buf, config).Write(createTestACHFile()); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+
+			data := buf.String()
+			if len(data) != 6*(RecordLength+len(tt.suffix)) {
+				t.Errorf("Unexpected output length %d", len(data))
+			}
+			if data[RecordLength:RecordLength+len(tt.suffix)] != tt.suffix {
+				t.Errorf("Expected records to end in %q", tt.suffix)
+			}
+
+			readerConfig := DefaultConfig()
+			readerConfig.LineTerminator = tt.terminator
+			reader := NewReaderWithConfig(strings.NewReader(data), readerConfig)
+			if _, err := reader.Read(); err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if len(reader.GetWarnings()) != 0 {
+				t.Errorf("Unexpected warnings: %v", reader.GetWarnings())
+			}
+		})
+	}
+}
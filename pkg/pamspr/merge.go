@@ -0,0 +1,115 @@
+package pamspr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Merge combines the schedules of files into one file for a single
+// transmission. The files must share the InputSystem, StandardPaymentVersion
+// and Same Day ACH flag of their headers; schedule numbers and PaymentIDs must
+// be unique across them, and a Same Day ACH file can only hold ACH schedules.
+// Every conflict found is returned, joined into one error. The merged file
+// has a new trailer and shares its schedules with files.
+func Merge(files ...*File) (*File, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to merge")
+	}
+	for i, file := range files {
+		if file == nil || file.Header == nil {
+			return nil, fmt.Errorf("file %d: file header is required", i+1)
+		}
+	}
+
+	first := files[0].Header
+	header := *first
+	merged := &File{
+		Header:    &header,
+		Schedules: make([]Schedule, 0),
+	}
+
+	var errs []error
+	conflict := func(err ValidationError, file int, format string, args ...interface{}) {
+		err.Message = fmt.Sprintf(format, args...)
+		errs = append(errs, fmt.Errorf("file %d: %w", file+1, err))
+	}
+
+	schedules := make(map[string]int) // Schedule number to the file it came from
+	payments := make(map[string]int)  // PaymentID to the file it came from
+	sameDay := first.IsRequestedForSameDayACH == SDAFlagEnabled
+
+	for i, file := range files {
+		h := file.Header
+		if i > 0 {
+			if !strings.EqualFold(strings.TrimSpace(h.InputSystem), strings.TrimSpace(first.InputSystem)) {
+				conflict(ValidationError{Field: "InputSystem", Value: h.InputSystem, Rule: "merge_input_system"}, i,
+					"input system %q differs from %q", strings.TrimSpace(h.InputSystem), strings.TrimSpace(first.InputSystem))
+			}
+			if strings.TrimSpace(h.StandardPaymentVersion) != strings.TrimSpace(first.StandardPaymentVersion) {
+				conflict(ValidationError{Field: "StandardPaymentVersion", Value: h.StandardPaymentVersion, Rule: "merge_version"}, i,
+					"standard payment version %q differs from %q", h.StandardPaymentVersion, first.StandardPaymentVersion)
+			}
+			if (h.IsRequestedForSameDayACH == SDAFlagEnabled) != sameDay {
+				conflict(ValidationError{Field: "IsRequestedForSameDayACH", Value: h.IsRequestedForSameDayACH, Rule: "merge_sda_flag"}, i,
+					"Same Day ACH flag %q differs from %q", h.IsRequestedForSameDayACH, first.IsRequestedForSameDayACH)
+			}
+		}
+
+		for _, schedule := range file.Schedules {
+			number := writtenScheduleNumber(schedule)
+			if sameDay {
+				if _, ok := schedule.(*CheckSchedule); ok {
+					conflict(ValidationError{Field: "ScheduleNumber", Value: number, Rule: "sda_ach_only", Code: CodeSDANonACHSchedule}, i,
+						"check schedule %s cannot be merged into a Same Day ACH file", strings.TrimSpace(number))
+				}
+			}
+
+			key := scheduleKey(number)
+			if from, ok := schedules[key]; ok {
+				conflict(ValidationError{Field: "ScheduleNumber", Value: number, Rule: "merge_unique_schedule", Code: CodeDuplicateScheduleInFile}, i,
+					"schedule number %s is also in file %d", strings.TrimSpace(number), from+1)
+			} else {
+				schedules[key] = i
+			}
+
+			for _, payment := range schedule.GetPayments() {
+				id := strings.TrimSpace(payment.GetPaymentID())
+				if id == "" {
+					continue // Left to payment validation
+				}
+				if from, ok := payments[id]; ok {
+					conflict(ValidationError{Field: "PaymentID", Value: id, Rule: "merge_unique_payment_id"}, i,
+						"payment ID %s in schedule %s is also in file %d", id, strings.TrimSpace(number), from+1)
+				} else {
+					payments[id] = i
+				}
+			}
+
+			merged.Schedules = append(merged.Schedules, schedule)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	merged.Trailer = mergedTrailer(merged)
+	return merged, nil
+}
+
+// mergedTrailer totals the schedules of a file into a new file trailer
+func mergedTrailer(file *File) *FileTrailer {
+	validator := NewValidator()
+	trailer := &FileTrailer{
+		RecordCode:        string(RecordTypeFileTrailer),
+		TotalCountRecords: 2, // File header and trailer
+	}
+	for _, schedule := range file.Schedules {
+		balance := validator.scheduleBalance(schedule)
+		trailer.TotalCountRecords += balance.Records
+		trailer.TotalCountPayments += balance.Payments
+		trailer.TotalAmountPayments += balance.Amount
+	}
+	return trailer
+}
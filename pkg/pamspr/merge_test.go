@@ -0,0 +1,132 @@
+package pamspr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	ach := createTestACHFile()
+	check := createTestCheckFile()
+	check.Schedules[0].(*CheckSchedule).Header.ScheduleNumber = "00000000000002"
+	check.Header.InputSystem = "test system "
+
+	merged, err := Merge(ach, check)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(merged.Schedules) != 2 {
+		t.Fatalf("Expected 2 schedules, got %d", len(merged.Schedules))
+	}
+	want := FileTrailer{RecordCode: "E ", TotalCountRecords: 10, TotalCountPayments: 3, TotalAmountPayments: 450000}
+	if *merged.Trailer != want {
+		t.Errorf("Expected trailer %+v, got %+v", want, *merged.Trailer)
+	}
+	if err := NewValidator().ValidateBalancing(merged); err != nil {
+		t.Errorf("Merged file does not balance: %v", err)
+	}
+	if merged.Header == ach.Header {
+		t.Error("Expected the merged file to have its own header")
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(merged); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := NewReader(&buf).Read(); err != nil {
+		t.Errorf("Merged file does not read back: %v", err)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    func() []*File
+		code     ErrorCode
+		messages []string
+	}{
+		{
+			name: "Input system",
+			files: func() []*File {
+				other := createTestCheckFile()
+				other.Schedules[0].(*CheckSchedule).Header.ScheduleNumber = "2"
+				return []*File{createTestACHFile(), other}
+			},
+			messages: []string{`input system "CHECK SYSTEM" differs from "TEST SYSTEM"`},
+		},
+		{
+			name: "Version and SDA flag",
+			files: func() []*File {
+				other := createTestACHFile()
+				other.Header.StandardPaymentVersion = "501"
+				other.Header.IsRequestedForSameDayACH = SDAFlagEnabled
+				other.Schedules = nil
+				return []*File{createTestACHFile(), other}
+			},
+			messages: []string{"standard payment version", "Same Day ACH flag"},
+		},
+		{
+			name: "Duplicate schedule and payment IDs",
+			files: func() []*File {
+				return []*File{createTestACHFile(), createTestACHFile()}
+			},
+			code: CodeDuplicateScheduleInFile,
+			messages: []string{
+				"file 2: ", "schedule number 00000000000001 is also in file 1",
+				"payment ID PAY001 in schedule 00000000000001 is also in file 1",
+				"payment ID PAY002",
+			},
+		},
+		{
+			name: "Schedule numbers compare zero-filled",
+			files: func() []*File {
+				other := createTestACHFile()
+				other.Schedules[0].(*ACHSchedule).Header.ScheduleNumber = "1"
+				other.Schedules[0].(*ACHSchedule).Payments = nil
+				return []*File{createTestACHFile(), other}
+			},
+			code:     CodeDuplicateScheduleInFile,
+			messages: []string{"schedule number 1 is also in file 1"},
+		},
+		{
+			name: "Check schedule in SDA file",
+			files: func() []*File {
+				sda := createTestACHFile()
+				sda.Header.IsRequestedForSameDayACH = SDAFlagEnabled
+				check := createTestCheckFile()
+				check.Header = sda.Header
+				check.Schedules[0].(*CheckSchedule).Header.ScheduleNumber = "2"
+				return []*File{sda, check}
+			},
+			code:     CodeSDANonACHSchedule,
+			messages: []string{"check schedule 2 cannot be merged into a Same Day ACH file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := Merge(tt.files()...)
+			if err == nil {
+				t.Fatalf("Expected a conflict, got a file with %d schedules", len(merged.Schedules))
+			}
+			for _, message := range tt.messages {
+				if !strings.Contains(err.Error(), message) {
+					t.Errorf("Expected %q in:\n%v", message, err)
+				}
+			}
+			var ve ValidationError
+			if !tt.code.IsZero() && (!errors.As(err, &ve) || ve.Code != tt.code) {
+				t.Errorf("Expected code %s, got %v", tt.code, ve.Code)
+			}
+		})
+	}
+
+	if _, err := Merge(); err == nil {
+		t.Error("Expected an error with no files")
+	}
+	if _, err := Merge(createTestACHFile(), &File{}); err == nil {
+		t.Error("Expected an error for a file without a header")
+	}
+}
@@ -0,0 +1,180 @@
+package pamspr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NormalizeChange describes one field rewritten by Normalize
+type NormalizeChange struct {
+	LineNumber    int    `json:"lineNumber"`
+	RecordCode    string `json:"recordCode"`
+	ScheduleIndex int    `json:"scheduleIndex"`
+	PaymentIndex  int    `json:"paymentIndex"`
+	Field         string `json:"field"`
+	Start         int    `json:"start"`
+	End           int    `json:"end"`
+	Before        string `json:"before"` // Field as received, padded to its length
+	After         string `json:"after"`  // Field as PAM stores it
+}
+
+// String formats the change as a diff entry
+func (c NormalizeChange) String() string {
+	return fmt.Sprintf("line %d record %q field %s (columns %d-%d):\n- %q\n+ %q",
+		c.LineNumber, c.RecordCode, c.Field, c.Start, c.End, c.Before, c.After)
+}
+
+// NormalizeField applies the PAM justification rules to a field value and
+// returns it padded to the field length. Alphanumeric fields are left
+// justified, numeric fields are right justified and zero filled, and blank
+// numeric fields that are not validated become zeros. Fields stored as
+// received and fillers are only padded.
+func (f *FieldFormatter) NormalizeField(value string, def FieldDefinition) string {
+	switch {
+	case def.Type == FieldTypeFiller || def.Justification == JustifyNone:
+		return f.formatField(value, def.Length)
+
+	case def.Justification == JustifyRight:
+		trimmed := strings.TrimSpace(value)
+		if def.Type != FieldTypeNumeric {
+			// Zero filled identifiers such as schedule numbers
+			trimmed = strings.ReplaceAll(trimmed, " ", "")
+		}
+		if trimmed == "" {
+			// Required fields stay blank so validation still reports them
+			if def.Type == FieldTypeNumeric && !def.Required {
+				return strings.Repeat("0", def.Length)
+			}
+			return f.formatField(value, def.Length)
+		}
+		if len(trimmed) > def.Length {
+			return f.formatField(value, def.Length)
+		}
+		return f.formatFieldRightJustified(trimmed, def.Length, '0')
+
+	default:
+		return f.formatField(strings.TrimLeft(value, " "), def.Length)
+	}
+}
+
+// Normalize corrects field justification throughout the file the way PAM
+// does when it stores a file, and returns every change in file order.
+// Only string fields are affected; numeric fields held as integers are
+// always written right justified and zero filled.
+func Normalize(file *File) []NormalizeChange {
+	if file == nil {
+		return nil
+	}
+
+	n := &normalizer{formatter: NewFieldFormatter(nil), line: 1}
+	n.record(file.Header, "H ", NoIndex, NoIndex)
+
+	for i, schedule := range file.Schedules {
+		n.line++
+		switch s := schedule.(type) {
+		case *ACHSchedule:
+			n.record(s.Header, "01", i, NoIndex)
+			if s.Header != nil {
+				s.ScheduleNumber = s.Header.ScheduleNumber
+				s.ALC = s.Header.AgencyLocationCode
+			}
+		case *CheckSchedule:
+			n.record(s.Header, "11", i, NoIndex)
+			if s.Header != nil {
+				s.ScheduleNumber = s.Header.ScheduleNumber
+				s.ALC = s.Header.AgencyLocationCode
+			}
+		}
+
+		for j, payment := range schedule.GetPayments() {
+			n.line++
+			n.payment(payment, i, j)
+		}
+
+		n.line++ // Schedule trailer
+	}
+
+	return n.changes
+}
+
+type normalizer struct {
+	formatter *FieldFormatter
+	changes   []NormalizeChange
+	line      int
+}
+
+func (n *normalizer) payment(payment Payment, sched, pay int) {
+	switch p := payment.(type) {
+	case *ACHPayment:
+		n.record(p, "02", sched, pay)
+		for _, addendum := range p.Addenda {
+			n.line++
+			n.record(addendum, "03", sched, pay)
+		}
+		for _, addendum := range p.CTXAddenda {
+			n.line++
+			n.record(addendum, "04", sched, pay)
+		}
+		n.associated(p.CARSTASBETC, p.DNP, sched, pay)
+
+	case *CheckPayment:
+		n.record(p, "12", sched, pay)
+		if p.Stub != nil {
+			// Stub lines are printed as written
+			n.line++
+		}
+		n.associated(p.CARSTASBETC, p.DNP, sched, pay)
+	}
+}
+
+func (n *normalizer) associated(cars []*CARSTASBETC, dnp *DNPRecord, sched, pay int) {
+	for _, car := range cars {
+		n.line++
+		n.record(car, "G ", sched, pay)
+	}
+	if dnp != nil {
+		n.line++
+		n.record(dnp, "DD", sched, pay)
+	}
+}
+
+// record normalizes the string fields of a record struct that have a field definition
+func (n *normalizer) record(record interface{}, recordCode string, sched, pay int) {
+	value := reflect.ValueOf(record)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return
+	}
+	value = value.Elem()
+
+	for _, named := range GetOrderedFieldDefinitions(recordCode) {
+		field := value.FieldByName(named.Name)
+		if !field.IsValid() || field.Kind() != reflect.String || !field.CanSet() {
+			continue
+		}
+
+		before := n.formatter.formatField(field.String(), named.Length)
+		after := n.formatter.NormalizeField(field.String(), named.FieldDefinition)
+		if before == after {
+			continue
+		}
+
+		if named.Justification == JustifyLeft {
+			field.SetString(strings.TrimRight(after, " "))
+		} else {
+			field.SetString(after)
+		}
+
+		n.changes = append(n.changes, NormalizeChange{
+			LineNumber:    n.line,
+			RecordCode:    recordCode,
+			ScheduleIndex: sched,
+			PaymentIndex:  pay,
+			Field:         named.Name,
+			Start:         named.Start,
+			End:           named.End,
+			Before:        before,
+			After:         after,
+		})
+	}
+}
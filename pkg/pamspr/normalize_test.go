@@ -0,0 +1,95 @@
+package pamspr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeField(t *testing.T) {
+	formatter := NewFieldFormatter(nil)
+
+	tests := []struct {
+		name     string
+		value    string
+		def      FieldDefinition
+		expected string
+	}{
+		{"Left justify alphanumeric", "  ABC", NewFieldDef(1, 6, true), "ABC   "},
+		{"Alphanumeric unchanged", "ABC", NewFieldDef(1, 6, true), "ABC   "},
+		{"Right justify numeric", "123  ", NewFieldDef(1, 6, true).Numeric(), "000123"},
+		{"Zero fill numeric", "42", NewFieldDef(1, 6, true).Numeric(), "000042"},
+		{"Blank optional numeric", "", NewFieldDef(1, 4, false).Numeric(), "0000"},
+		{"Blank required numeric", "", NewFieldDef(1, 4, true).Numeric(), "    "},
+		{"Invalid numeric kept", "12A", NewFieldDef(1, 4, true).Numeric(), "012A"},
+		{"Zero filled identifier", " 12 34", NewFieldDef(1, 8, true).ZeroFilled(), "00001234"},
+		{"As received", "  X", NewFieldDef(1, 4, false).AsReceived(), "  X "},
+		{"Filler", "  ", NewFillerDef(1, 4), "    "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatter.NormalizeField(tt.value, tt.def); got != tt.expected {
+				t.Errorf("NormalizeField(%q) = %q, want %q", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	file := createTestACHFile()
+	schedule := file.Schedules[0].(*ACHSchedule)
+	schedule.Header.ScheduleNumber = "1"
+	payment := schedule.Payments[1].(*ACHPayment)
+	payment.PayeeName = "   TEST PAYEE 2"
+	payment.RoutingNumber = "22000247 "
+
+	changes := Normalize(file)
+	if len(changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d: %v", len(changes), changes)
+	}
+
+	expected := []struct {
+		line  int
+		field string
+		after string
+	}{
+		{2, "ScheduleNumber", "00000000000001"},
+		{4, "PayeeName", "TEST PAYEE 2"},
+		{4, "RoutingNumber", "022000247"},
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.LineNumber != e.line || c.Field != e.field || strings.TrimRight(c.After, " ") != e.after {
+			t.Errorf("Change %d: got %s", i, c)
+		}
+	}
+	if changes[1].ScheduleIndex != 0 || changes[1].PaymentIndex != 1 || changes[1].RecordCode != "02" {
+		t.Errorf("Unexpected change location: %+v", changes[1])
+	}
+
+	// The file itself is corrected
+	if payment.PayeeName != "TEST PAYEE 2" || payment.RoutingNumber != "022000247" {
+		t.Errorf("Payment not normalized: %q %q", payment.PayeeName, payment.RoutingNumber)
+	}
+	if schedule.GetScheduleNumber() != "00000000000001" {
+		t.Errorf("Schedule number not updated: %q", schedule.GetScheduleNumber())
+	}
+
+	// A normalized file survives a round trip unchanged
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	read, err := NewReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if again := Normalize(read); len(again) != 0 {
+		t.Errorf("Expected no changes after normalizing, got %v", again)
+	}
+
+	if Normalize(nil) != nil {
+		t.Error("Expected no changes for a nil file")
+	}
+}
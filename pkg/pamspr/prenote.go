@@ -0,0 +1,125 @@
+package pamspr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prenoteTransactionCodes maps each ACH credit transaction code to its prenote
+var prenoteTransactionCodes = map[string]string{
+	"22": "23",
+	"24": "23", // Zero dollar credits prenote the account they credit
+	"32": "33",
+	"34": "33",
+	"42": "43",
+	"52": "53",
+}
+
+// PrenoteTransactionCode returns the prenote transaction code for an ACH
+// transaction code; prenote codes are returned unchanged
+func PrenoteTransactionCode(code string) (string, bool) {
+	if PrenoteTransactionCodes[code] {
+		return code, true
+	}
+	prenote, ok := prenoteTransactionCodes[code]
+	return prenote, ok
+}
+
+// NewPrenoteSchedule copies an ACH schedule as a prenote schedule numbered
+// scheduleNumber (blank keeps the number). Every payment gets the prenote
+// transaction code for its account type and a zero amount, as do its CARS
+// records; addenda and DNP records are kept.
+func NewPrenoteSchedule(schedule *ACHSchedule, scheduleNumber string) (*ACHSchedule, error) {
+	if schedule.Header == nil {
+		return nil, fmt.Errorf("ACH schedule header is required")
+	}
+
+	header := *schedule.Header
+	if strings.TrimSpace(scheduleNumber) != "" {
+		header.ScheduleNumber = scheduleNumber
+	}
+	if len(strings.TrimSpace(header.ScheduleNumber)) > ScheduleNumberLength {
+		return nil, fmt.Errorf("schedule number %s is longer than %d characters", strings.TrimSpace(header.ScheduleNumber), ScheduleNumberLength)
+	}
+
+	prenote := &ACHSchedule{
+		Header: &header,
+		BaseSchedule: BaseSchedule{
+			ScheduleNumber: header.ScheduleNumber,
+			PaymentType:    schedule.PaymentType,
+			ALC:            header.AgencyLocationCode,
+			Payments:       make([]Payment, 0, len(schedule.Payments)),
+		},
+	}
+
+	for i, payment := range schedule.Payments {
+		achPayment, ok := payment.(*ACHPayment)
+		if !ok {
+			return nil, fmt.Errorf("payment %d: ACH schedule cannot contain non-ACH payments", i)
+		}
+		code, ok := PrenoteTransactionCode(achPayment.ACH_TransactionCode)
+		if !ok {
+			return nil, fmt.Errorf("payment %d (%s): transaction code %q has no prenote",
+				i, strings.TrimSpace(achPayment.PaymentID), achPayment.ACH_TransactionCode)
+		}
+
+		p := *achPayment
+		p.ACH_TransactionCode = code
+		p.Amount = 0
+		if strings.TrimSpace(p.AmountEligibleForOffset) != "" {
+			p.AmountEligibleForOffset = "0000000000"
+		}
+		p.CARSTASBETC = make([]*CARSTASBETC, len(achPayment.CARSTASBETC))
+		for j, car := range achPayment.CARSTASBETC {
+			c := *car
+			c.AccountClassificationAmount = 0
+			p.CARSTASBETC[j] = &c
+		}
+		prenote.Payments = append(prenote.Payments, &p)
+	}
+
+	prenote.Trailer = &ScheduleTrailer{
+		RecordCode:    string(RecordTypeScheduleTrailer),
+		ScheduleCount: int64(len(prenote.Payments)),
+	}
+	return prenote, nil
+}
+
+// NewPrenoteFile builds a prenote file from the ACH schedules of file; check
+// schedules are left out. scheduleNumber names each prenote schedule from the
+// original number and its position among the ACH schedules; nil keeps the
+// numbers, which must then not have been sent in the fiscal year.
+func NewPrenoteFile(file *File, scheduleNumber func(original string, index int) string) (*File, error) {
+	if file.Header == nil {
+		return nil, fmt.Errorf("file header is required")
+	}
+
+	header := *file.Header
+	prenote := &File{
+		Header:    &header,
+		Schedules: make([]Schedule, 0, len(file.Schedules)),
+	}
+
+	for _, schedule := range file.Schedules {
+		achSchedule, ok := schedule.(*ACHSchedule)
+		if !ok {
+			continue
+		}
+		var number string
+		if scheduleNumber != nil {
+			number = scheduleNumber(strings.TrimSpace(writtenScheduleNumber(schedule)), len(prenote.Schedules))
+		}
+		s, err := NewPrenoteSchedule(achSchedule, number)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", strings.TrimSpace(writtenScheduleNumber(schedule)), err)
+		}
+		prenote.Schedules = append(prenote.Schedules, s)
+	}
+
+	if len(prenote.Schedules) == 0 {
+		return nil, fmt.Errorf("file has no ACH schedules to prenote")
+	}
+
+	prenote.Trailer = mergedTrailer(prenote)
+	return prenote, nil
+}
@@ -0,0 +1,99 @@
+package pamspr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrenoteTransactionCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"22", "23", true},
+		{"24", "23", true},
+		{"32", "33", true},
+		{"34", "33", true},
+		{"42", "43", true},
+		{"52", "53", true},
+		{"53", "53", true},
+		{"27", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := PrenoteTransactionCode(tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("PrenoteTransactionCode(%q) = %q, %v; want %q, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewPrenoteFile(t *testing.T) {
+	file := createTestMixedFile()
+	schedule := file.Schedules[0].(*ACHSchedule)
+	for _, payment := range schedule.Payments {
+		p := payment.(*ACHPayment)
+		p.TIN = "123456789"
+		p.AddCARSTASBETC(&CARSTASBETC{RecordCode: "G ", PaymentID: p.PaymentID, AccountClassificationAmount: p.Amount})
+	}
+	schedule.Payments[1].(*ACHPayment).ACH_TransactionCode = "32"
+
+	prenote, err := NewPrenoteFile(file, func(original string, index int) string {
+		return fmt.Sprintf("P%s", strings.TrimLeft(original, "0"))
+	})
+	if err != nil {
+		t.Fatalf("NewPrenoteFile failed: %v", err)
+	}
+
+	if len(prenote.Schedules) != 1 {
+		t.Fatalf("Expected only the ACH schedule, got %d schedules", len(prenote.Schedules))
+	}
+	s := prenote.Schedules[0].(*ACHSchedule)
+	if s.Header.ScheduleNumber != "P1" || s.Header == schedule.Header {
+		t.Errorf("Expected a new header numbered P1, got %q", s.Header.ScheduleNumber)
+	}
+	for i, want := range []string{"23", "33"} {
+		p := s.Payments[i].(*ACHPayment)
+		if p.ACH_TransactionCode != want || p.Amount != 0 || p.CARSTASBETC[0].AccountClassificationAmount != 0 {
+			t.Errorf("Payment %d: expected a zero dollar %s prenote, got %s for %d", i, want, p.ACH_TransactionCode, p.Amount)
+		}
+	}
+	if schedule.Payments[0].GetAmount() == 0 || schedule.Payments[0].(*ACHPayment).CARSTASBETC[0].AccountClassificationAmount == 0 {
+		t.Error("Expected the original schedule to be unchanged")
+	}
+
+	if report := NewValidator().ValidateFile(prenote); report.HasIssues() {
+		t.Errorf("Prenote file does not validate: %v", report.Issues)
+	}
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(prenote); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if _, err := NewReader(&buf).Read(); err != nil {
+		t.Errorf("Prenote file does not read back: %v", err)
+	}
+}
+
+func TestNewPrenoteFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    *File
+		number  func(string, int) string
+		message string
+	}{
+		{"No ACH schedules", createTestCheckFile(), nil, "no ACH schedules"},
+		{"Debit transaction code", createTestACHFile(), nil, `payment 1 (PAY002): transaction code "27" has no prenote`},
+		{"Schedule number too long", createTestACHFile(), func(string, int) string { return "PRENOTE-SCHEDULE-1" }, "longer than 14"},
+		{"No header", &File{}, nil, "header is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPrenoteFile(tt.file, tt.number)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
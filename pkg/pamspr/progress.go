@@ -0,0 +1,90 @@
+package pamspr
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultProgressInterval is the number of records between progress calls
+const DefaultProgressInterval = 1000
+
+// ProgressCallback receives the running totals of a read or write. For a
+// Writer, LinesProcessed counts records written and BytesProcessed the bytes
+// of output before encoding. It is called on the reading or writing goroutine,
+// so it should return quickly.
+type ProgressCallback func(stats Stats)
+
+func progressInterval(interval int) int {
+	if interval <= 0 {
+		return DefaultProgressInterval
+	}
+	return interval
+}
+
+// ProcessFileContext is ProcessFile that stops before the next record once ctx
+// is done, returning an error that wraps ctx.Err()
+func (r *Reader) ProcessFileContext(
+	ctx context.Context,
+	scheduleCallback ScheduleCallback,
+	paymentCallback PaymentCallback,
+	recordCallback RecordCallback,
+) error {
+	return r.withContext(ctx, func() error {
+		return r.ProcessFile(scheduleCallback, paymentCallback, recordCallback)
+	})
+}
+
+// ProcessPaymentsOnlyContext is ProcessPaymentsOnly that stops before the next
+// record once ctx is done, returning an error that wraps ctx.Err()
+func (r *Reader) ProcessPaymentsOnlyContext(ctx context.Context, callback PaymentCallback) error {
+	return r.withContext(ctx, func() error {
+		return r.ProcessPaymentsOnly(callback)
+	})
+}
+
+// ReadAllContext is ReadAll that stops before the next record once ctx is
+// done, returning an error that wraps ctx.Err()
+func (r *Reader) ReadAllContext(ctx context.Context) (*File, error) {
+	var file *File
+	err := r.withContext(ctx, func() error {
+		var err error
+		file, err = r.ReadAll()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// withContext runs read with ctx checked before every record. A read blocked
+// in the underlying io.Reader is not interrupted; close it to unblock.
+func (r *Reader) withContext(ctx context.Context, read func() error) error {
+	r.ctx, r.ctxErr = ctx, nil
+	defer func() { r.ctx = nil }()
+
+	err := read()
+	if r.ctxErr != nil {
+		// Whatever error the truncated input caused is beside the point
+		return fmt.Errorf("reading stopped after line %d: %w", r.lineNum, r.ctxErr)
+	}
+	return err
+}
+
+// WriteContext is Write that stops before the next record once ctx is done,
+// returning an error that wraps ctx.Err(). Records already buffered are not flushed.
+func (w *Writer) WriteContext(ctx context.Context, file *File) error {
+	w.ctx = ctx
+	defer func() { w.ctx = nil }()
+	return w.Write(file)
+}
+
+// progressStats reports the writer's totals as Stats
+func (w *Writer) progressStats() Stats {
+	return Stats{
+		LinesProcessed:     w.recordCount,
+		PaymentsProcessed:  w.paymentCount,
+		SchedulesProcessed: w.scheduleCount,
+		BytesProcessed:     w.bytesWritten,
+	}
+}
@@ -0,0 +1,164 @@
+package pamspr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderProgress(t *testing.T) {
+	data := writeTestFileData(t, 2500)
+
+	tests := []struct {
+		name     string
+		interval int
+		workers  int
+		calls    int // Periodic calls plus the final one
+	}{
+		{name: "Default interval", calls: 2 + 1},
+		{name: "Every 500 records", interval: 500, calls: 5 + 1},
+		{name: "Parallel validation", interval: 500, workers: 4, calls: 5 + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reports []Stats
+			config := DefaultConfig()
+			config.ProgressInterval = tt.interval
+			config.ValidationWorkers = tt.workers
+			config.Progress = func(stats Stats) {
+				reports = append(reports, stats)
+			}
+
+			reader := NewReaderWithConfig(strings.NewReader(data), config)
+			if err := reader.ProcessFile(nil, nil, nil); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+
+			if len(reports) != tt.calls {
+				t.Fatalf("Expected %d progress calls, got %d", tt.calls, len(reports))
+			}
+			for i := 1; i < len(reports); i++ {
+				if reports[i].LinesProcessed < reports[i-1].LinesProcessed || reports[i].BytesProcessed < reports[i-1].BytesProcessed {
+					t.Errorf("Progress went backwards: %+v then %+v", reports[i-1], reports[i])
+				}
+			}
+
+			final := reports[len(reports)-1]
+			if final != reader.GetStats() {
+				t.Errorf("Expected final progress %+v, got %+v", reader.GetStats(), final)
+			}
+			if final.BytesProcessed != int64(len(data)) || final.PaymentsProcessed != 2500 || final.SchedulesProcessed != 3 {
+				t.Errorf("Unexpected final progress: %+v", final)
+			}
+		})
+	}
+}
+
+func TestReaderContext(t *testing.T) {
+	data := writeTestFileData(t, 2500)
+
+	t.Run("Cancel during ProcessFile", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		payments := 0
+		reader := NewReader(strings.NewReader(data))
+		err := reader.ProcessFileContext(ctx, nil, func(Payment, int, int) bool {
+			payments++
+			if payments == 100 {
+				cancel()
+			}
+			return true
+		}, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if payments != 100 {
+			t.Errorf("Expected processing to stop after 100 payments, got %d", payments)
+		}
+	})
+
+	t.Run("Cancel during ProcessPaymentsOnly", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		payments := 0
+		config := DefaultConfig()
+		config.ValidationWorkers = 4
+		reader := NewReaderWithConfig(strings.NewReader(data), config)
+		err := reader.ProcessPaymentsOnlyContext(ctx, func(Payment, int, int) bool {
+			payments++
+			if payments == 1500 {
+				cancel()
+			}
+			return true
+		})
+		if !errors.Is(err, context.Canceled) || payments != 1500 {
+			t.Errorf("Expected context.Canceled after 1500 payments, got %v after %d", err, payments)
+		}
+	})
+
+	t.Run("Done before ReadAll", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		file, err := NewReader(strings.NewReader(data)).ReadAllContext(ctx)
+		if !errors.Is(err, context.Canceled) || file != nil {
+			t.Errorf("Expected context.Canceled and no file, got %v", err)
+		}
+	})
+
+	t.Run("Not canceled", func(t *testing.T) {
+		file, err := NewReader(strings.NewReader(data)).ReadAllContext(context.Background())
+		if err != nil {
+			t.Fatalf("ReadAllContext failed: %v", err)
+		}
+		if len(file.Schedules) != 3 {
+			t.Errorf("Expected 3 schedules, got %d", len(file.Schedules))
+		}
+	})
+}
+
+func TestWriterProgressAndContext(t *testing.T) {
+	file := generateTestFile(2500)
+
+	var reports []Stats
+	config := DefaultWriterConfig()
+	config.ProgressInterval = 1000
+	config.Progress = func(stats Stats) {
+		reports = append(reports, stats)
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriterWithConfig(&buf, config).WriteContext(context.Background(), file); err != nil {
+		t.Fatalf("WriteContext failed: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("Expected 3 progress calls, got %d", len(reports))
+	}
+	final := reports[len(reports)-1]
+	if final.BytesProcessed != int64(buf.Len()) || final.PaymentsProcessed != 2500 || final.LinesProcessed != 2508 {
+		t.Errorf("Unexpected final progress: %+v", final)
+	}
+
+	// Cancel from the progress callback
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config.Progress = func(stats Stats) {
+		if stats.LinesProcessed == 1000 {
+			cancel()
+		}
+	}
+	writer := NewWriterWithConfig(io.Discard, config)
+	err := writer.WriteContext(ctx, file)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if records, _, _, _ := writer.GetStats(); records != 1000 {
+		t.Errorf("Expected writing to stop after 1000 records, got %d", records)
+	}
+}
@@ -2,9 +2,11 @@ package pamspr
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
 )
 
 // PaymentCallback is called for each payment as it's parsed
@@ -33,6 +35,38 @@ type ReaderConfig struct {
 
 	// SkipInvalidRecords continues processing on invalid records (default: false)
 	SkipInvalidRecords bool
+
+	// RecoveryMode resynchronizes after damaged records instead of failing:
+	// bad payments are dropped with their associated records, bad schedule
+	// headers drop their schedule, and missing or unbalanced trailers are
+	// rebuilt. Every repair is listed in GetRepairReport (default: false)
+	RecoveryMode bool
+
+	// LineTerminator is the record delimiter of the input. The default accepts
+	// LF and CRLF and detects fixed 850-byte blocks, warning about each
+	LineTerminator LineTerminator
+
+	// PadShortRecords right-pads records shorter than RecordLength with spaces,
+	// as when trailing blanks were stripped in transfer, with a warning per record (default: false)
+	PadShortRecords bool
+
+	// ValidationWorkers runs record, payment and agency rule checks on this
+	// many goroutines while the calling goroutine reads. Issues are recorded in
+	// the same order as serial validation, but only once the read returns, and
+	// callbacks must not modify the payments they are given. 0 or 1 validates
+	// serially (default: 0)
+	ValidationWorkers int
+
+	// Progress is called with the running Stats every ProgressInterval records
+	// and once more when the read returns (optional)
+	Progress ProgressCallback
+
+	// ProgressInterval is the number of records between Progress calls (default: 1000)
+	ProgressInterval int
+
+	// Encoding is the input character set; EBCDIC input is decoded to ISO-8859-1
+	// and fields are checked against the hexadecimal character rule in that code page (default: ASCII)
+	Encoding Encoding
 }
 
 // DefaultConfig returns sensible defaults for the reader
@@ -50,12 +84,22 @@ func DefaultConfig() *ReaderConfig {
 // without loading the entire file into memory
 type Reader struct {
 	scanner     *bufio.Scanner
+	splitter    *recordSplitter
 	validator   *Validator
 	config      *ReaderConfig
 	lineNum     int
 	errors      []error
+	report      *ValidationReport
+	repairs     *RepairReport
+	warnings    []ReaderWarning
 	nextLine    *string
+	currentLine string
 	currentFile *FileHeader
+	lines       RecordLines         // Source lines of the records built by Read
+	recordLines bool                // Set by ReadAll, which keeps lines for a whole File
+	pipeline    *validationPipeline // Set while validating in parallel
+	ctx         context.Context     // Set by the Context variants
+	ctxErr      error               // Why reading stopped early
 
 	// Parsers
 	fileParser   *FileParser
@@ -64,7 +108,9 @@ type Reader struct {
 	commonParser *CommonParser
 
 	// Statistics
-	stats Stats
+	stats   Stats
+	balance FileBalanceInfo
+	errored atomic.Int64 // Stats.ErrorsEncountered, counted by the validation merger when parallel
 }
 
 // Stats tracks processing statistics
@@ -73,7 +119,7 @@ type Stats struct {
 	PaymentsProcessed  int64
 	SchedulesProcessed int64
 	ErrorsEncountered  int64
-	BytesProcessed     int64
+	BytesProcessed     int64 // Input bytes consumed, including line terminators
 }
 
 // NewReader creates a new PAM SPR reader with streaming capability
@@ -86,7 +132,10 @@ func NewReader(r io.Reader) *Reader {
 func NewReaderWithConfig(r io.Reader, config *ReaderConfig) *Reader {
 
 	validator := NewValidator()
-	scanner := bufio.NewScanner(r)
+	validator.Encoding = config.Encoding
+	scanner := bufio.NewScanner(newDecodingReader(r, config.Encoding))
+	splitter := &recordSplitter{terminator: config.LineTerminator}
+	scanner.Split(splitter.split)
 
 	// Set custom buffer size if specified
 	if config.BufferSize > 0 {
@@ -94,22 +143,33 @@ func NewReaderWithConfig(r io.Reader, config *ReaderConfig) *Reader {
 		scanner.Buffer(buffer, config.BufferSize)
 	}
 
-	return &Reader{
+	report := NewValidationReport()
+	report.MaxIssues = config.MaxErrors
+
+	reader := &Reader{
 		scanner:      scanner,
+		splitter:     splitter,
 		validator:    validator,
 		config:       config,
 		errors:       make([]error, 0),
+		report:       report,
+		repairs:      NewRepairReport(),
 		fileParser:   NewFileParser(validator),
 		achParser:    NewACHParser(validator),
 		checkParser:  NewCheckParser(validator),
 		commonParser: NewCommonParser(validator),
 		stats:        Stats{},
 	}
+	splitter.warn = reader.warn
+
+	return reader
 }
 
 // GetStats returns current processing statistics
 func (r *Reader) GetStats() Stats {
-	return r.stats
+	stats := r.stats
+	stats.ErrorsEncountered = r.errored.Load()
+	return stats
 }
 
 // GetErrors returns accumulated errors (if enabled)
@@ -117,11 +177,49 @@ func (r *Reader) GetErrors() []error {
 	return r.errors
 }
 
-// ProcessFile streams through the entire file calling callbacks for each component
+// GetValidationReport returns the issues collected by ProcessFile with their
+// schedule index, payment index, record code and line number (if CollectErrors is enabled)
+func (r *Reader) GetValidationReport() *ValidationReport {
+	return r.report
+}
+
+// GetWarnings returns the adjustments made to accept the input, such as
+// CRLF line ends, fixed-block records or padded short records
+func (r *Reader) GetWarnings() []ReaderWarning {
+	return r.warnings
+}
+
+// GetRepairReport returns the repairs made in RecoveryMode
+func (r *Reader) GetRepairReport() *RepairReport {
+	return r.repairs
+}
+
+// RecordLines returns the lines the records of the file built by Read came
+// from, for Validator.ValidateFileLines. Streaming methods such as ProcessFile
+// do not keep them.
+func (r *Reader) RecordLines() RecordLines {
+	return r.lines
+}
+
+// ProcessFile streams through the entire file calling callbacks for each component.
+// In RecoveryMode each schedule is recovered before its callbacks are called.
 func (r *Reader) ProcessFile(
 	scheduleCallback ScheduleCallback,
 	paymentCallback PaymentCallback,
 	recordCallback RecordCallback,
+) error {
+	return r.run(func() error {
+		if r.config.RecoveryMode {
+			return r.processRecovering(scheduleCallback, paymentCallback, recordCallback)
+		}
+		return r.processFile(scheduleCallback, paymentCallback, recordCallback)
+	})
+}
+
+func (r *Reader) processFile(
+	scheduleCallback ScheduleCallback,
+	paymentCallback PaymentCallback,
+	recordCallback RecordCallback,
 ) error {
 	// Read file header and notify callback
 	line, ok := r.scanLine()
@@ -144,11 +242,13 @@ func (r *Reader) ProcessFile(
 
 	if r.config.EnableValidation {
 		if err := r.validator.ValidateFileHeader(header); err != nil {
+			r.recordIssue(err, NoIndex, NoIndex, string(RecordTypeFileHeader))
 			return fmt.Errorf("validating file header: %w", err)
 		}
 	}
 
 	r.currentFile = header
+	r.balance = FileBalanceInfo{}
 
 	// Call record callback for header
 	if recordCallback != nil {
@@ -165,7 +265,7 @@ func (r *Reader) ProcessFile(
 		}
 
 		if len(line) < 2 {
-			r.addError(fmt.Errorf("line %d: line too short", r.lineNum))
+			r.recordIssue(fmt.Errorf("line too short"), scheduleIndex, NoIndex, "")
 			continue
 		}
 
@@ -183,7 +283,7 @@ func (r *Reader) ProcessFile(
 				if !r.config.SkipInvalidRecords {
 					return fmt.Errorf("parsing schedule header at line %d: %w", r.lineNum, err)
 				}
-				r.addError(err)
+				r.recordIssue(err, scheduleIndex, NoIndex, recordCode)
 				continue
 			}
 
@@ -207,22 +307,40 @@ func (r *Reader) ProcessFile(
 			if recordCallback != nil {
 				recordCallback("E ", r.lineNum, line)
 			}
+			if r.config.EnableValidation {
+				r.checkFileTrailer(line)
+			}
 			return nil // End of schedules
 
 		default:
 			if !r.config.SkipInvalidRecords {
 				return fmt.Errorf("unexpected record code '%s' at line %d", recordCode, r.lineNum)
 			}
-			r.addError(fmt.Errorf("line %d: unexpected record code '%s'", r.lineNum, recordCode))
+			r.recordIssue(fmt.Errorf("unexpected record code '%s'", recordCode), scheduleIndex, NoIndex, recordCode)
 		}
 	}
 
 	return nil
 }
 
+// run reads with the configured validation workers, then reports the final progress
+func (r *Reader) run(read func() error) error {
+	err := r.validateInParallel(read)
+	if r.config.Progress != nil {
+		r.config.Progress(r.GetStats())
+	}
+	return err
+}
+
 // ProcessPaymentsOnly streams through file calling callback only for payments
 // This is optimized for payment-only processing without building schedule objects
 func (r *Reader) ProcessPaymentsOnly(callback PaymentCallback) error {
+	return r.run(func() error {
+		return r.processPaymentsOnly(callback)
+	})
+}
+
+func (r *Reader) processPaymentsOnly(callback PaymentCallback) error {
 	// Read file header (but don't store full structure)
 	_, err := r.readFileHeader()
 	if err != nil {
@@ -260,7 +378,7 @@ func (r *Reader) ProcessPaymentsOnly(callback PaymentCallback) error {
 				if !r.config.SkipInvalidRecords {
 					return fmt.Errorf("parsing ACH payment at line %d: %w", r.lineNum, err)
 				}
-				r.addError(err)
+				r.recordIssue(err, scheduleIndex-1, NoIndex, recordCode)
 				continue
 			}
 
@@ -281,7 +399,7 @@ func (r *Reader) ProcessPaymentsOnly(callback PaymentCallback) error {
 				if !r.config.SkipInvalidRecords {
 					return fmt.Errorf("parsing check payment at line %d: %w", r.lineNum, err)
 				}
-				r.addError(err)
+				r.recordIssue(err, scheduleIndex-1, NoIndex, recordCode)
 				continue
 			}
 
@@ -378,37 +496,147 @@ func (r *Reader) ValidateFileStructureOnly() error {
 // Helper methods
 
 func (r *Reader) scanLine() (string, bool) {
+	if r.ctx != nil && r.ctxErr == nil {
+		r.ctxErr = r.ctx.Err()
+	}
+	if r.ctxErr != nil {
+		return "", false // Callers see the end of the input
+	}
+
 	if r.nextLine != nil {
 		line := *r.nextLine
 		r.nextLine = nil
+		r.currentLine = line
 		r.lineNum++
-		r.stats.BytesProcessed += int64(len(line))
+		r.stats.LinesProcessed++
 		return line, true
 	}
 
 	if r.scanner.Scan() {
 		line := r.scanner.Text()
+		if r.config.PadShortRecords && len(line) >= RecordCodeLength && len(line) < RecordLength {
+			r.warn(r.lineNum+1, fmt.Sprintf("record padded from %d to %d characters", len(line), RecordLength))
+			line += strings.Repeat(" ", RecordLength-len(line))
+		}
+		r.currentLine = line
 		r.lineNum++
 		r.stats.LinesProcessed++
-		r.stats.BytesProcessed += int64(len(line))
+		r.stats.BytesProcessed = r.splitter.consumed
+		if r.config.EnableValidation {
+			r.deferCheck(func() []error {
+				return r.validator.ValidateRecordFields(line)
+			}, NoIndex, NoIndex, firstN(line, RecordCodeLength))
+		}
+		if r.config.Progress != nil && r.splitter.records%int64(progressInterval(r.config.ProgressInterval)) == 0 {
+			r.config.Progress(r.GetStats())
+		}
 		return line, true
 	}
 
 	return "", false
 }
 
+// recordError ties err to the line most recently scanned
+func (r *Reader) recordError(err error) error {
+	return &RecordError{
+		Line:       r.lineNum,
+		RecordCode: firstN(r.currentLine, RecordCodeLength),
+		Record:     r.currentLine,
+		Err:        err,
+	}
+}
+
+// markLine records that record was parsed from the line most recently scanned
+func (r *Reader) markLine(record interface{}) {
+	if !r.recordLines {
+		return // Streaming callers hold one schedule at a time
+	}
+	if r.lines == nil {
+		r.lines = make(RecordLines)
+	}
+	r.lines[record] = r.lineNum
+}
+
 func (r *Reader) pushBackLine(line string) {
 	r.nextLine = &line
 	r.lineNum--
 	r.stats.LinesProcessed--
 }
 
+// recordIssue adds err to the collected errors and the validation report at the current line
+func (r *Reader) recordIssue(err error, scheduleIndex, paymentIndex int, recordCode string) {
+	if err == nil || !r.config.CollectErrors {
+		return
+	}
+	if r.pipeline != nil {
+		// Queue behind the checks still running for earlier records
+		r.pipeline.add(pendingIssue{
+			scheduleIndex: scheduleIndex,
+			paymentIndex:  paymentIndex,
+			recordCode:    recordCode,
+			lineNumber:    r.lineNum,
+			err:           err,
+		})
+		return
+	}
+	r.recordIssueAt(err, scheduleIndex, paymentIndex, recordCode, r.lineNum)
+}
+
+// recordIssueAt adds err to the collected errors and the validation report at lineNumber
+func (r *Reader) recordIssueAt(err error, scheduleIndex, paymentIndex int, recordCode string, lineNumber int) {
+	if err == nil || !r.config.CollectErrors {
+		return
+	}
+	r.addError(fmt.Errorf("line %d: %w", lineNumber, err))
+	r.report.AddError(err, scheduleIndex, paymentIndex, recordCode, lineNumber)
+}
+
+// checkScheduleTrailer compares a schedule trailer with the payments streamed before it
+func (r *Reader) checkScheduleTrailer(line string, schedule Schedule, scheduleIndex int, balance ScheduleBalanceInfo) {
+	trailer, err := r.commonParser.ParseScheduleTrailer(line)
+	if err != nil {
+		r.recordIssue(err, scheduleIndex, NoIndex, string(RecordTypeScheduleTrailer))
+		return
+	}
+
+	scheduleType := "ACH"
+	if _, ok := schedule.(*CheckSchedule); ok {
+		scheduleType = "Check"
+	}
+	for _, err := range r.validator.scheduleTrailerErrors(trailer, balance, scheduleType) {
+		r.recordIssue(err, scheduleIndex, NoIndex, string(RecordTypeScheduleTrailer))
+	}
+}
+
+// checkFileTrailer compares the file trailer with the totals streamed before it
+func (r *Reader) checkFileTrailer(line string) {
+	trailer, err := r.fileParser.ParseFileTrailer(line)
+	if err != nil {
+		r.recordIssue(err, NoIndex, NoIndex, string(RecordTypeFileTrailer))
+		return
+	}
+
+	balance := r.balance
+	balance.TotalRecords = int64(r.lineNum)
+	for _, err := range r.validator.fileTrailerErrors(trailer, balance) {
+		r.recordIssue(err, NoIndex, NoIndex, string(RecordTypeFileTrailer))
+	}
+}
+
+// warn records a warning, up to MaxErrors of them
+func (r *Reader) warn(lineNumber int, message string) {
+	if r.config.MaxErrors > 0 && len(r.warnings) >= r.config.MaxErrors {
+		return
+	}
+	r.warnings = append(r.warnings, ReaderWarning{LineNumber: lineNumber, Message: message})
+}
+
 func (r *Reader) addError(err error) {
 	if !r.config.CollectErrors {
 		return
 	}
 
-	r.stats.ErrorsEncountered++
+	r.errored.Add(1)
 
 	if r.config.MaxErrors > 0 && len(r.errors) >= r.config.MaxErrors {
 		return // Don't exceed max errors
@@ -454,14 +682,30 @@ func (r *Reader) parseScheduleHeader(line string) (Schedule, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &ACHSchedule{Header: header, BaseSchedule: BaseSchedule{}}, nil
+		return &ACHSchedule{
+			Header: header,
+			BaseSchedule: BaseSchedule{
+				ScheduleNumber: strings.TrimSpace(header.ScheduleNumber),
+				PaymentType:    strings.TrimSpace(header.PaymentTypeCode),
+				ALC:            strings.TrimSpace(header.AgencyLocationCode),
+				Payments:       make([]Payment, 0),
+			},
+		}, nil
 
 	case "11":
 		header, err := r.checkParser.ParseCheckScheduleHeader(line)
 		if err != nil {
 			return nil, err
 		}
-		return &CheckSchedule{Header: header, BaseSchedule: BaseSchedule{}}, nil
+		return &CheckSchedule{
+			Header: header,
+			BaseSchedule: BaseSchedule{
+				ScheduleNumber: strings.TrimSpace(header.ScheduleNumber),
+				PaymentType:    strings.TrimSpace(header.PaymentTypeCode),
+				ALC:            strings.TrimSpace(header.AgencyLocationCode),
+				Payments:       make([]Payment, 0),
+			},
+		}, nil
 
 	default:
 		return nil, fmt.Errorf("invalid schedule header record code: %s", recordCode)
@@ -475,6 +719,15 @@ func (r *Reader) processSchedulePayments(
 	recordCallback RecordCallback,
 ) error {
 	paymentIndex := 0
+	balance := ScheduleBalanceInfo{}
+	addenda, ctxAddenda := 0, 0 // addendum records seen for the current ACH payment
+	lastRTN := ""               // routing number of the previous ACH payment
+	var prenotes prenoteBalance
+
+	secCode := ""
+	if achSchedule, ok := schedule.(*ACHSchedule); ok && achSchedule.Header != nil {
+		secCode = achSchedule.Header.StandardEntryClassCode
+	}
 
 	for {
 		line, ok := r.scanLine()
@@ -500,11 +753,24 @@ func (r *Reader) processSchedulePayments(
 				if !r.config.SkipInvalidRecords {
 					return fmt.Errorf("parsing ACH payment at line %d: %w", r.lineNum, err)
 				}
-				r.addError(err)
+				r.recordIssue(err, scheduleIndex, paymentIndex, recordCode)
 				continue
 			}
+			payment.StandardEntryClassCode = secCode
+			addenda, ctxAddenda = 0, 0
+
+			if r.config.EnableValidation {
+				r.validatePayment(payment, scheduleIndex, paymentIndex, recordCode)
+				if paymentIndex > 0 {
+					r.recordIssue(checkRoutingNumberOrder(lastRTN, payment.RoutingNumber), scheduleIndex, paymentIndex, recordCode)
+				}
+			}
+			lastRTN = payment.RoutingNumber
+			prenotes.add(payment, paymentIndex)
 
 			r.stats.PaymentsProcessed++
+			balance.Payments++
+			balance.Amount += payment.Amount
 
 			if paymentCallback != nil && !paymentCallback(payment, scheduleIndex, paymentIndex) {
 				return nil // Stop processing
@@ -517,45 +783,90 @@ func (r *Reader) processSchedulePayments(
 				if !r.config.SkipInvalidRecords {
 					return fmt.Errorf("parsing check payment at line %d: %w", r.lineNum, err)
 				}
-				r.addError(err)
+				r.recordIssue(err, scheduleIndex, paymentIndex, recordCode)
 				continue
 			}
 
+			if r.config.EnableValidation {
+				r.validatePayment(payment, scheduleIndex, paymentIndex, recordCode)
+			}
+
 			r.stats.PaymentsProcessed++
+			balance.Payments++
+			balance.Amount += payment.Amount
 
 			if paymentCallback != nil && !paymentCallback(payment, scheduleIndex, paymentIndex) {
 				return nil // Stop processing
 			}
 			paymentIndex++
 
-		case "03", "04", "13", "G ", "DD": // Associated records - skip in streaming mode
+		case "03", "04": // ACH addenda are not parsed in streaming mode, only counted
+			if recordCode == "03" {
+				addenda++
+			} else {
+				ctxAddenda++
+			}
+			if r.config.EnableValidation && paymentIndex > 0 {
+				r.recordIssue(ValidateAddendaCount(secCode, addenda, ctxAddenda), scheduleIndex, paymentIndex-1, recordCode)
+			}
+
+		case "13", "G ", "DD": // Associated records - skip in streaming mode
 			continue
 
 		case "T ": // Schedule trailer
+			if r.config.EnableValidation {
+				r.checkScheduleTrailer(line, schedule, scheduleIndex, balance)
+				r.recordIssue(prenotes.err(), scheduleIndex, NoIndex, recordCode)
+			}
+			r.addScheduleBalance(balance)
 			return nil // End of schedule
 
 		case "01", "11": // Next schedule
 			r.pushBackLine(line)
+			r.addScheduleBalance(balance)
 			return nil
 
 		case "E ": // File trailer
 			r.pushBackLine(line)
+			r.addScheduleBalance(balance)
 			return nil
 
 		default:
 			if !r.config.SkipInvalidRecords {
 				return fmt.Errorf("unexpected record code '%s' at line %d", recordCode, r.lineNum)
 			}
-			r.addError(fmt.Errorf("line %d: unexpected record code '%s'", r.lineNum, recordCode))
+			r.recordIssue(fmt.Errorf("unexpected record code '%s'", recordCode), scheduleIndex, NoIndex, recordCode)
 		}
 	}
 }
 
+// addScheduleBalance adds a streamed schedule's totals to the running file totals
+func (r *Reader) addScheduleBalance(balance ScheduleBalanceInfo) {
+	r.balance.TotalPayments += balance.Payments
+	r.balance.TotalAmount += balance.Amount
+}
+
 // ReadAll reads a complete PAM SPR file in streaming fashion
 // This method provides compatibility with the traditional Reader.Read() API
 // while using streaming internally for memory efficiency
 func (r *Reader) ReadAll() (*File, error) {
-	return r.readAllLegacyCompatible()
+	r.recordLines = true
+	return r.readFile()
+}
+
+// readFile reads the whole file, repairing it in recovery mode
+func (r *Reader) readFile() (*File, error) {
+	var file *File
+	err := r.run(func() error {
+		var err error
+		if r.config.RecoveryMode {
+			file, err = r.readRecovering(nil)
+		} else {
+			file, err = r.readAllLegacyCompatible()
+		}
+		return err
+	})
+	return file, err
 }
 
 // ReadPayments reads only payments from the file in streaming fashion
@@ -587,6 +898,27 @@ func (r *Reader) readAllLegacyCompatible() (*File, error) {
 		Schedules: make([]Schedule, 0),
 	}
 
+	trailer, err := r.readSchedules(
+		func(header *FileHeader) error {
+			file.Header = header
+			return nil
+		},
+		func(schedule Schedule) error {
+			file.Schedules = append(file.Schedules, schedule)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	file.Trailer = trailer
+
+	return file, nil
+}
+
+// readSchedules reads the file one whole schedule at a time, passing the
+// header and then each schedule to the callbacks, and returns the file trailer
+func (r *Reader) readSchedules(onHeader func(*FileHeader) error, onSchedule func(Schedule) error) (*FileTrailer, error) {
 	// Read file header
 	line, ok := r.scanLine()
 	if !ok {
@@ -595,9 +927,12 @@ func (r *Reader) readAllLegacyCompatible() (*File, error) {
 
 	header, err := r.fileParser.ParseFileHeader(line)
 	if err != nil {
-		return nil, fmt.Errorf("line %d: %w", r.lineNum, err)
+		return nil, r.recordError(err)
+	}
+	r.markLine(header)
+	if err := onHeader(header); err != nil {
+		return nil, err
 	}
-	file.Header = header
 
 	// Read schedules
 	for {
@@ -610,28 +945,26 @@ func (r *Reader) readAllLegacyCompatible() (*File, error) {
 		if strings.HasPrefix(line, "E ") {
 			trailer, err := r.fileParser.ParseFileTrailer(line)
 			if err != nil {
-				return nil, fmt.Errorf("line %d: %w", r.lineNum, err)
+				return nil, r.recordError(err)
 			}
-			file.Trailer = trailer
-			break
+			r.markLine(trailer)
+			return trailer, nil
 		}
 
 		// Parse schedule
 		schedule, err := r.parseSchedule(line)
 		if err != nil {
-			return nil, fmt.Errorf("line %d: %w", r.lineNum, err)
+			return nil, r.recordError(err)
 		}
 		if schedule != nil {
-			file.Schedules = append(file.Schedules, schedule)
+			if err := onSchedule(schedule); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	// Check that we found a file trailer
-	if file.Trailer == nil {
-		return nil, fmt.Errorf("missing file trailer")
-	}
-
-	return file, nil
+	return nil, fmt.Errorf("missing file trailer")
 }
 
 // parseSchedule parses a schedule starting with the given line
@@ -665,6 +998,7 @@ func (r *Reader) parseACHSchedule(headerLine string) (*ACHSchedule, error) {
 			Payments:       make([]Payment, 0),
 		},
 	}
+	r.markLine(schedule)
 
 	// Read payments until schedule trailer
 	var currentPayment *ACHPayment
@@ -687,9 +1021,10 @@ func (r *Reader) parseACHSchedule(headerLine string) (*ACHSchedule, error) {
 				return nil, err
 			}
 			payment.StandardEntryClassCode = header.StandardEntryClassCode
+			r.markLine(payment)
 			currentPayment = payment
 
-		case "03", "04": // ACH Addendum
+		case "03": // ACH Addendum
 			if currentPayment == nil {
 				return nil, fmt.Errorf("addendum without payment")
 			}
@@ -699,6 +1034,16 @@ func (r *Reader) parseACHSchedule(headerLine string) (*ACHSchedule, error) {
 			}
 			currentPayment.Addenda = append(currentPayment.Addenda, addendum)
 
+		case "04": // CTX ACH Addendum
+			if currentPayment == nil {
+				return nil, fmt.Errorf("addendum without payment")
+			}
+			addendum, err := r.achParser.ParseCTXAddendum(line)
+			if err != nil {
+				return nil, err
+			}
+			currentPayment.CTXAddenda = append(currentPayment.CTXAddenda, addendum)
+
 		case "G ": // CARS TAS/BETC
 			if currentPayment == nil {
 				return nil, fmt.Errorf("CARS record without payment")
@@ -707,6 +1052,7 @@ func (r *Reader) parseACHSchedule(headerLine string) (*ACHSchedule, error) {
 			if err != nil {
 				return nil, err
 			}
+			r.markLine(cars)
 			currentPayment.CARSTASBETC = append(currentPayment.CARSTASBETC, cars)
 
 		case "DD": // DNP
@@ -727,6 +1073,7 @@ func (r *Reader) parseACHSchedule(headerLine string) (*ACHSchedule, error) {
 			if err != nil {
 				return nil, err
 			}
+			r.markLine(trailer)
 			schedule.Trailer = trailer
 			return schedule, nil
 
@@ -758,6 +1105,7 @@ func (r *Reader) parseCheckSchedule(headerLine string) (*CheckSchedule, error) {
 			Payments:       make([]Payment, 0),
 		},
 	}
+	r.markLine(schedule)
 
 	// Read payments until schedule trailer
 	var currentPayment *CheckPayment
@@ -779,6 +1127,7 @@ func (r *Reader) parseCheckSchedule(headerLine string) (*CheckSchedule, error) {
 			if err != nil {
 				return nil, err
 			}
+			r.markLine(payment)
 			currentPayment = payment
 
 		case "13": // Check Stub
@@ -799,6 +1148,7 @@ func (r *Reader) parseCheckSchedule(headerLine string) (*CheckSchedule, error) {
 			if err != nil {
 				return nil, err
 			}
+			r.markLine(cars)
 			currentPayment.CARSTASBETC = append(currentPayment.CARSTASBETC, cars)
 
 		case "DD": // DNP
@@ -819,6 +1169,7 @@ func (r *Reader) parseCheckSchedule(headerLine string) (*CheckSchedule, error) {
 			if err != nil {
 				return nil, err
 			}
+			r.markLine(trailer)
 			schedule.Trailer = trailer
 			return schedule, nil
 
@@ -0,0 +1,377 @@
+package pamspr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reconcilement is a typed view of the 100 character payment Reconcilement
+// field, following the agency layouts in Appendix B of the specification
+type Reconcilement interface {
+	// Marshal returns the padded 100 character field value
+	Marshal() (string, error)
+	// Unmarshal populates the struct from a Reconcilement field value
+	Unmarshal(recon string) error
+}
+
+// IRSEnclosureCode is the only non-blank Check Detail Enclosure Code for IRS refunds
+const IRSEnclosureCode = "enclose"
+
+// IRSReconcilement is the IRS Tax Refunds layout
+type IRSReconcilement struct {
+	TaxPeriodYear            string // 4 chars
+	TaxPeriodMonth           string // 2 chars
+	MFTCode                  string // 2 chars
+	ServiceCenterCode        string // 2 chars
+	DistrictOfficeCode       string // 2 chars
+	FileTINCode              string // 1 char
+	NameControl              string // 4 chars
+	PlanReportNumber         string // 3 chars
+	SplitRefundCode          string // 1 char
+	InjuredSpouseCode        string // 1 char
+	DebtBypassIndicator      string // 1 char
+	DocumentLocatorNumber    string // 14 chars
+	CheckDetailEnclosureCode string // 10 chars, "enclose" or blank
+}
+
+// IRSBondsReconcilement is the IRS Savings Bonds Orders layout
+type IRSBondsReconcilement struct {
+	TaxPeriodYear       string // 4 chars
+	TaxPeriodMonth      string // 2 chars
+	MFTCode             string // 2 chars
+	ServiceCenterCode   string // 2 chars
+	DistrictOfficeCode  string // 2 chars
+	FileTINCode         string // 1 char
+	NameControl         string // 4 chars
+	PlanReportNumber    string // 3 chars
+	SplitRefundCode     string // 1 char
+	InjuredSpouseCode   string // 1 char
+	DebtBypassIndicator string // 1 char
+	BondName1           string // 33 chars
+	BondREGCode         string // 1 char, "0", "1" or blank
+	BondName2           string // 33 chars
+}
+
+// VACheckReconcilement is the VA Education, C&P and Insurance check layout
+type VACheckReconcilement struct {
+	StationCode    string // 2 chars
+	FinCode        string // 2 chars
+	CourtesyCode   string // 1 char
+	AppropCode     string // 1 char
+	AddressSeqCode string // 1 char
+	PolicyPreCode  string // 2 chars
+	PolicyNum      string // 2 chars
+	PayPeriodInfo  string // 12 chars
+	NameCode       string // 3 chars
+}
+
+// VAACHReconcilement is the VA Education, C&P and Insurance ACH layout
+type VAACHReconcilement struct {
+	StationCode    string // 2 chars
+	FinCode        string // 2 chars
+	AppropCode     string // 1 char
+	AddressSeqCode string // 1 char
+	PolicyPreCode  string // 2 chars
+	PolicyNum      string // 2 chars
+	PayPeriodInfo  string // 12 chars
+}
+
+// SSAReconcilement is the SSA Daily and Monthly Benefit layout.
+// For SSA Allotments (SSA-A) the TIN Indicator Offset position is filler and must be blank.
+type SSAReconcilement struct {
+	ProgramServiceCenterCode string // 1 char
+	PaymentIDCode            string // 2 chars
+	TINIndicatorOffset       string // 1 char, not used by SSA-A
+}
+
+// RRBReconcilement is the RRB Daily and Monthly Benefit layout
+type RRBReconcilement struct {
+	BeneficiarySymbol string // 2 chars
+	PrefixCode        string // 1 char
+	PayeeCode         string // 1 char
+	ObjectCode        string // 1 char
+}
+
+// CCCReconcilement is the CCC layout
+type CCCReconcilement struct {
+	TOPPaymentAgencyID string // 2 alphabetic chars
+	TOPAgencySiteID    string // 2 alphabetic chars
+}
+
+// reconField ties a struct field to its width in the Appendix B layout
+type reconField struct {
+	name       string
+	length     int
+	alphabetic bool
+	value      *string
+}
+
+func (r *IRSReconcilement) fields() []reconField {
+	return append(irsCommonFields(&r.TaxPeriodYear, &r.TaxPeriodMonth, &r.MFTCode, &r.ServiceCenterCode,
+		&r.DistrictOfficeCode, &r.FileTINCode, &r.NameControl, &r.PlanReportNumber,
+		&r.SplitRefundCode, &r.InjuredSpouseCode, &r.DebtBypassIndicator),
+		reconField{name: "DocumentLocatorNumber", length: 14, value: &r.DocumentLocatorNumber},
+		reconField{name: "CheckDetailEnclosureCode", length: 10, value: &r.CheckDetailEnclosureCode},
+	)
+}
+
+// Marshal encodes the IRS Tax Refunds layout
+func (r *IRSReconcilement) Marshal() (string, error) {
+	if r.CheckDetailEnclosureCode != "" && r.CheckDetailEnclosureCode != IRSEnclosureCode {
+		return "", NewFieldFormatError("CheckDetailEnclosureCode", r.CheckDetailEnclosureCode,
+			fmt.Sprintf("be '%s' or blank", IRSEnclosureCode))
+	}
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the IRS Tax Refunds layout
+func (r *IRSReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+func (r *IRSBondsReconcilement) fields() []reconField {
+	return append(irsCommonFields(&r.TaxPeriodYear, &r.TaxPeriodMonth, &r.MFTCode, &r.ServiceCenterCode,
+		&r.DistrictOfficeCode, &r.FileTINCode, &r.NameControl, &r.PlanReportNumber,
+		&r.SplitRefundCode, &r.InjuredSpouseCode, &r.DebtBypassIndicator),
+		reconField{name: "BondName1", length: 33, value: &r.BondName1},
+		reconField{name: "BondREGCode", length: 1, value: &r.BondREGCode},
+		reconField{name: "BondName2", length: 33, value: &r.BondName2},
+	)
+}
+
+// Marshal encodes the IRS Savings Bonds Orders layout
+func (r *IRSBondsReconcilement) Marshal() (string, error) {
+	switch r.BondREGCode {
+	case "", "0", "1":
+	default:
+		return "", NewFieldFormatError("BondREGCode", r.BondREGCode, "be '0', '1' or blank")
+	}
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the IRS Savings Bonds Orders layout
+func (r *IRSBondsReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+func (r *VACheckReconcilement) fields() []reconField {
+	return []reconField{
+		{name: "StationCode", length: 2, value: &r.StationCode},
+		{name: "FinCode", length: 2, value: &r.FinCode},
+		{name: "CourtesyCode", length: 1, value: &r.CourtesyCode},
+		{name: "AppropCode", length: 1, value: &r.AppropCode},
+		{name: "AddressSeqCode", length: 1, value: &r.AddressSeqCode},
+		{name: "PolicyPreCode", length: 2, value: &r.PolicyPreCode},
+		{name: "PolicyNum", length: 2, value: &r.PolicyNum},
+		{name: "PayPeriodInfo", length: 12, value: &r.PayPeriodInfo},
+		{name: "NameCode", length: 3, value: &r.NameCode},
+	}
+}
+
+// Marshal encodes the VA check layout
+func (r *VACheckReconcilement) Marshal() (string, error) {
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the VA check layout
+func (r *VACheckReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+func (r *VAACHReconcilement) fields() []reconField {
+	return []reconField{
+		{name: "StationCode", length: 2, value: &r.StationCode},
+		{name: "FinCode", length: 2, value: &r.FinCode},
+		{name: "AppropCode", length: 1, value: &r.AppropCode},
+		{name: "AddressSeqCode", length: 1, value: &r.AddressSeqCode},
+		{name: "PolicyPreCode", length: 2, value: &r.PolicyPreCode},
+		{name: "PolicyNum", length: 2, value: &r.PolicyNum},
+		{name: "PayPeriodInfo", length: 12, value: &r.PayPeriodInfo},
+	}
+}
+
+// Marshal encodes the VA ACH layout
+func (r *VAACHReconcilement) Marshal() (string, error) {
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the VA ACH layout
+func (r *VAACHReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+func (r *SSAReconcilement) fields() []reconField {
+	return []reconField{
+		{name: "ProgramServiceCenterCode", length: 1, value: &r.ProgramServiceCenterCode},
+		{name: "PaymentIDCode", length: 2, value: &r.PaymentIDCode},
+		{name: "TINIndicatorOffset", length: 1, value: &r.TINIndicatorOffset},
+	}
+}
+
+// Marshal encodes the SSA layout
+func (r *SSAReconcilement) Marshal() (string, error) {
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the SSA layout
+func (r *SSAReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+func (r *RRBReconcilement) fields() []reconField {
+	return []reconField{
+		{name: "BeneficiarySymbol", length: 2, value: &r.BeneficiarySymbol},
+		{name: "PrefixCode", length: 1, value: &r.PrefixCode},
+		{name: "PayeeCode", length: 1, value: &r.PayeeCode},
+		{name: "ObjectCode", length: 1, value: &r.ObjectCode},
+	}
+}
+
+// Marshal encodes the RRB layout
+func (r *RRBReconcilement) Marshal() (string, error) {
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the RRB layout
+func (r *RRBReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+func (r *CCCReconcilement) fields() []reconField {
+	return []reconField{
+		{name: "TOPPaymentAgencyID", length: 2, alphabetic: true, value: &r.TOPPaymentAgencyID},
+		{name: "TOPAgencySiteID", length: 2, alphabetic: true, value: &r.TOPAgencySiteID},
+	}
+}
+
+// Marshal encodes the CCC layout
+func (r *CCCReconcilement) Marshal() (string, error) {
+	return marshalReconcilement(r.fields())
+}
+
+// Unmarshal decodes the CCC layout
+func (r *CCCReconcilement) Unmarshal(recon string) error {
+	return unmarshalReconcilement(recon, r.fields())
+}
+
+// NewAgencyReconcilement returns an empty reconcilement for a Custom Agency Rule ID
+// and payment type. IRS returns the Tax Refunds layout; use IRSBondsReconcilement
+// directly for savings bond orders.
+func NewAgencyReconcilement(ruleID string, paymentType PaymentType) (Reconcilement, error) {
+	switch ruleID {
+	case "IRS":
+		return &IRSReconcilement{}, nil
+	case "VA", "VACP":
+		if paymentType == PaymentTypeACH {
+			return &VAACHReconcilement{}, nil
+		}
+		return &VACheckReconcilement{}, nil
+	case "SSA", "SSA-Daily", "SSA-A":
+		return &SSAReconcilement{}, nil
+	case "RRB":
+		return &RRBReconcilement{}, nil
+	case "CCC":
+		return &CCCReconcilement{}, nil
+	}
+	return nil, fmt.Errorf("no reconcilement layout for agency rule ID '%s'", ruleID)
+}
+
+// EncodeReconcilement sets the payment's Reconcilement field from a typed layout
+func (p *ACHPayment) EncodeReconcilement(recon Reconcilement) error {
+	value, err := recon.Marshal()
+	if err != nil {
+		return err
+	}
+	p.Reconcilement = value
+	return nil
+}
+
+// DecodeReconcilement reads the payment's Reconcilement field into a typed layout
+func (p *ACHPayment) DecodeReconcilement(recon Reconcilement) error {
+	return recon.Unmarshal(p.Reconcilement)
+}
+
+// EncodeReconcilement sets the payment's Reconcilement field from a typed layout
+func (p *CheckPayment) EncodeReconcilement(recon Reconcilement) error {
+	value, err := recon.Marshal()
+	if err != nil {
+		return err
+	}
+	p.Reconcilement = value
+	return nil
+}
+
+// DecodeReconcilement reads the payment's Reconcilement field into a typed layout
+func (p *CheckPayment) DecodeReconcilement(recon Reconcilement) error {
+	return recon.Unmarshal(p.Reconcilement)
+}
+
+// Helper functions
+
+// irsCommonFields returns the leading fields shared by both IRS layouts
+func irsCommonFields(year, month, mft, serviceCenter, district, fileTIN, nameControl, planReport,
+	splitRefund, injuredSpouse, debtBypass *string) []reconField {
+	return []reconField{
+		{name: "TaxPeriodYear", length: 4, value: year},
+		{name: "TaxPeriodMonth", length: 2, value: month},
+		{name: "MFTCode", length: 2, value: mft},
+		{name: "ServiceCenterCode", length: 2, value: serviceCenter},
+		{name: "DistrictOfficeCode", length: 2, value: district},
+		{name: "FileTINCode", length: 1, value: fileTIN},
+		{name: "NameControl", length: 4, value: nameControl},
+		{name: "PlanReportNumber", length: 3, value: planReport},
+		{name: "SplitRefundCode", length: 1, value: splitRefund},
+		{name: "InjuredSpouseCode", length: 1, value: injuredSpouse},
+		{name: "DebtBypassIndicator", length: 1, value: debtBypass},
+	}
+}
+
+// marshalReconcilement left-justifies each field and pads the result to ReconcilementLength
+func marshalReconcilement(fields []reconField) (string, error) {
+	var sb strings.Builder
+	sb.Grow(ReconcilementLength)
+
+	for _, f := range fields {
+		value := *f.value
+		if len(value) > f.length {
+			return "", ValidationError{
+				Field:   "Reconcilement." + f.name,
+				Value:   value,
+				Rule:    "max_length",
+				Message: fmt.Sprintf("must be at most %d characters, got %d", f.length, len(value)),
+			}
+		}
+		if f.alphabetic && !isAlphabetic(value) {
+			return "", NewFieldFormatError("Reconcilement."+f.name, value, "contain only alphabetic characters")
+		}
+		sb.WriteString(value)
+		sb.WriteString(strings.Repeat(" ", f.length-len(value)))
+	}
+
+	sb.WriteString(strings.Repeat(" ", ReconcilementLength-sb.Len()))
+	return sb.String(), nil
+}
+
+// unmarshalReconcilement slices recon by the field widths; shorter values are treated as space padded
+func unmarshalReconcilement(recon string, fields []reconField) error {
+	if len(recon) > ReconcilementLength {
+		return NewFieldLengthError("Reconcilement", recon, ReconcilementLength, len(recon))
+	}
+	recon += strings.Repeat(" ", ReconcilementLength-len(recon))
+
+	pos := 0
+	for _, f := range fields {
+		*f.value = strings.TrimSpace(recon[pos : pos+f.length])
+		pos += f.length
+	}
+	return nil
+}
+
+func isAlphabetic(value string) bool {
+	for _, r := range value {
+		if !((r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package pamspr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReconcilementRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		recon    Reconcilement
+		empty    func() Reconcilement
+		expected string // leading, non-filler part of the marshaled field
+	}{
+		{
+			name: "IRS",
+			recon: &IRSReconcilement{
+				TaxPeriodYear: "2023", TaxPeriodMonth: "12", MFTCode: "30", ServiceCenterCode: "09",
+				DistrictOfficeCode: "12", FileTINCode: "R", NameControl: "ODR1", PlanReportNumber: "23",
+				SplitRefundCode: "1", InjuredSpouseCode: "0", DebtBypassIndicator: "1",
+				DocumentLocatorNumber: "12345678901234", CheckDetailEnclosureCode: IRSEnclosureCode,
+			},
+			empty:    func() Reconcilement { return &IRSReconcilement{} },
+			expected: "2023123009" + "12" + "R" + "ODR1" + "23 " + "101" + "12345678901234" + "enclose   ",
+		},
+		{
+			name: "IRS savings bonds",
+			recon: &IRSBondsReconcilement{
+				TaxPeriodYear: "2023", TaxPeriodMonth: "04", MFTCode: "30", BondName1: "JOHN Q PUBLIC",
+				BondREGCode: "1", BondName2: "JANE PUBLIC",
+			},
+			empty:    func() Reconcilement { return &IRSBondsReconcilement{} },
+			expected: "202304" + "30" + strings.Repeat(" ", 15) + "JOHN Q PUBLIC" + strings.Repeat(" ", 20) + "1" + "JANE PUBLIC",
+		},
+		{
+			name: "VA check",
+			recon: &VACheckReconcilement{
+				StationCode: "01", FinCode: "02", CourtesyCode: "A", AppropCode: "B", AddressSeqCode: "C",
+				PolicyPreCode: "DE", PolicyNum: "FG", PayPeriodInfo: "202301202312", NameCode: "XYZ",
+			},
+			empty:    func() Reconcilement { return &VACheckReconcilement{} },
+			expected: "0102ABCDEFG202301202312XYZ",
+		},
+		{
+			name: "VA ACH",
+			recon: &VAACHReconcilement{
+				StationCode: "01", FinCode: "02", AppropCode: "B", AddressSeqCode: "C",
+				PolicyPreCode: "DE", PolicyNum: "FG", PayPeriodInfo: "202301",
+			},
+			empty:    func() Reconcilement { return &VAACHReconcilement{} },
+			expected: "0102BCDEFG202301",
+		},
+		{
+			name:     "SSA",
+			recon:    &SSAReconcilement{ProgramServiceCenterCode: "1", PaymentIDCode: "AB", TINIndicatorOffset: "C"},
+			empty:    func() Reconcilement { return &SSAReconcilement{} },
+			expected: "1ABC",
+		},
+		{
+			name:     "RRB",
+			recon:    &RRBReconcilement{BeneficiarySymbol: "AB", PrefixCode: "C", PayeeCode: "D", ObjectCode: "E"},
+			empty:    func() Reconcilement { return &RRBReconcilement{} },
+			expected: "ABCDE",
+		},
+		{
+			name:     "CCC",
+			recon:    &CCCReconcilement{TOPPaymentAgencyID: "AG", TOPAgencySiteID: "KS"},
+			empty:    func() Reconcilement { return &CCCReconcilement{} },
+			expected: "AGKS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.recon.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if len(value) != ReconcilementLength {
+				t.Fatalf("Expected %d characters, got %d", ReconcilementLength, len(value))
+			}
+			if !strings.HasPrefix(value, tt.expected) {
+				t.Errorf("Unexpected layout:\n got %q\nwant prefix %q", value, tt.expected)
+			}
+			if strings.TrimRight(value[len(tt.expected):], " ") != "" {
+				t.Errorf("Expected filler after %q, got %q", tt.expected, value)
+			}
+
+			decoded := tt.empty()
+			if err := decoded.Unmarshal(value); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.recon) {
+				t.Errorf("Round trip mismatch:\n got %+v\nwant %+v", decoded, tt.recon)
+			}
+		})
+	}
+}
+
+func TestReconcilementMatchesParser(t *testing.T) {
+	parser := &AgencyReconcilementParser{}
+
+	va := &VACheckReconcilement{StationCode: "01", FinCode: "02", CourtesyCode: "A", PayPeriodInfo: "2023", NameCode: "XYZ"}
+	value, err := va.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	fields := parser.ParseVAReconcilement(value, false)
+	if fields["StationCode"] != "01" || fields["CourtesyCode"] != "A" || fields["PayPeriodInfo"] != "2023" || fields["NameCode"] != "XYZ" {
+		t.Errorf("Parser disagrees with VACheckReconcilement layout: %v", fields)
+	}
+
+	bonds := &IRSBondsReconcilement{NameControl: "ODR1", BondName1: "JOHN", BondREGCode: "0", BondName2: "JANE"}
+	value, err = bonds.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	fields = parser.ParseIRSReconcilement(value, "BONDS")
+	if fields["NameControl"] != "ODR1" || fields["BondName1"] != "JOHN" || fields["BondREGCode"] != "0" || fields["BondName2"] != "JANE" {
+		t.Errorf("Parser disagrees with IRSBondsReconcilement layout: %v", fields)
+	}
+}
+
+func TestReconcilementMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		recon Reconcilement
+		field string
+	}{
+		{"Field too long", &RRBReconcilement{BeneficiarySymbol: "ABC"}, "Reconcilement.BeneficiarySymbol"},
+		{"CCC not alphabetic", &CCCReconcilement{TOPPaymentAgencyID: "A1"}, "Reconcilement.TOPPaymentAgencyID"},
+		{"IRS enclosure code", &IRSReconcilement{CheckDetailEnclosureCode: "stub"}, "CheckDetailEnclosureCode"},
+		{"IRS bond REG code", &IRSBondsReconcilement{BondREGCode: "2"}, "BondREGCode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.recon.Marshal()
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			if ve, ok := err.(ValidationError); !ok || ve.Field != tt.field {
+				t.Errorf("Expected ValidationError on %s, got %v", tt.field, err)
+			}
+		})
+	}
+
+	if err := (&SSAReconcilement{}).Unmarshal(strings.Repeat("X", ReconcilementLength+1)); err == nil {
+		t.Error("Expected error for overlong reconcilement")
+	}
+}
+
+func TestPaymentReconcilement(t *testing.T) {
+	validator := NewValidator()
+
+	achPayment := &ACHPayment{}
+	if err := achPayment.EncodeReconcilement(&VAACHReconcilement{StationCode: "01", FinCode: "02"}); err != nil {
+		t.Fatalf("EncodeReconcilement failed: %v", err)
+	}
+	if err := validator.ValidateAgencySpecific(achPayment, "VA"); err != nil {
+		t.Errorf("Encoded VA reconcilement failed validation: %v", err)
+	}
+
+	var decoded VAACHReconcilement
+	if err := achPayment.DecodeReconcilement(&decoded); err != nil {
+		t.Fatalf("DecodeReconcilement failed: %v", err)
+	}
+	if decoded.StationCode != "01" || decoded.FinCode != "02" {
+		t.Errorf("Unexpected decoded reconcilement: %+v", decoded)
+	}
+
+	checkPayment := &CheckPayment{}
+	if err := checkPayment.EncodeReconcilement(&RRBReconcilement{BeneficiarySymbol: "AB", PrefixCode: "C", PayeeCode: "D", ObjectCode: "E"}); err != nil {
+		t.Fatalf("EncodeReconcilement failed: %v", err)
+	}
+	if err := validator.ValidateAgencySpecific(checkPayment, "RRB"); err != nil {
+		t.Errorf("Encoded RRB reconcilement failed validation: %v", err)
+	}
+
+	// A failed encode leaves the field untouched
+	if err := checkPayment.EncodeReconcilement(&RRBReconcilement{BeneficiarySymbol: "TOO LONG"}); err == nil {
+		t.Error("Expected encode error")
+	}
+	if !strings.HasPrefix(checkPayment.Reconcilement, "ABCDE") {
+		t.Errorf("Reconcilement changed after failed encode: %q", checkPayment.Reconcilement)
+	}
+}
+
+func TestNewAgencyReconcilement(t *testing.T) {
+	tests := []struct {
+		ruleID      string
+		paymentType PaymentType
+		expected    Reconcilement
+	}{
+		{"IRS", PaymentTypeCheck, &IRSReconcilement{}},
+		{"VA", PaymentTypeACH, &VAACHReconcilement{}},
+		{"VACP", PaymentTypeCheck, &VACheckReconcilement{}},
+		{"SSA-A", PaymentTypeACH, &SSAReconcilement{}},
+		{"RRB", PaymentTypeCheck, &RRBReconcilement{}},
+		{"CCC", PaymentTypeACH, &CCCReconcilement{}},
+	}
+
+	for _, tt := range tests {
+		recon, err := NewAgencyReconcilement(tt.ruleID, tt.paymentType)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.ruleID, err)
+			continue
+		}
+		if reflect.TypeOf(recon) != reflect.TypeOf(tt.expected) {
+			t.Errorf("%s: expected %T, got %T", tt.ruleID, tt.expected, recon)
+		}
+	}
+
+	if _, err := NewAgencyReconcilement("DOD", PaymentTypeACH); err == nil {
+		t.Error("Expected error for unknown rule ID")
+	}
+}
@@ -0,0 +1,441 @@
+package pamspr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RepairAction identifies how the reader recovered from a damaged record
+type RepairAction string
+
+const (
+	RepairSkippedRecord              RepairAction = "skipped_record"               // Unreadable, unknown or orphaned record dropped
+	RepairSkippedPayment             RepairAction = "skipped_payment"              // Payment dropped with its associated records
+	RepairSkippedSchedule            RepairAction = "skipped_schedule"             // Schedule with an unreadable header dropped
+	RepairSynthesizedScheduleTrailer RepairAction = "synthesized_schedule_trailer" // Schedule trailer rebuilt from the payments kept
+	RepairSynthesizedFileTrailer     RepairAction = "synthesized_file_trailer"     // File trailer rebuilt from the schedules kept
+)
+
+// Repair records one change made by the reader in recovery mode
+type Repair struct {
+	LineNumber    int          `json:"lineNumber,omitempty"` // Line the repair was made at, 0 at end of file
+	RecordCode    string       `json:"recordCode,omitempty"`
+	ScheduleIndex int          `json:"scheduleIndex"` // Index in the recovered file, NoIndex if none
+	Action        RepairAction `json:"action"`
+	Records       int          `json:"records,omitempty"` // Number of records dropped
+	Message       string       `json:"message"`
+}
+
+// String formats the repair as a single line of text
+func (r Repair) String() string {
+	var sb strings.Builder
+	if r.LineNumber > 0 {
+		fmt.Fprintf(&sb, "line %d: ", r.LineNumber)
+	}
+	if r.RecordCode != "" {
+		fmt.Fprintf(&sb, "record %q ", r.RecordCode)
+	}
+	if r.ScheduleIndex != NoIndex {
+		fmt.Fprintf(&sb, "schedule[%d] ", r.ScheduleIndex)
+	}
+	fmt.Fprintf(&sb, "%s: %s", r.Action, r.Message)
+	return sb.String()
+}
+
+// RepairReport lists every repair made while recovering a file
+type RepairReport struct {
+	Repairs []Repair `json:"repairs"`
+}
+
+// NewRepairReport creates an empty repair report
+func NewRepairReport() *RepairReport {
+	return &RepairReport{
+		Repairs: make([]Repair, 0),
+	}
+}
+
+// HasRepairs reports whether the file needed any repair
+func (r *RepairReport) HasRepairs() bool {
+	return len(r.Repairs) > 0
+}
+
+// RecordsDropped returns the total number of records dropped from the file
+func (r *RepairReport) RecordsDropped() int {
+	total := 0
+	for _, repair := range r.Repairs {
+		total += repair.Records
+	}
+	return total
+}
+
+// recovery resynchronizes the reader after damaged records. A record is
+// damaged when it has the wrong length, an unknown record code or does not
+// parse. The rules are:
+//   - a damaged payment, or one of the wrong type for its schedule, is dropped
+//     with its associated records, resuming at the next payment, schedule
+//     trailer, schedule header or file trailer
+//   - a damaged or orphaned associated record is dropped on its own
+//   - a damaged schedule header drops the schedule through its trailer
+//   - a schedule trailer that is damaged, missing, or follows dropped payments
+//     is rebuilt from the payments kept
+//   - a file trailer that is damaged, missing, or follows dropped records is
+//     rebuilt from the schedules kept, and records after it are dropped
+type recovery struct {
+	r              *Reader
+	recordCallback RecordCallback
+	balance        FileBalanceInfo
+	dropped        int // Records dropped anywhere in the file
+	schedules      int // Schedules recovered so far
+}
+
+// scan reads the next line, reporting it to the record callback once
+func (rc *recovery) scan() (string, bool) {
+	pushedBack := rc.r.nextLine != nil
+	line, ok := rc.r.scanLine()
+	if ok && !pushedBack && rc.recordCallback != nil {
+		rc.recordCallback(firstN(line, RecordCodeLength), rc.r.lineNum, line)
+	}
+	return line, ok
+}
+
+func (rc *recovery) repair(lineNumber int, action RepairAction, recordCode string, scheduleIndex, records int, format string, args ...interface{}) {
+	rc.dropped += records
+	rc.r.repairs.Repairs = append(rc.r.repairs.Repairs, Repair{
+		LineNumber:    lineNumber,
+		RecordCode:    recordCode,
+		ScheduleIndex: scheduleIndex,
+		Action:        action,
+		Records:       records,
+		Message:       fmt.Sprintf(format, args...),
+	})
+}
+
+// run reads the file, passing each recovered schedule to emit. It returns the
+// file header and the original or rebuilt file trailer.
+func (rc *recovery) run(emit func(Schedule, int) bool) (*FileHeader, *FileTrailer, error) {
+	line, ok := rc.scan()
+	if !ok {
+		return nil, nil, fmt.Errorf("missing file header")
+	}
+	header, err := rc.r.fileParser.ParseFileHeader(line)
+	if err == nil && !strings.HasPrefix(line, "H ") {
+		err = fmt.Errorf("expected file header, got record code '%s'", firstN(line, RecordCodeLength))
+	}
+	if err != nil {
+		// Without a header there is nothing to attach schedules to
+		return nil, nil, rc.r.recordError(err)
+	}
+	rc.r.markLine(header)
+
+	for {
+		line, ok := rc.scan()
+		if !ok {
+			return header, rc.fileTrailer("file trailer missing"), nil
+		}
+
+		recordCode := firstN(line, RecordCodeLength)
+		switch recordCode {
+		case "01", "11":
+			schedule, err := rc.r.parseScheduleHeader(line)
+			if err != nil {
+				lineNumber := rc.r.lineNum
+				records := rc.skipSchedule()
+				rc.repair(lineNumber, RepairSkippedSchedule, recordCode, NoIndex, records,
+					"schedule header unreadable (%v), dropped %d record(s)", err, records)
+				continue
+			}
+			rc.r.markLine(schedule)
+
+			schedule = rc.schedulePayments(schedule, rc.schedules)
+			rc.balance.TotalRecords += rc.r.validator.scheduleBalance(schedule).Records
+			rc.balance.TotalPayments += int64(len(schedule.GetPayments()))
+			for _, payment := range schedule.GetPayments() {
+				rc.balance.TotalAmount += payment.GetAmount()
+			}
+			rc.schedules++
+			if !emit(schedule, rc.schedules-1) {
+				return header, nil, nil
+			}
+
+		case "E ":
+			trailer, err := rc.r.fileParser.ParseFileTrailer(line)
+			switch {
+			case err != nil:
+				trailer = rc.fileTrailer(fmt.Sprintf("file trailer unreadable (%v)", err))
+			case rc.dropped > 0:
+				trailer = rc.fileTrailer(fmt.Sprintf("%d record(s) dropped", rc.dropped))
+			default:
+				rc.r.markLine(trailer)
+			}
+
+			lineNumber := rc.r.lineNum + 1
+			if extra := rc.skipRest(); extra > 0 {
+				rc.repair(lineNumber, RepairSkippedRecord, "", NoIndex, extra, "dropped %d record(s) after the file trailer", extra)
+			}
+			return header, trailer, nil
+
+		default:
+			rc.repair(rc.r.lineNum, RepairSkippedRecord, recordCode, NoIndex, 1, "record outside a schedule dropped")
+		}
+	}
+}
+
+// schedulePayments reads the payments of schedule through its trailer
+func (rc *recovery) schedulePayments(schedule Schedule, index int) Schedule {
+	var achPayment *ACHPayment
+	var checkPayment *CheckPayment
+	achSchedule, isACH := schedule.(*ACHSchedule)
+	checkSchedule, _ := schedule.(*CheckSchedule)
+	skipped := 0
+
+	for {
+		line, ok := rc.scan()
+		if !ok {
+			rc.scheduleTrailer(schedule, index, "schedule trailer missing at end of file")
+			return schedule
+		}
+
+		recordCode := firstN(line, RecordCodeLength)
+		switch recordCode {
+		case "02", "12":
+			achPayment, checkPayment = nil, nil
+			var err error
+			switch {
+			case recordCode == "02" && isACH:
+				if achPayment, err = rc.r.achParser.ParseACHPayment(line); err == nil {
+					achPayment.StandardEntryClassCode = achSchedule.Header.StandardEntryClassCode
+					rc.r.markLine(achPayment)
+					achSchedule.Payments = append(achSchedule.Payments, achPayment)
+				}
+			case recordCode == "12" && !isACH:
+				if checkPayment, err = rc.r.checkParser.ParseCheckPayment(line); err == nil {
+					rc.r.markLine(checkPayment)
+					checkSchedule.Payments = append(checkSchedule.Payments, checkPayment)
+				}
+			default:
+				err = fmt.Errorf("payment record '%s' in %s schedule", recordCode, paymentKind(isACH))
+			}
+
+			if err != nil {
+				achPayment, checkPayment = nil, nil
+				lineNumber := rc.r.lineNum
+				records := 1 + rc.skipAssociated()
+				skipped++
+				rc.repair(lineNumber, RepairSkippedPayment, recordCode, index, records,
+					"payment unreadable (%v), dropped %d record(s)", err, records)
+			}
+
+		case "03", "04", "13", "G ", "DD":
+			if err := rc.attach(line, recordCode, achPayment, checkPayment); err != nil {
+				rc.repair(rc.r.lineNum, RepairSkippedRecord, recordCode, index, 1, "%v, record dropped", err)
+			}
+
+		case "T ":
+			trailer, err := rc.r.commonParser.ParseScheduleTrailer(line)
+			switch {
+			case err != nil:
+				rc.scheduleTrailer(schedule, index, fmt.Sprintf("schedule trailer unreadable (%v)", err))
+			case skipped > 0:
+				rc.scheduleTrailer(schedule, index, fmt.Sprintf("%d payment(s) dropped", skipped))
+			default:
+				rc.r.markLine(trailer)
+				schedule.SetTrailer(trailer)
+			}
+			return schedule
+
+		case "01", "11", "E ":
+			rc.r.pushBackLine(line)
+			rc.scheduleTrailer(schedule, index, "schedule trailer missing")
+			return schedule
+
+		default:
+			rc.repair(rc.r.lineNum, RepairSkippedRecord, recordCode, index, 1, "unknown record code, record dropped")
+		}
+	}
+}
+
+// attach parses an associated record onto the current payment
+func (rc *recovery) attach(line, recordCode string, ach *ACHPayment, check *CheckPayment) error {
+	if ach == nil && check == nil {
+		return fmt.Errorf("no payment to attach to")
+	}
+
+	switch {
+	case recordCode == "03" && ach != nil:
+		addendum, err := rc.r.achParser.ParseACHAddendum(line)
+		if err != nil {
+			return err
+		}
+		ach.Addenda = append(ach.Addenda, addendum)
+	case recordCode == "04" && ach != nil:
+		addendum, err := rc.r.achParser.ParseCTXAddendum(line)
+		if err != nil {
+			return err
+		}
+		ach.CTXAddenda = append(ach.CTXAddenda, addendum)
+	case recordCode == "13" && check != nil:
+		stub, err := rc.r.checkParser.ParseCheckStub(line)
+		if err != nil {
+			return err
+		}
+		check.Stub = stub
+	case recordCode == "G ":
+		cars, err := rc.r.commonParser.ParseCARSTASBETC(line)
+		if err != nil {
+			return err
+		}
+		rc.r.markLine(cars)
+		if ach != nil {
+			ach.CARSTASBETC = append(ach.CARSTASBETC, cars)
+		} else {
+			check.CARSTASBETC = append(check.CARSTASBETC, cars)
+		}
+	case recordCode == "DD":
+		dnp, err := rc.r.commonParser.ParseDNP(line)
+		if err != nil {
+			return err
+		}
+		if ach != nil {
+			ach.DNP = dnp
+		} else {
+			check.DNP = dnp
+		}
+	default:
+		return fmt.Errorf("record '%s' does not belong to a %s payment", recordCode, paymentKind(ach != nil))
+	}
+	return nil
+}
+
+func paymentKind(isACH bool) string {
+	if isACH {
+		return "ACH"
+	}
+	return "check"
+}
+
+// skipAssociated drops records up to the next payment, trailer or schedule header
+func (rc *recovery) skipAssociated() int {
+	return rc.skipUntil(func(recordCode string) bool {
+		switch recordCode {
+		case "02", "12", "T ", "01", "11", "E ":
+			return true
+		}
+		return false
+	})
+}
+
+// skipSchedule drops records through the schedule trailer, or up to the next
+// schedule header or file trailer when the trailer is missing
+func (rc *recovery) skipSchedule() int {
+	records := 1 + rc.skipUntil(func(recordCode string) bool {
+		switch recordCode {
+		case "T ", "01", "11", "E ":
+			return true
+		}
+		return false
+	})
+
+	if line, ok := rc.scan(); ok {
+		if strings.HasPrefix(line, "T ") {
+			return records + 1
+		}
+		rc.r.pushBackLine(line)
+	}
+	return records
+}
+
+// skipUntil drops records until stop matches a record code, leaving that record to be read next
+func (rc *recovery) skipUntil(stop func(recordCode string) bool) int {
+	skipped := 0
+	for {
+		line, ok := rc.scan()
+		if !ok {
+			return skipped
+		}
+		if stop(firstN(line, RecordCodeLength)) {
+			rc.r.pushBackLine(line)
+			return skipped
+		}
+		skipped++
+	}
+}
+
+// skipRest drops everything after the file trailer
+func (rc *recovery) skipRest() int {
+	skipped := 0
+	for {
+		if _, ok := rc.scan(); !ok {
+			return skipped
+		}
+		skipped++
+	}
+}
+
+// scheduleTrailer rebuilds a schedule trailer from the payments kept
+func (rc *recovery) scheduleTrailer(schedule Schedule, index int, reason string) {
+	balance := rc.r.validator.scheduleBalance(schedule)
+	schedule.SetTrailer(&ScheduleTrailer{
+		RecordCode:     string(RecordTypeScheduleTrailer),
+		ScheduleCount:  balance.Payments,
+		ScheduleAmount: balance.Amount,
+	})
+	rc.repair(rc.r.lineNum, RepairSynthesizedScheduleTrailer, string(RecordTypeScheduleTrailer), index, 0,
+		"%s, trailer rebuilt for %d payment(s) totaling %d cents", reason, balance.Payments, balance.Amount)
+}
+
+// fileTrailer rebuilds the file trailer from the schedules kept
+func (rc *recovery) fileTrailer(reason string) *FileTrailer {
+	trailer := &FileTrailer{
+		RecordCode:          string(RecordTypeFileTrailer),
+		TotalCountRecords:   rc.balance.TotalRecords + 2, // File header and trailer
+		TotalCountPayments:  rc.balance.TotalPayments,
+		TotalAmountPayments: rc.balance.TotalAmount,
+	}
+	rc.repair(rc.r.lineNum, RepairSynthesizedFileTrailer, string(RecordTypeFileTrailer), NoIndex, 0,
+		"%s, trailer rebuilt for %d record(s)", reason, trailer.TotalCountRecords)
+	return trailer
+}
+
+// readRecovering reads the whole file in recovery mode
+func (r *Reader) readRecovering(recordCallback RecordCallback) (*File, error) {
+	file := &File{
+		Schedules: make([]Schedule, 0),
+	}
+
+	rc := &recovery{r: r, recordCallback: recordCallback}
+	header, trailer, err := rc.run(func(schedule Schedule, _ int) bool {
+		file.Schedules = append(file.Schedules, schedule)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	file.Header = header
+	file.Trailer = trailer
+	return file, nil
+}
+
+// processRecovering streams the file in recovery mode, one schedule at a time
+func (r *Reader) processRecovering(
+	scheduleCallback ScheduleCallback,
+	paymentCallback PaymentCallback,
+	recordCallback RecordCallback,
+) error {
+	rc := &recovery{r: r, recordCallback: recordCallback}
+	header, _, err := rc.run(func(schedule Schedule, index int) bool {
+		r.stats.SchedulesProcessed++
+		if scheduleCallback != nil && !scheduleCallback(schedule, index) {
+			return false
+		}
+		for j, payment := range schedule.GetPayments() {
+			r.stats.PaymentsProcessed++
+			if paymentCallback != nil && !paymentCallback(payment, index, j) {
+				return false
+			}
+		}
+		return true
+	})
+	r.currentFile = header
+	return err
+}
@@ -0,0 +1,222 @@
+package pamspr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReaderRecoveryMode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestMixedFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// H, 01, 02, 02, T, 11, 12, 13, T, E
+	original := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	record := func(code string) string {
+		return code + strings.Repeat(" ", RecordLength-len(code))
+	}
+
+	tests := []struct {
+		name      string
+		corrupt   func(lines []string) []string
+		schedules int
+		payments  int
+		amount    int64
+		actions   []RepairAction
+		dropped   int
+		line      int // Line of the first repair, if checked
+	}{
+		{
+			name:      "Clean file",
+			corrupt:   func(lines []string) []string { return lines },
+			schedules: 2, payments: 3, amount: 450000,
+		},
+		{
+			name: "Short ACH payment",
+			corrupt: func(lines []string) []string {
+				lines[2] = lines[2][:400]
+				return lines
+			},
+			schedules: 2, payments: 2, amount: 350000,
+			actions: []RepairAction{RepairSkippedPayment, RepairSynthesizedScheduleTrailer, RepairSynthesizedFileTrailer},
+			dropped: 1,
+		},
+		{
+			name: "Check payment dropped with its stub",
+			corrupt: func(lines []string) []string {
+				lines[6] = lines[6] + "EXTRA"
+				return lines
+			},
+			schedules: 2, payments: 2, amount: 300000,
+			actions: []RepairAction{RepairSkippedPayment, RepairSynthesizedScheduleTrailer, RepairSynthesizedFileTrailer},
+			dropped: 2,
+			line:    7,
+		},
+		{
+			name: "Orphaned addendum",
+			corrupt: func(lines []string) []string {
+				return append(lines[:2], append([]string{record("03")}, lines[2:]...)...)
+			},
+			schedules: 2, payments: 3, amount: 450000,
+			actions: []RepairAction{RepairSkippedRecord, RepairSynthesizedFileTrailer},
+			dropped: 1,
+		},
+		{
+			name: "Check payment in ACH schedule",
+			corrupt: func(lines []string) []string {
+				return append(lines[:4], append([]string{lines[6]}, lines[4:]...)...)
+			},
+			schedules: 2, payments: 3, amount: 450000,
+			actions: []RepairAction{RepairSkippedPayment, RepairSynthesizedScheduleTrailer, RepairSynthesizedFileTrailer},
+			dropped: 1,
+		},
+		{
+			name: "Unknown record code",
+			corrupt: func(lines []string) []string {
+				return append(lines[:3], append([]string{record("ZZ")}, lines[3:]...)...)
+			},
+			schedules: 2, payments: 3, amount: 450000,
+			actions: []RepairAction{RepairSkippedRecord, RepairSynthesizedFileTrailer},
+			dropped: 1,
+		},
+		{
+			name: "Missing schedule trailer",
+			corrupt: func(lines []string) []string {
+				return append(lines[:4], lines[5:]...)
+			},
+			schedules: 2, payments: 3, amount: 450000,
+			actions: []RepairAction{RepairSynthesizedScheduleTrailer},
+		},
+		{
+			name: "Damaged schedule header",
+			corrupt: func(lines []string) []string {
+				lines[5] = lines[5][:100]
+				return lines
+			},
+			schedules: 1, payments: 2, amount: 300000,
+			actions: []RepairAction{RepairSkippedSchedule, RepairSynthesizedFileTrailer},
+			dropped: 4,
+			line:    6,
+		},
+		{
+			name: "Missing file trailer",
+			corrupt: func(lines []string) []string {
+				return lines[:9]
+			},
+			schedules: 2, payments: 3, amount: 450000,
+			actions: []RepairAction{RepairSynthesizedFileTrailer},
+		},
+		{
+			name: "Records after file trailer",
+			corrupt: func(lines []string) []string {
+				return append(lines, "garbage")
+			},
+			schedules: 2, payments: 3, amount: 450000,
+			actions: []RepairAction{RepairSkippedRecord},
+			dropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := tt.corrupt(append([]string(nil), original...))
+			data := strings.Join(lines, "\n") + "\n"
+
+			config := DefaultConfig()
+			config.RecoveryMode = true
+			reader := NewReaderWithConfig(strings.NewReader(data), config)
+			file, err := reader.Read()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+
+			if len(file.Schedules) != tt.schedules {
+				t.Errorf("Expected %d schedules, got %d", tt.schedules, len(file.Schedules))
+			}
+			if file.Trailer.TotalCountPayments != int64(tt.payments) || file.Trailer.TotalAmountPayments != tt.amount {
+				t.Errorf("Expected %d payments totaling %d, got %+v", tt.payments, tt.amount, file.Trailer)
+			}
+
+			// The salvaged file balances
+			if err := NewValidator().ValidateBalancing(file); err != nil {
+				t.Errorf("Recovered file does not balance: %v", err)
+			}
+
+			repairs := reader.GetRepairReport()
+			if len(repairs.Repairs) != len(tt.actions) {
+				t.Fatalf("Expected repairs %v, got %v", tt.actions, repairs.Repairs)
+			}
+			for i, action := range tt.actions {
+				if repairs.Repairs[i].Action != action {
+					t.Errorf("Repair %d: expected %s, got %s", i, action, repairs.Repairs[i])
+				}
+			}
+			if tt.line > 0 && repairs.Repairs[0].LineNumber != tt.line {
+				t.Errorf("Expected first repair at line %d, got %s", tt.line, repairs.Repairs[0])
+			}
+			if repairs.RecordsDropped() != tt.dropped {
+				t.Errorf("Expected %d records dropped, got %d", tt.dropped, repairs.RecordsDropped())
+			}
+		})
+	}
+
+	t.Run("Damaged file header", func(t *testing.T) {
+		lines := append([]string(nil), original...)
+		lines[0] = lines[0][:10]
+
+		config := DefaultConfig()
+		config.RecoveryMode = true
+		_, err := NewReaderWithConfig(strings.NewReader(strings.Join(lines, "\n")), config).Read()
+		if err == nil {
+			t.Error("Expected an error for an unreadable file header")
+		}
+	})
+}
+
+func TestReaderRecoveryProcessFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestMixedFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	lines[3] = lines[3][:200]
+	lines = append(lines[:8], lines[9:]...) // Drop the check schedule trailer
+
+	config := DefaultConfig()
+	config.RecoveryMode = true
+	reader := NewReaderWithConfig(strings.NewReader(strings.Join(lines, "\n")), config)
+
+	var schedules, payments, records int
+	err := reader.ProcessFile(
+		func(schedule Schedule, scheduleIndex int) bool {
+			schedules++
+			if schedule.GetTrailer() == nil {
+				t.Errorf("Schedule %d has no trailer", scheduleIndex)
+			}
+			return true
+		},
+		func(payment Payment, scheduleIndex, paymentIndex int) bool {
+			payments++
+			return true
+		},
+		func(recordType string, lineNumber int, line string) {
+			records++
+		},
+	)
+	if err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	if schedules != 2 || payments != 2 {
+		t.Errorf("Expected 2 schedules and 2 payments, got %d and %d", schedules, payments)
+	}
+	if records != len(lines) {
+		t.Errorf("Expected each of %d records reported once, got %d", len(lines), records)
+	}
+
+	repairs := reader.GetRepairReport().Repairs
+	if len(repairs) != 4 || repairs[0].Action != RepairSkippedPayment || repairs[0].LineNumber != 4 {
+		t.Errorf("Unexpected repairs: %v", repairs)
+	}
+}
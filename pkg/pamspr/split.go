@@ -0,0 +1,435 @@
+package pamspr
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SplitOptions sets the limits each file produced by Split must respect
+type SplitOptions struct {
+	// MaxSchedulesPerFile limits the schedules in each file (default: MaxSchedulesPerFile)
+	MaxSchedulesPerFile int
+
+	// MaxPaymentsPerSchedule limits the payments in each schedule; larger
+	// schedules are continued in new schedules (default: MaxPaymentsPerSchedule)
+	MaxPaymentsPerSchedule int
+
+	// MaxFileSizeBytes limits the size of each file as written with
+	// LineTerminator (default: MaxFileSizeBytes)
+	MaxFileSizeBytes int64
+
+	// LineTerminator is the record delimiter the files will be written with,
+	// used to size them (default: LF)
+	LineTerminator LineTerminator
+
+	// ScheduleNumber numbers the continuation of a schedule that did not fit,
+	// part being 2 for the first continuation. The default appends "-2", "-3"
+	// and so on, shortening the number to fit 14 characters. Numbers must be
+	// unique across the files produced (optional)
+	ScheduleNumber func(scheduleNumber string, part int) string
+}
+
+// DefaultSplitOptions returns the Treasury limits from constants.go
+func DefaultSplitOptions() *SplitOptions {
+	return &SplitOptions{
+		MaxSchedulesPerFile:    MaxSchedulesPerFile,
+		MaxPaymentsPerSchedule: MaxPaymentsPerSchedule,
+		MaxFileSizeBytes:       MaxFileSizeBytes,
+	}
+}
+
+// Split partitions file into files that each respect the limits in options
+// (nil for the defaults). Each payment stays with its addenda, CARS, stub and
+// DNP records; schedules that do not fit are continued under new schedule
+// numbers, and every trailer is rebuilt. The files share payments with file.
+func Split(file *File, options *SplitOptions) ([]*File, error) {
+	if file.Header == nil {
+		return nil, fmt.Errorf("file header is required")
+	}
+
+	var parts []*File
+	s, err := newFileSplitter(options, func(part *File) error {
+		parts = append(parts, part)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.splitFile(file); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// SplitReader splits the file read by reader like Split, holding one schedule
+// and one output file in memory at a time. Each file is written with config to
+// the writer returned by create, numbered from 1; create may return an
+// io.WriteCloser, which is closed once its file is written. It returns the
+// number of files written.
+//
+// Schedule numbers are only known as schedules are read, so a continuation
+// may take the number of a later schedule, e.g. "1-2" for schedule 1 before
+// schedule "1-2" is read. That schedule is then renumbered as a continuation
+// of itself ("1-2-2"), keeping numbers unique as Split does.
+func SplitReader(reader *Reader, options *SplitOptions, config *WriterConfig, create func(part int) (io.Writer, error)) (int, error) {
+	if config == nil {
+		config = DefaultWriterConfig()
+	}
+
+	count := 0
+	s, err := newFileSplitter(options, func(part *File) error {
+		count++
+		out, err := create(count)
+		if err != nil {
+			return fmt.Errorf("creating file %d: %w", count, err)
+		}
+		err = NewWriterWithConfig(out, config).Write(part)
+		if closer, ok := out.(io.Closer); ok {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("writing file %d: %w", count, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if reader.config.RecoveryMode {
+		// Recovery repairs schedules after reading them, so read the whole file
+		file, err := reader.readFile()
+		if err != nil {
+			return count, err
+		}
+		return count, s.splitFile(file)
+	}
+
+	err = reader.run(func() error {
+		_, err := reader.readSchedules(
+			func(header *FileHeader) error {
+				s.header = header
+				return nil
+			},
+			func(schedule Schedule) error {
+				number, err := s.claim(writtenScheduleNumber(schedule))
+				if err != nil {
+					return err
+				}
+				return s.addSchedule(schedule, number)
+			},
+		)
+		return err
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, s.finish()
+}
+
+// fileSplitter fills one output file at a time, passing each to emit when full
+type fileSplitter struct {
+	options    SplitOptions
+	maxRecords int64
+	emit       func(*File) error
+	header     *FileHeader
+	used       map[string]bool // Schedule numbers, see scheduleKey; true for continuations
+
+	part     *File
+	records  int64    // Records in part, counting its header and trailer
+	segment  Schedule // Open schedule in part
+	payments int      // Payments in segment
+	amount   int64
+}
+
+func newFileSplitter(options *SplitOptions, emit func(*File) error) (*fileSplitter, error) {
+	resolved := *DefaultSplitOptions()
+	if options != nil {
+		resolved = *options
+		if resolved.MaxSchedulesPerFile <= 0 {
+			resolved.MaxSchedulesPerFile = MaxSchedulesPerFile
+		}
+		if resolved.MaxPaymentsPerSchedule <= 0 {
+			resolved.MaxPaymentsPerSchedule = MaxPaymentsPerSchedule
+		}
+		if resolved.MaxFileSizeBytes <= 0 {
+			resolved.MaxFileSizeBytes = MaxFileSizeBytes
+		}
+	}
+
+	maxRecords := resolved.MaxFileSizeBytes / int64(RecordLength+len(resolved.LineTerminator.bytes()))
+	if maxRecords < 5 {
+		return nil, fmt.Errorf("maximum file size %d bytes cannot hold a schedule with one payment", resolved.MaxFileSizeBytes)
+	}
+
+	return &fileSplitter{
+		options:    resolved,
+		maxRecords: maxRecords,
+		emit:       emit,
+		used:       make(map[string]bool),
+	}, nil
+}
+
+// scheduleKey compares schedule numbers the way PAM stores them: upper-case,
+// right-justified and zero-filled
+func scheduleKey(scheduleNumber string) string {
+	return strings.TrimLeft(strings.ToUpper(strings.TrimSpace(scheduleNumber)), "0")
+}
+
+// splitFile splits a whole file, reserving its schedule numbers first so
+// continuations do not take a number used later in the file
+func (s *fileSplitter) splitFile(file *File) error {
+	for _, schedule := range file.Schedules {
+		if err := s.reserve(writtenScheduleNumber(schedule), false); err != nil {
+			return err
+		}
+	}
+
+	s.header = file.Header
+	for _, schedule := range file.Schedules {
+		if err := s.addSchedule(schedule, writtenScheduleNumber(schedule)); err != nil {
+			return err
+		}
+	}
+	return s.finish()
+}
+
+// reserve claims a schedule number, which must be unique within the file
+// (Error Reason Group 2 Message 1)
+func (s *fileSplitter) reserve(scheduleNumber string, continuation bool) error {
+	key := scheduleKey(scheduleNumber)
+	if _, ok := s.used[key]; ok {
+		return fmt.Errorf("schedule number %s is not unique", strings.TrimSpace(scheduleNumber))
+	}
+	s.used[key] = continuation
+	return nil
+}
+
+// claim reserves the number of a schedule read after earlier schedules were
+// split. A number already taken by a continuation is replaced with the next
+// free continuation of it; one used by another input schedule is an error.
+func (s *fileSplitter) claim(scheduleNumber string) (string, error) {
+	if continuation := s.used[scheduleKey(scheduleNumber)]; continuation {
+		return s.continuationNumber(scheduleNumber, 2)
+	}
+	return scheduleNumber, s.reserve(scheduleNumber, false)
+}
+
+// addSchedule adds schedule and its payments under number, continuing it in
+// new schedules and files as the limits require
+func (s *fileSplitter) addSchedule(schedule Schedule, number string) error {
+	payments := schedule.GetPayments()
+	continuation := 1
+
+	var first int64
+	if len(payments) > 0 {
+		first = paymentRecords(payments[0])
+	}
+	if err := s.openSegment(schedule, number, first); err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		records := paymentRecords(payment)
+		if 2+1+records+1 > s.maxRecords {
+			return fmt.Errorf("payment %s has %d records, more than fit in a file", strings.TrimSpace(payment.GetPaymentID()), records)
+		}
+
+		// The open schedule's trailer still has to fit
+		if s.payments >= s.options.MaxPaymentsPerSchedule || s.records+records+1 > s.maxRecords {
+			continuation++
+			next, err := s.continuationNumber(number, continuation)
+			if err != nil {
+				return err
+			}
+			s.closeSegment()
+			if err := s.openSegment(schedule, next, records); err != nil {
+				return err
+			}
+		}
+
+		s.segment = appendPayment(s.segment, payment)
+		s.records += records
+		s.payments++
+		s.amount += payment.GetAmount()
+	}
+
+	s.closeSegment()
+	return nil
+}
+
+// openSegment starts a schedule in the current file, or a new file if the
+// schedule with its first payment does not fit
+func (s *fileSplitter) openSegment(schedule Schedule, number string, firstPayment int64) error {
+	if s.part != nil && (len(s.part.Schedules) >= s.options.MaxSchedulesPerFile ||
+		s.records+1+firstPayment+1 > s.maxRecords) {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	if s.part == nil {
+		s.part = &File{Header: s.header, Schedules: make([]Schedule, 0)}
+		s.records = 2 // File header and trailer
+	}
+
+	segment, err := scheduleSegment(schedule, number)
+	if err != nil {
+		return err
+	}
+	s.part.Schedules = append(s.part.Schedules, segment)
+	s.segment = segment
+	s.records++ // Schedule header
+	s.payments, s.amount = 0, 0
+	return nil
+}
+
+// closeSegment writes the open schedule's trailer
+func (s *fileSplitter) closeSegment() {
+	s.segment.SetTrailer(&ScheduleTrailer{
+		RecordCode:     string(RecordTypeScheduleTrailer),
+		ScheduleCount:  int64(s.payments),
+		ScheduleAmount: s.amount,
+	})
+	s.records++
+	s.segment = nil
+}
+
+// flush completes the current file and passes it to emit
+func (s *fileSplitter) flush() error {
+	part := s.part
+	s.part = nil
+
+	trailer := &FileTrailer{
+		RecordCode:        string(RecordTypeFileTrailer),
+		TotalCountRecords: s.records,
+	}
+	for _, schedule := range part.Schedules {
+		trailer.TotalCountPayments += schedule.GetTrailer().ScheduleCount
+		trailer.TotalAmountPayments += schedule.GetTrailer().ScheduleAmount
+	}
+	part.Trailer = trailer
+
+	return s.emit(part)
+}
+
+// finish emits the last file
+func (s *fileSplitter) finish() error {
+	if s.part == nil {
+		if s.header == nil {
+			return fmt.Errorf("file header is required")
+		}
+		// A file without schedules is still passed through
+		s.part = &File{Header: s.header, Schedules: make([]Schedule, 0)}
+		s.records = 2
+	}
+	return s.flush()
+}
+
+// continuationNumber numbers part of a schedule, claiming the number
+func (s *fileSplitter) continuationNumber(scheduleNumber string, part int) (string, error) {
+	if s.options.ScheduleNumber != nil {
+		number := s.options.ScheduleNumber(scheduleNumber, part)
+		if len(number) > 14 {
+			return "", fmt.Errorf("continuation schedule number %s is longer than 14 characters", number)
+		}
+		if err := s.reserve(number, true); err != nil {
+			return "", err
+		}
+		return number, nil
+	}
+
+	base := strings.TrimLeft(strings.ToUpper(strings.TrimSpace(scheduleNumber)), "0")
+	for n := part; ; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		number := base
+		if len(number)+len(suffix) > 14 {
+			number = number[len(number)+len(suffix)-14:]
+		}
+		number += suffix
+		if s.reserve(number, true) == nil {
+			return number, nil
+		}
+	}
+}
+
+// writtenScheduleNumber returns the schedule number from the schedule header
+func writtenScheduleNumber(schedule Schedule) string {
+	switch s := schedule.(type) {
+	case *ACHSchedule:
+		if s.Header != nil {
+			return s.Header.ScheduleNumber
+		}
+	case *CheckSchedule:
+		if s.Header != nil {
+			return s.Header.ScheduleNumber
+		}
+	}
+	return schedule.GetScheduleNumber()
+}
+
+// scheduleSegment copies schedule's header under a new number, without payments
+func scheduleSegment(schedule Schedule, number string) (Schedule, error) {
+	switch s := schedule.(type) {
+	case *ACHSchedule:
+		segment := &ACHSchedule{BaseSchedule: s.BaseSchedule}
+		if s.Header != nil {
+			header := *s.Header
+			header.ScheduleNumber = number
+			segment.Header = &header
+		}
+		segment.ScheduleNumber = number
+		segment.Payments = make([]Payment, 0)
+		segment.Trailer = nil
+		return segment, nil
+	case *CheckSchedule:
+		segment := &CheckSchedule{BaseSchedule: s.BaseSchedule}
+		if s.Header != nil {
+			header := *s.Header
+			header.ScheduleNumber = number
+			segment.Header = &header
+		}
+		segment.ScheduleNumber = number
+		segment.Payments = make([]Payment, 0)
+		segment.Trailer = nil
+		return segment, nil
+	default:
+		return nil, fmt.Errorf("unknown schedule type %T", schedule)
+	}
+}
+
+// appendPayment adds payment to a schedule created by scheduleSegment
+func appendPayment(schedule Schedule, payment Payment) Schedule {
+	switch s := schedule.(type) {
+	case *ACHSchedule:
+		s.Payments = append(s.Payments, payment)
+	case *CheckSchedule:
+		s.Payments = append(s.Payments, payment)
+	}
+	return schedule
+}
+
+// paymentRecords counts the payment record and its associated records
+func paymentRecords(payment Payment) int64 {
+	switch p := payment.(type) {
+	case *ACHPayment:
+		records := 1 + int64(p.AddendaCount()+len(p.CARSTASBETC))
+		if p.DNP != nil {
+			records++
+		}
+		return records
+	case *CheckPayment:
+		records := 1 + int64(len(p.CARSTASBETC))
+		if p.Stub != nil {
+			records++
+		}
+		if p.DNP != nil {
+			records++
+		}
+		return records
+	}
+	return 1
+}
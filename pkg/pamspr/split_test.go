@@ -0,0 +1,314 @@
+package pamspr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  *SplitOptions
+		payments [][]int // Payments in each schedule of each file
+	}{
+		{
+			name:     "Within limits",
+			options:  nil,
+			payments: [][]int{{1000, 1000, 500}},
+		},
+		{
+			name:     "Payments per schedule",
+			options:  &SplitOptions{MaxPaymentsPerSchedule: 400},
+			payments: [][]int{{400, 400, 200, 400, 400, 200, 400, 100}},
+		},
+		{
+			name:     "Schedules per file",
+			options:  &SplitOptions{MaxPaymentsPerSchedule: 400, MaxSchedulesPerFile: 3},
+			payments: [][]int{{400, 400, 200}, {400, 400, 200}, {400, 100}},
+		},
+		{
+			name:     "File size",
+			options:  &SplitOptions{MaxFileSizeBytes: 1000 * (RecordLength + 1)},
+			payments: [][]int{{996}, {4, 990}, {10, 500}},
+		},
+		{
+			name:     "File size with CRLF",
+			options:  &SplitOptions{MaxFileSizeBytes: 1000 * (RecordLength + 1), LineTerminator: LineTerminatorCRLF},
+			payments: [][]int{{994}, {6, 986}, {14, 500}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := generateTestFile(2500)
+			parts, err := Split(file, tt.options)
+			if err != nil {
+				t.Fatalf("Split failed: %v", err)
+			}
+
+			var payments [][]int
+			for _, part := range parts {
+				var counts []int
+				for _, schedule := range part.Schedules {
+					counts = append(counts, len(schedule.GetPayments()))
+				}
+				payments = append(payments, counts)
+			}
+			if fmt.Sprint(payments) != fmt.Sprint(tt.payments) {
+				t.Errorf("Expected payments %v, got %v", tt.payments, payments)
+			}
+
+			options := tt.options
+			if options == nil {
+				options = DefaultSplitOptions()
+			}
+			checkSplit(t, file, parts, options)
+		})
+	}
+}
+
+// checkSplit verifies parts are balanced, within limits and hold file's payments in order
+func checkSplit(t *testing.T, file *File, parts []*File, options *SplitOptions) {
+	t.Helper()
+
+	validator := NewValidator()
+	numbers := make(map[string]bool)
+	var ids []string
+	for i, part := range parts {
+		if err := validator.ValidateBalancing(part); err != nil {
+			t.Errorf("File %d does not balance: %v", i+1, err)
+		}
+		if options.MaxSchedulesPerFile > 0 && len(part.Schedules) > options.MaxSchedulesPerFile {
+			t.Errorf("File %d has %d schedules", i+1, len(part.Schedules))
+		}
+
+		var buf bytes.Buffer
+		config := DefaultWriterConfig()
+		config.LineTerminator = options.LineTerminator
+		if err := NewWriterWithConfig(&buf, config).Write(part); err != nil {
+			t.Fatalf("File %d: Write failed: %v", i+1, err)
+		}
+		if options.MaxFileSizeBytes > 0 && int64(buf.Len()) > options.MaxFileSizeBytes {
+			t.Errorf("File %d is %d bytes, over %d", i+1, buf.Len(), options.MaxFileSizeBytes)
+		}
+
+		for _, schedule := range part.Schedules {
+			number := scheduleKey(writtenScheduleNumber(schedule))
+			if numbers[number] {
+				t.Errorf("Schedule number %s used twice", number)
+			}
+			numbers[number] = true
+			if options.MaxPaymentsPerSchedule > 0 && len(schedule.GetPayments()) > options.MaxPaymentsPerSchedule {
+				t.Errorf("Schedule %s has %d payments", number, len(schedule.GetPayments()))
+			}
+			for _, payment := range schedule.GetPayments() {
+				ids = append(ids, payment.GetPaymentID())
+			}
+		}
+	}
+
+	var want []string
+	for _, schedule := range file.Schedules {
+		for _, payment := range schedule.GetPayments() {
+			want = append(want, payment.GetPaymentID())
+		}
+	}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %d payments in their original order, got %d", len(want), len(ids))
+	}
+}
+
+func TestSplitScheduleNumbers(t *testing.T) {
+	file := generateTestFile(1500)
+
+	parts, err := Split(file, &SplitOptions{MaxPaymentsPerSchedule: 400})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	var numbers []string
+	for _, schedule := range parts[0].Schedules {
+		numbers = append(numbers, writtenScheduleNumber(schedule))
+	}
+	if got := strings.Join(numbers, " "); got != "00000000000001 1-2 1-3 00000000000002 2-2" {
+		t.Errorf("Unexpected schedule numbers: %s", got)
+	}
+	if file.Schedules[0].(*ACHSchedule).Header.ScheduleNumber != "00000000000001" {
+		t.Error("Split modified the input schedule header")
+	}
+
+	// A continuation skips numbers already in the file
+	file.Schedules[1].(*ACHSchedule).Header.ScheduleNumber = "1-2"
+	parts, err = Split(file, &SplitOptions{MaxPaymentsPerSchedule: 400})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if got := writtenScheduleNumber(parts[0].Schedules[1]); got != "1-3" {
+		t.Errorf("Expected continuation 1-3, got %s", got)
+	}
+
+	// Custom numbering
+	parts, err = Split(generateTestFile(1500), &SplitOptions{
+		MaxPaymentsPerSchedule: 400,
+		ScheduleNumber: func(scheduleNumber string, part int) string {
+			return fmt.Sprintf("%s%d", strings.TrimLeft(scheduleNumber, "0"), part*100)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if got := writtenScheduleNumber(parts[0].Schedules[1]); got != "1200" {
+		t.Errorf("Expected custom continuation 1200, got %s", got)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	duplicate := createTestACHFile()
+	duplicate.Schedules = append(duplicate.Schedules, createTestACHFile().Schedules[0])
+	if _, err := Split(duplicate, nil); err == nil || !strings.Contains(err.Error(), "not unique") {
+		t.Errorf("Expected a duplicate schedule number error, got %v", err)
+	}
+
+	large := createTestACHWithAddenda()
+	if _, err := Split(large, &SplitOptions{MaxFileSizeBytes: 5 * (RecordLength + 1)}); err == nil || !strings.Contains(err.Error(), "PAY001") {
+		t.Errorf("Expected an oversized payment error, got %v", err)
+	}
+
+	if _, err := Split(large, &SplitOptions{MaxFileSizeBytes: 4 * (RecordLength + 1)}); err == nil {
+		t.Error("Expected an error for a size that cannot hold a payment")
+	}
+}
+
+func TestSplitReader(t *testing.T) {
+	// One payment per file keeps each payment's addenda, CARS, stub and DNP records together
+	file := createTestMixedFile()
+	file.Schedules[1].(*CheckSchedule).Header.ScheduleNumber = "00000000000002"
+	ach := file.Schedules[0].(*ACHSchedule)
+	ach.Payments[0].(*ACHPayment).Addenda = []*ACHAddendum{{RecordCode: "03", PaymentID: "PAY001", AddendaInformation: "INVOICE 1"}}
+	file.Trailer.TotalCountRecords++
+
+	var input bytes.Buffer
+	if err := NewWriter(&input).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var outputs []*bytes.Buffer
+	count, err := SplitReader(NewReader(&input), &SplitOptions{MaxFileSizeBytes: 6 * (RecordLength + 1)}, nil,
+		func(part int) (io.Writer, error) {
+			if part != len(outputs)+1 {
+				t.Errorf("Expected file %d, got %d", len(outputs)+1, part)
+			}
+			outputs = append(outputs, &bytes.Buffer{})
+			return outputs[part-1], nil
+		})
+	if err != nil {
+		t.Fatalf("SplitReader failed: %v", err)
+	}
+	if count != 3 || len(outputs) != 3 {
+		t.Fatalf("Expected 3 files, got %d", count)
+	}
+
+	wantRecords := []int{6, 5, 6} // H 01 02 03 T E, H 01 02 T E, H 11 12 13 T E
+	for i, output := range outputs {
+		records := strings.Count(output.String(), "\n")
+		if records != wantRecords[i] {
+			t.Errorf("File %d: expected %d records, got %d", i+1, wantRecords[i], records)
+		}
+		part, err := NewReader(output).Read()
+		if err != nil {
+			t.Fatalf("File %d: Read failed: %v", i+1, err)
+		}
+		if err := NewValidator().ValidateBalancing(part); err != nil {
+			t.Errorf("File %d does not balance: %v", i+1, err)
+		}
+	}
+}
+
+func TestSplitReaderScheduleNumbers(t *testing.T) {
+	// Schedule 1 is continued as 1-2 before the schedule numbered 1-2 is read
+	file := generateTestFile(1500)
+	file.Schedules[1].(*ACHSchedule).Header.ScheduleNumber = "1-2"
+
+	var input bytes.Buffer
+	if err := NewWriter(&input).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var outputs []*bytes.Buffer
+	_, err := SplitReader(NewReader(&input), &SplitOptions{MaxPaymentsPerSchedule: 400}, nil,
+		func(part int) (io.Writer, error) {
+			outputs = append(outputs, &bytes.Buffer{})
+			return outputs[part-1], nil
+		})
+	if err != nil {
+		t.Fatalf("SplitReader failed: %v", err)
+	}
+
+	var numbers []string
+	for _, output := range outputs {
+		part, err := NewReader(output).Read()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		for _, schedule := range part.Schedules {
+			numbers = append(numbers, strings.TrimLeft(writtenScheduleNumber(schedule), "0"))
+		}
+	}
+	if got := strings.Join(numbers, " "); got != "1 1-2 1-3 1-2-2 1-2-2-2" {
+		t.Errorf("Unexpected schedule numbers: %s", got)
+	}
+
+	// A number repeated in the input is still an error
+	duplicate := createTestACHFile()
+	duplicate.Schedules = append(duplicate.Schedules, createTestACHFile().Schedules[0])
+	duplicate.Trailer.TotalCountRecords += 4
+	duplicate.Trailer.TotalCountPayments += 2
+	duplicate.Trailer.TotalAmountPayments += 300000
+	input.Reset()
+	if err := NewWriter(&input).Write(duplicate); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	_, err = SplitReader(NewReader(&input), nil, nil, func(part int) (io.Writer, error) { return io.Discard, nil })
+	if err == nil || !strings.Contains(err.Error(), "not unique") {
+		t.Errorf("Expected a duplicate schedule number error, got %v", err)
+	}
+}
+
+func TestSplitReaderKeepsNoRecordLines(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/synthetic/valid/synthetic_multi_schedule.spr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, recovery := range []bool{false, true} {
+		config := DefaultConfig()
+		config.RecoveryMode = recovery
+
+		reader := NewReaderWithConfig(bytes.NewReader(data), config)
+		if _, err := SplitReader(reader, nil, nil, func(part int) (io.Writer, error) { return io.Discard, nil }); err != nil {
+			t.Fatalf("SplitReader failed: %v", err)
+		}
+		if n := len(reader.RecordLines()); n != 0 {
+			t.Errorf("Recovery %v: SplitReader kept %d record lines", recovery, n)
+		}
+
+		reader = NewReaderWithConfig(bytes.NewReader(data), config)
+		if err := reader.ProcessFile(nil, nil, nil); err != nil {
+			t.Fatalf("ProcessFile failed: %v", err)
+		}
+		if n := len(reader.RecordLines()); n != 0 {
+			t.Errorf("Recovery %v: ProcessFile kept %d record lines", recovery, n)
+		}
+
+		reader = NewReaderWithConfig(bytes.NewReader(data), config)
+		if _, err := reader.Read(); err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if n := len(reader.RecordLines()); n != 9 {
+			t.Errorf("Recovery %v: expected Read to keep 9 record lines, got %d", recovery, n)
+		}
+	}
+}
@@ -107,6 +107,38 @@ func BenchmarkStreamingWriter_vs_TraditionalWriter(b *testing.B) {
 				_ = buf.Len()
 			}
 		})
+
+		b.Run(fmt.Sprintf("Schedule_API_Writer_%d_payments", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				writer := NewWriter(&buf)
+
+				if err := writer.WriteFileHeader(testFile.Header); err != nil {
+					b.Fatal(err)
+				}
+
+				// Trailers are computed by the writer
+				for _, schedule := range testFile.Schedules {
+					if err := writer.BeginSchedule(schedule); err != nil {
+						b.Fatal(err)
+					}
+					for _, payment := range schedule.GetPayments() {
+						if err := writer.AddPayment(payment); err != nil {
+							b.Fatal(err)
+						}
+					}
+					if _, err := writer.EndSchedule(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if _, err := writer.Close(); err != nil {
+					b.Fatal(err)
+				}
+
+				// Force evaluation
+				_ = buf.Len()
+			}
+		})
 	}
 }
 
@@ -296,8 +328,39 @@ func BenchmarkConcurrentProcessing(b *testing.B) {
 	})
 }
 
+// BenchmarkParallelValidation compares serial validation with the worker pool
+func BenchmarkParallelValidation(b *testing.B) {
+	testData := writeTestFileData(b, 10000)
+
+	for _, workers := range []int{0, 2, 4, 8} {
+		b.Run(fmt.Sprintf("Workers_%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(testData)))
+			for i := 0; i < b.N; i++ {
+				config := DefaultConfig()
+				config.ValidationWorkers = workers
+				reader := NewReaderWithConfig(strings.NewReader(testData), config)
+				err := reader.ProcessFile(nil, func(Payment, int, int) bool { return true }, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_ = reader.GetValidationReport()
+			}
+		})
+	}
+}
+
 // Helper functions for benchmark data generation
 
+// writeTestFileData renders generateTestFile with the Writer
+func writeTestFileData(tb testing.TB, numPayments int) string {
+	tb.Helper()
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(generateTestFile(numPayments)); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.String()
+}
+
 func generateTestFileData(numPayments int) string {
 	var builder strings.Builder
 
@@ -0,0 +1,249 @@
+package pamspr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Check stub layout (13.03)
+const (
+	StubLineLength = 55 // Characters per payment identification line
+	StubLineCount  = 14 // Payment identification lines per stub record
+)
+
+// StubEnclosureObjectLine is the object line 3 PAM prints on checks of a
+// schedule with the "stub" enclosure code (1.7 Derived Data Elements)
+const StubEnclosureObjectLine = "Per Enclosed Mailing Notice"
+
+// CheckObjectLine3 returns the object line 3 PAM derives for checks with
+// enclosureCode, blank unless the checks carry a stub
+func CheckObjectLine3(enclosureCode string) string {
+	if strings.TrimSpace(enclosureCode) == CheckEnclosureStub {
+		return StubEnclosureObjectLine
+	}
+	return ""
+}
+
+// StubColumn is a column of a table added to a StubComposer
+type StubColumn struct {
+	Header string
+	Width  int
+	Right  bool // Right justify, e.g. for amounts
+}
+
+// StubComposer lays out free text and tables as the 55 character payment
+// identification lines of check stubs. Text is word wrapped, and words
+// longer than a line are split. Errors are collected and returned by Stub
+// and Pages.
+type StubComposer struct {
+	// Continued is the last line of every stub but the final one when Pages
+	// splits the text across stubs, e.g. "CONTINUED ON NEXT STUB" (blank = none)
+	Continued string
+
+	lines  []string
+	errors []error
+}
+
+// NewStubComposer returns an empty composer
+func NewStubComposer() *StubComposer {
+	return &StubComposer{}
+}
+
+// AddText word wraps text; each newline starts a new line and blank lines are kept
+func (c *StubComposer) AddText(text string) *StubComposer {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		c.lines = append(c.lines, wrapStubText(paragraph, StubLineLength)...)
+	}
+	return c
+}
+
+// AddBlankLine adds an empty line
+func (c *StubComposer) AddBlankLine() *StubComposer {
+	c.lines = append(c.lines, "")
+	return c
+}
+
+// AddTable adds a header row, when any column has a header, and one row per
+// entry of rows. Columns are separated by a space and cells wider than their
+// column wrap onto further lines of the row.
+func (c *StubComposer) AddTable(columns []StubColumn, rows [][]string) *StubComposer {
+	if len(columns) == 0 {
+		c.errors = append(c.errors, fmt.Errorf("stub table has no columns"))
+		return c
+	}
+
+	width := len(columns) - 1 // Separators
+	for i, column := range columns {
+		if column.Width < 1 {
+			c.errors = append(c.errors, fmt.Errorf("stub table column %d has width %d", i+1, column.Width))
+			return c
+		}
+		width += column.Width
+	}
+	if width > StubLineLength {
+		c.errors = append(c.errors, fmt.Errorf("stub table is %d characters wide, maximum is %d", width, StubLineLength))
+		return c
+	}
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+	if strings.TrimSpace(strings.Join(headers, "")) != "" {
+		c.lines = append(c.lines, stubTableRow(columns, headers)...)
+	}
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			c.errors = append(c.errors, fmt.Errorf("stub table row %d has %d cells, expected %d", i+1, len(row), len(columns)))
+			continue
+		}
+		c.lines = append(c.lines, stubTableRow(columns, row)...)
+	}
+	return c
+}
+
+// Lines returns the composed lines
+func (c *StubComposer) Lines() []string {
+	return append([]string(nil), c.lines...)
+}
+
+// Err returns the errors collected while composing, joined into one error
+func (c *StubComposer) Err() error {
+	return errors.Join(c.errors...)
+}
+
+// Stub builds the "13" record for a payment; the lines must fit on one stub
+func (c *StubComposer) Stub(paymentID string) (*CheckStub, error) {
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	if len(c.lines) > StubLineCount {
+		return nil, fmt.Errorf("stub text needs %d lines, maximum is %d", len(c.lines), StubLineCount)
+	}
+	return c.stub(paymentID, c.lines), nil
+}
+
+// Pages splits the lines across as many "13" records as needed, ending every
+// stub but the last with Continued. A payment carries one stub, so where the
+// further stubs go, such as the payments of a split remittance, is up to the caller.
+func (c *StubComposer) Pages(paymentID string) ([]*CheckStub, error) {
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	if len(c.Continued) > StubLineLength {
+		return nil, fmt.Errorf("continued line is %d characters, maximum is %d", len(c.Continued), StubLineLength)
+	}
+
+	if len(c.lines) <= StubLineCount {
+		return []*CheckStub{c.stub(paymentID, c.lines)}, nil
+	}
+
+	perPage := StubLineCount
+	if c.Continued != "" {
+		perPage--
+	}
+
+	var stubs []*CheckStub
+	for start := 0; start < len(c.lines); start += perPage {
+		if len(c.lines)-start <= StubLineCount {
+			stubs = append(stubs, c.stub(paymentID, c.lines[start:]))
+			break
+		}
+		page := c.lines[start : start+perPage]
+		if c.Continued != "" {
+			page = append(append([]string(nil), page...), c.Continued)
+		}
+		stubs = append(stubs, c.stub(paymentID, page))
+	}
+	return stubs, nil
+}
+
+func (c *StubComposer) stub(paymentID string, lines []string) *CheckStub {
+	stub := &CheckStub{
+		RecordCode: string(RecordTypeCheckStub),
+		PaymentID:  paymentID,
+	}
+	copy(stub.PaymentIdentificationLines[:], lines)
+	return stub
+}
+
+// EncodeStub composes the payment's stub record for a schedule with
+// enclosureCode. Only "stub" schedules send stub records, one per payment.
+func (p *CheckPayment) EncodeStub(c *StubComposer, enclosureCode string) error {
+	if code := strings.TrimSpace(enclosureCode); code != CheckEnclosureStub {
+		return fmt.Errorf("check enclosure code %q does not send stub records, use %q", code, CheckEnclosureStub)
+	}
+	stub, err := c.Stub(p.PaymentID)
+	if err != nil {
+		return err
+	}
+	p.Stub = stub
+	return nil
+}
+
+// wrapStubText greedily word wraps text to width, splitting longer words; a
+// blank text is one empty line
+func wrapStubText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	var line string
+	for _, word := range words {
+		for len(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// stubTableRow lays out one table row, as many lines as its tallest cell
+func stubTableRow(columns []StubColumn, cells []string) []string {
+	wrapped := make([][]string, len(columns))
+	height := 1
+	for i, column := range columns {
+		wrapped[i] = wrapStubText(cells[i], column.Width)
+		if len(wrapped[i]) > height {
+			height = len(wrapped[i])
+		}
+	}
+
+	lines := make([]string, height)
+	for n := range lines {
+		parts := make([]string, len(columns))
+		for i, column := range columns {
+			var cell string
+			if n < len(wrapped[i]) {
+				cell = wrapped[i][n]
+			}
+			if column.Right {
+				parts[i] = PadLeft(cell, column.Width, ' ')
+			} else {
+				parts[i] = PadRight(cell, column.Width, ' ')
+			}
+		}
+		lines[n] = strings.TrimRight(strings.Join(parts, " "), " ")
+	}
+	return lines
+}
@@ -0,0 +1,151 @@
+package pamspr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapStubText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{"Blank", "   ", 10, []string{""}},
+		{"Fits", "INVOICE 1234", 12, []string{"INVOICE 1234"}},
+		{"Wraps at words", "PAYMENT FOR INVOICE 1234", 12, []string{"PAYMENT FOR", "INVOICE 1234"}},
+		{"Collapses spaces", "A   B\tC", 10, []string{"A B C"}},
+		{"Splits long words", "REF ABCDEFGHIJKLMNOP X", 6, []string{"REF", "ABCDEF", "GHIJKL", "MNOP X"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapStubText(tt.text, tt.width)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrapStubText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStubComposer(t *testing.T) {
+	composer := NewStubComposer().
+		AddText("PAYMENT OF THE INVOICES BELOW UNDER CONTRACT 47QRAA18D00XY, PLEASE DIRECT QUESTIONS TO THE AGENCY\n").
+		AddTable([]StubColumn{
+			{Header: "INVOICE", Width: 12},
+			{Header: "DESCRIPTION", Width: 26},
+			{Header: "AMOUNT", Width: 12, Right: true},
+		}, [][]string{
+			{"INV-1001", "OFFICE SUPPLIES", "1,250.00"},
+			{"INV-1002", "MAINTENANCE SERVICES FOR THE THIRD QUARTER", "980.50"},
+		})
+
+	want := []string{
+		"PAYMENT OF THE INVOICES BELOW UNDER CONTRACT",
+		"47QRAA18D00XY, PLEASE DIRECT QUESTIONS TO THE AGENCY",
+		"",
+		"INVOICE      DESCRIPTION                      AMOUNT",
+		"INV-1001     OFFICE SUPPLIES                1,250.00",
+		"INV-1002     MAINTENANCE SERVICES FOR         980.50",
+		"             THE THIRD QUARTER",
+	}
+	lines := composer.Lines()
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected lines:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	for _, line := range lines {
+		if len(line) > StubLineLength {
+			t.Errorf("Line is %d characters: %q", len(line), line)
+		}
+	}
+
+	payment := &CheckPayment{PaymentID: "CHK001", Amount: 223050}
+	if err := payment.EncodeStub(composer, "stub      "); err != nil {
+		t.Fatalf("EncodeStub failed: %v", err)
+	}
+	if payment.Stub.RecordCode != "13" || payment.Stub.PaymentID != "CHK001" {
+		t.Errorf("Unexpected stub record %q for %q", payment.Stub.RecordCode, payment.Stub.PaymentID)
+	}
+	if payment.Stub.PaymentIdentificationLines[6] != want[6] || payment.Stub.PaymentIdentificationLines[7] != "" {
+		t.Errorf("Unexpected stub lines: %q", payment.Stub.PaymentIdentificationLines)
+	}
+
+	for _, code := range []string{CheckEnclosureNameOnly, CheckEnclosureLetter, CheckEnclosureInsert, ""} {
+		if err := (&CheckPayment{PaymentID: "CHK002"}).EncodeStub(composer, code); err == nil {
+			t.Errorf("Expected an error for enclosure code %q", code)
+		}
+	}
+}
+
+func TestStubComposerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		composer *StubComposer
+		want     string
+	}{
+		{"Too wide", NewStubComposer().AddTable([]StubColumn{{Width: 30}, {Width: 30}}, nil), "61 characters wide"},
+		{"No columns", NewStubComposer().AddTable(nil, nil), "no columns"},
+		{"Zero width", NewStubComposer().AddTable([]StubColumn{{Width: 0}}, nil), "width 0"},
+		{"Missing cell", NewStubComposer().AddTable([]StubColumn{{Width: 10}, {Width: 10}}, [][]string{{"A"}}), "row 1 has 1 cells"},
+		{"Too many lines", NewStubComposer().AddText(strings.Repeat("LINE\n", 14)), "needs 15 lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.composer.Stub("CHK001")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStubComposerPages(t *testing.T) {
+	composer := NewStubComposer()
+	for i := 1; i <= 30; i++ {
+		composer.AddText(strings.Repeat("X", i))
+	}
+
+	stubs, err := composer.Pages("CHK001")
+	if err != nil {
+		t.Fatalf("Pages failed: %v", err)
+	}
+	if len(stubs) != 3 {
+		t.Fatalf("Expected 30 lines on 3 stubs, got %d", len(stubs))
+	}
+	if stubs[2].PaymentIdentificationLines[1] != strings.Repeat("X", 30) {
+		t.Errorf("Expected the last line on the third stub, got %q", stubs[2].PaymentIdentificationLines)
+	}
+
+	composer.Continued = "CONTINUED ON NEXT STUB"
+	stubs, err = composer.Pages("CHK001")
+	if err != nil {
+		t.Fatalf("Pages failed: %v", err)
+	}
+	// 13 lines and the continued line on each full stub, the last 4 on the third
+	if len(stubs) != 3 {
+		t.Fatalf("Expected 3 stubs, got %d", len(stubs))
+	}
+	for _, stub := range stubs[:2] {
+		if stub.PaymentIdentificationLines[13] != composer.Continued {
+			t.Errorf("Expected a continued line, got %q", stub.PaymentIdentificationLines[13])
+		}
+	}
+	if stubs[2].PaymentIdentificationLines[3] != strings.Repeat("X", 30) || stubs[2].PaymentIdentificationLines[4] != "" {
+		t.Errorf("Unexpected last stub: %q", stubs[2].PaymentIdentificationLines)
+	}
+
+	// A stub that fits gets no continued line
+	stubs, err = NewStubComposer().AddText("ONE LINE").Pages("CHK001")
+	if err != nil || len(stubs) != 1 || stubs[0].PaymentIdentificationLines[0] != "ONE LINE" {
+		t.Errorf("Expected one stub, got %v, %v", stubs, err)
+	}
+}
+
+func TestCheckObjectLine3(t *testing.T) {
+	if got := CheckObjectLine3("stub      "); got != "Per Enclosed Mailing Notice" {
+		t.Errorf("CheckObjectLine3(stub) = %q", got)
+	}
+	if got := CheckObjectLine3(CheckEnclosureLetter); got != "" {
+		t.Errorf("CheckObjectLine3(letter) = %q, want blank", got)
+	}
+}
@@ -2,6 +2,7 @@ package pamspr
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"unicode"
 )
@@ -252,6 +253,9 @@ func (fb *FileBuilder) AddACHPayment(payment *ACHPayment) *FileBuilder {
 
 	if achSchedule, ok := fb.currentSchedule.(*ACHSchedule); ok {
 		payment.RecordCode = "02"
+		if payment.StandardEntryClassCode == "" && achSchedule.Header != nil {
+			payment.StandardEntryClassCode = achSchedule.Header.StandardEntryClassCode
+		}
 		achSchedule.Payments = append(achSchedule.Payments, payment)
 	} else {
 		fb.errors = append(fb.errors, fmt.Errorf("current schedule is not an ACH schedule"))
@@ -292,6 +296,7 @@ func (fb *FileBuilder) Build() (*File, error) {
 	for _, schedule := range fb.file.Schedules {
 		switch s := schedule.(type) {
 		case *ACHSchedule:
+			SortACHSchedule(s)
 			fb.calculateScheduleTrailer(&s.BaseSchedule, s.Payments)
 			s.Trailer = s.BaseSchedule.Trailer
 		case *CheckSchedule:
@@ -336,7 +341,7 @@ func (fb *FileBuilder) calculateFileTrailer() {
 				totalPayments++
 				if achPay, ok := payment.(*ACHPayment); ok {
 					totalAmount += achPay.Amount
-					totalRecords += int64(len(achPay.Addenda))
+					totalRecords += int64(achPay.AddendaCount())
 					totalRecords += int64(len(achPay.CARSTASBETC))
 					if achPay.DNP != nil {
 						totalRecords++
@@ -369,6 +374,29 @@ func (fb *FileBuilder) calculateFileTrailer() {
 	}
 }
 
+// SortACHSchedule orders a schedule's ACH payments by routing number, as required
+// by Error Reason Group 1 Message 7. The sort is stable, so payments to the same
+// routing number keep their relative order.
+func SortACHSchedule(schedule *ACHSchedule) {
+	if schedule == nil {
+		return
+	}
+	sortByRoutingNumber(schedule.Payments)
+}
+
+func sortByRoutingNumber(payments []Payment) {
+	sort.SliceStable(payments, func(i, j int) bool {
+		return routingNumberOf(payments[i]) < routingNumberOf(payments[j])
+	})
+}
+
+func routingNumberOf(payment Payment) string {
+	if p, ok := payment.(*ACHPayment); ok {
+		return p.RoutingNumber
+	}
+	return ""
+}
+
 // AgencyReconcilementParser provides parsing for agency-specific reconcilement fields
 type AgencyReconcilementParser struct{}
 
@@ -625,6 +625,53 @@ func TestFileBuilder_MultipleSchedules(t *testing.T) {
 	}
 }
 
+// TestSortACHSchedule tests routing number ordering is stable
+func TestSortACHSchedule(t *testing.T) {
+	schedule := &ACHSchedule{
+		BaseSchedule: BaseSchedule{
+			Payments: []Payment{
+				&ACHPayment{PaymentID: "PAY001", RoutingNumber: "122000247"},
+				&ACHPayment{PaymentID: "PAY002", RoutingNumber: "021000021"},
+				&ACHPayment{PaymentID: "PAY003", RoutingNumber: "122000247"},
+				&ACHPayment{PaymentID: "PAY004", RoutingNumber: "011000015"},
+			},
+		},
+	}
+
+	SortACHSchedule(schedule)
+
+	expected := []string{"PAY004", "PAY002", "PAY001", "PAY003"}
+	for i, payment := range schedule.Payments {
+		if payment.GetPaymentID() != expected[i] {
+			t.Errorf("Payment %d = %s, want %s", i, payment.GetPaymentID(), expected[i])
+		}
+	}
+	if err := NewValidator().validateACHPaymentOrder(schedule); err != nil {
+		t.Errorf("Sorted schedule failed order validation: %v", err)
+	}
+
+	SortACHSchedule(nil) // no-op
+}
+
+// TestFileBuilder_BuildSortsACHPayments tests Build orders ACH payments by routing number
+func TestFileBuilder_BuildSortsACHPayments(t *testing.T) {
+	file, err := NewFileBuilder().
+		WithHeader("TEST SYSTEM", "502", false).
+		StartACHSchedule("000000000001", "Vendor", "12345678", "CCD").
+		AddACHPayment(&ACHPayment{PaymentID: "PAY001", RoutingNumber: "122000247", Amount: 100}).
+		AddACHPayment(&ACHPayment{PaymentID: "PAY002", RoutingNumber: "021000021", Amount: 200}).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error building file: %v", err)
+	}
+
+	payments := file.Schedules[0].GetPayments()
+	if payments[0].GetPaymentID() != "PAY002" || payments[1].GetPaymentID() != "PAY001" {
+		t.Errorf("Expected payments in routing number order, got %s, %s",
+			payments[0].GetPaymentID(), payments[1].GetPaymentID())
+	}
+}
+
 // TestAgencyReconcilementParser_ParseIRSReconcilement tests IRS reconcilement parsing
 func TestAgencyReconcilementParser_ParseIRSReconcilement(t *testing.T) {
 	arp := &AgencyReconcilementParser{}
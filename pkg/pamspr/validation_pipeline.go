@@ -0,0 +1,160 @@
+package pamspr
+
+import "sync"
+
+// validationBatchSize is the number of deferred checks handed to a worker at once
+const validationBatchSize = 256
+
+// pendingIssue is a validation result in file order: either an error found
+// while reading or a check for a worker to run
+type pendingIssue struct {
+	scheduleIndex int
+	paymentIndex  int
+	recordCode    string
+	lineNumber    int
+	err           error
+	check         func() []error
+	errs          []error // Result of check
+}
+
+// validationBatch is a run of consecutive pending issues
+type validationBatch struct {
+	issues []pendingIssue
+	result chan []pendingIssue
+}
+
+// validationPipeline runs the reader's record and payment checks on a pool of
+// workers. Batches are merged in the order they were read, so the report and
+// errors match serial validation issue for issue.
+type validationPipeline struct {
+	reader  *Reader
+	batch   []pendingIssue
+	checks  int // Checks in batch
+	jobs    chan validationBatch
+	pending chan chan []pendingIssue // Results in file order
+	workers sync.WaitGroup
+	merged  chan struct{}
+}
+
+func newValidationPipeline(r *Reader, workers int) *validationPipeline {
+	p := &validationPipeline{
+		reader:  r,
+		jobs:    make(chan validationBatch, workers),
+		pending: make(chan chan []pendingIssue, 2*workers),
+		merged:  make(chan struct{}),
+	}
+
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	go p.merge()
+
+	return p
+}
+
+// add queues an issue behind everything read before it
+func (p *validationPipeline) add(issue pendingIssue) {
+	p.batch = append(p.batch, issue)
+	if issue.check != nil {
+		p.checks++
+		if p.checks >= validationBatchSize {
+			p.flush()
+		}
+	}
+}
+
+// flush hands the current batch to the workers
+func (p *validationPipeline) flush() {
+	if len(p.batch) == 0 {
+		return
+	}
+	result := make(chan []pendingIssue, 1)
+	p.pending <- result // Blocks while the merger is 2*workers batches behind
+	p.jobs <- validationBatch{issues: p.batch, result: result}
+	p.batch, p.checks = nil, 0
+}
+
+// close flushes the last batch and waits until every issue is recorded
+func (p *validationPipeline) close() {
+	p.flush()
+	close(p.jobs)
+	close(p.pending)
+	p.workers.Wait()
+	<-p.merged
+}
+
+func (p *validationPipeline) work() {
+	defer p.workers.Done()
+	for job := range p.jobs {
+		for i := range job.issues {
+			if job.issues[i].check != nil {
+				job.issues[i].errs = job.issues[i].check()
+			}
+		}
+		job.result <- job.issues
+	}
+}
+
+// merge records issues in file order; it is the only goroutine that touches
+// the reader's errors and report while the pipeline runs
+func (p *validationPipeline) merge() {
+	defer close(p.merged)
+	for result := range p.pending {
+		for _, issue := range <-result {
+			if issue.check == nil {
+				p.reader.recordIssueAt(issue.err, issue.scheduleIndex, issue.paymentIndex, issue.recordCode, issue.lineNumber)
+				continue
+			}
+			for _, err := range issue.errs {
+				p.reader.recordIssueAt(err, issue.scheduleIndex, issue.paymentIndex, issue.recordCode, issue.lineNumber)
+			}
+		}
+	}
+}
+
+// validateInParallel runs read with the checks it finds spread over
+// ValidationWorkers goroutines, returning once every issue is recorded
+func (r *Reader) validateInParallel(read func() error) error {
+	if !r.config.EnableValidation || r.config.ValidationWorkers <= 1 {
+		return read()
+	}
+
+	r.pipeline = newValidationPipeline(r, r.config.ValidationWorkers)
+	defer func() {
+		r.pipeline.close()
+		r.pipeline = nil
+	}()
+	return read()
+}
+
+// deferCheck runs check at the current line, on a worker when validating in parallel
+func (r *Reader) deferCheck(check func() []error, scheduleIndex, paymentIndex int, recordCode string) {
+	if r.pipeline == nil {
+		for _, err := range check() {
+			r.recordIssue(err, scheduleIndex, paymentIndex, recordCode)
+		}
+		return
+	}
+	r.pipeline.add(pendingIssue{
+		scheduleIndex: scheduleIndex,
+		paymentIndex:  paymentIndex,
+		recordCode:    recordCode,
+		lineNumber:    r.lineNum,
+		check:         check,
+	})
+}
+
+// validatePayment runs the payment and agency rules for a streamed payment
+func (r *Reader) validatePayment(payment Payment, scheduleIndex, paymentIndex int, recordCode string) {
+	r.deferCheck(func() []error {
+		var err error
+		switch p := payment.(type) {
+		case *ACHPayment:
+			err = r.validator.ValidateACHPayment(p)
+		case *CheckPayment:
+			err = r.validator.ValidateCheckPayment(p)
+		}
+		return []error{err, r.validator.ValidateAgencySpecific(payment, r.validator.CustomAgencyRuleID)}
+	}, scheduleIndex, paymentIndex, recordCode)
+}
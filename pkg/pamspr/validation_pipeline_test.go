@@ -0,0 +1,102 @@
+package pamspr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReaderParallelValidation(t *testing.T) {
+	// Damage every 7th payment so issues are spread over many batches
+	lines := strings.Split(strings.TrimSuffix(writeTestFileData(t, 2500), "\n"), "\n")
+	for i := range lines {
+		if strings.HasPrefix(lines[i], "02") && i%7 == 0 {
+			lines[i] = lines[i][:40] + "\t" + lines[i][41:]
+		}
+	}
+	data := strings.Join(lines, "\n") + "\n"
+
+	read := map[string]func(r *Reader) error{
+		"ProcessFile": func(r *Reader) error {
+			return r.ProcessFile(nil, func(Payment, int, int) bool { return true }, nil)
+		},
+		"ProcessPaymentsOnly": func(r *Reader) error {
+			return r.ProcessPaymentsOnly(func(Payment, int, int) bool { return true })
+		},
+		"ReadAll": func(r *Reader) error {
+			_, err := r.ReadAll()
+			return err
+		},
+	}
+
+	for name, run := range read {
+		t.Run(name, func(t *testing.T) {
+			serial := NewReaderWithConfig(strings.NewReader(data), &ReaderConfig{
+				EnableValidation: true,
+				CollectErrors:    true,
+			})
+			if err := run(serial); err != nil {
+				t.Fatalf("serial read failed: %v", err)
+			}
+			want := serial.GetValidationReport().Issues
+			if len(want) < validationBatchSize {
+				t.Fatalf("Expected issues across several batches, got %d", len(want))
+			}
+
+			for _, workers := range []int{2, 4, 16} {
+				parallel := NewReaderWithConfig(strings.NewReader(data), &ReaderConfig{
+					EnableValidation:  true,
+					CollectErrors:     true,
+					ValidationWorkers: workers,
+				})
+				if err := run(parallel); err != nil {
+					t.Fatalf("%d workers: read failed: %v", workers, err)
+				}
+
+				got := parallel.GetValidationReport().Issues
+				if len(got) != len(want) {
+					t.Fatalf("%d workers: expected %d issues, got %d", workers, len(want), len(got))
+				}
+				for i := range want {
+					if got[i].String() != want[i].String() {
+						t.Fatalf("%d workers: issue %d differs:\nwant %s\ngot  %s", workers, i, want[i], got[i])
+					}
+				}
+				if fmt.Sprint(parallel.GetErrors()) != fmt.Sprint(serial.GetErrors()) {
+					t.Errorf("%d workers: errors differ from serial validation", workers)
+				}
+				if parallel.GetStats() != serial.GetStats() {
+					t.Errorf("%d workers: expected stats %+v, got %+v", workers, serial.GetStats(), parallel.GetStats())
+				}
+			}
+		})
+	}
+}
+
+func TestReaderParallelValidationStopsEarly(t *testing.T) {
+	data := writeTestFileData(t, 2000)
+	config := DefaultConfig()
+	config.ValidationWorkers = 4
+
+	reader := NewReaderWithConfig(strings.NewReader(data), config)
+	schedules := 0
+	err := reader.ProcessFile(func(Schedule, int) bool {
+		schedules++
+		return schedules < 2
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	if stats := reader.GetStats(); stats.PaymentsProcessed != 1000 {
+		t.Errorf("Expected processing to stop after the first schedule, got %d payments", stats.PaymentsProcessed)
+	}
+
+	// A failed read still waits for the workers
+	reader = NewReaderWithConfig(strings.NewReader(data[:len(data)/2]), config)
+	if _, err := reader.ReadAll(); err == nil {
+		t.Error("Expected an error for a truncated file")
+	}
+	if reader.pipeline != nil {
+		t.Error("Expected the pipeline to be closed")
+	}
+}
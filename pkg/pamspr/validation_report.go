@@ -0,0 +1,525 @@
+package pamspr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NoIndex marks a ValidationIssue that is not tied to a schedule or payment
+const NoIndex = -1
+
+// ValidationIssue is a single rule violation and where it was found
+type ValidationIssue struct {
+	ScheduleIndex int            `json:"scheduleIndex"`        // Zero-based, NoIndex for file-level issues
+	PaymentIndex  int            `json:"paymentIndex"`         // Zero-based within the schedule, NoIndex if not a payment
+	RecordCode    string         `json:"recordCode,omitempty"` // Record the issue was found on
+	LineNumber    int            `json:"lineNumber,omitempty"` // One-based, 0 if unknown
+	Field         string         `json:"field,omitempty"`
+	Value         string         `json:"value,omitempty"`
+	Rule          string         `json:"rule,omitempty"`
+	Message       string         `json:"message"`
+	Code          ErrorCode      `json:"code"`
+	Rejects       RejectionScope `json:"rejects"`
+
+	// Err is the underlying error as returned by the single-error APIs
+	Err error `json:"-"`
+}
+
+// String formats the issue as a single line of text
+func (i ValidationIssue) String() string {
+	var sb strings.Builder
+
+	if i.LineNumber > 0 {
+		fmt.Fprintf(&sb, "line %d: ", i.LineNumber)
+	}
+	if i.RecordCode != "" {
+		fmt.Fprintf(&sb, "record %q ", i.RecordCode)
+	}
+	if i.ScheduleIndex != NoIndex {
+		fmt.Fprintf(&sb, "schedule[%d] ", i.ScheduleIndex)
+	}
+	if i.PaymentIndex != NoIndex {
+		fmt.Fprintf(&sb, "payment[%d] ", i.PaymentIndex)
+	}
+	if i.Field != "" {
+		fmt.Fprintf(&sb, "%s: ", i.Field)
+	}
+	sb.WriteString(i.Message)
+	if !i.Code.IsZero() {
+		fmt.Fprintf(&sb, " (%s, %s rejected)", i.Code, i.Rejects)
+	}
+
+	return sb.String()
+}
+
+// ValidationReport collects every validation failure found in a file
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+
+	// MaxIssues limits how many issues are kept (0 = unlimited)
+	MaxIssues int `json:"-"`
+	// Dropped counts issues discarded after MaxIssues was reached
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// NewValidationReport creates an empty report
+func NewValidationReport() *ValidationReport {
+	return &ValidationReport{
+		Issues: make([]ValidationIssue, 0),
+	}
+}
+
+// Add appends an issue to the report
+func (r *ValidationReport) Add(issue ValidationIssue) {
+	if r.MaxIssues > 0 && len(r.Issues) >= r.MaxIssues {
+		r.Dropped++
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// AddError records err at the given location, copying the details of a ValidationError
+func (r *ValidationReport) AddError(err error, scheduleIndex, paymentIndex int, recordCode string, lineNumber int) {
+	if err == nil {
+		return
+	}
+
+	issue := ValidationIssue{
+		ScheduleIndex: scheduleIndex,
+		PaymentIndex:  paymentIndex,
+		RecordCode:    recordCode,
+		LineNumber:    lineNumber,
+		Message:       err.Error(),
+		Err:           err,
+	}
+
+	var ve ValidationError
+	if errors.As(err, &ve) {
+		issue.Field = ve.Field
+		issue.Value = ve.Value
+		issue.Rule = ve.Rule
+		issue.Message = ve.Message
+		issue.Code = ve.Code
+		issue.Rejects = ve.Rejection()
+	}
+
+	r.Add(issue)
+}
+
+// HasIssues reports whether any issue was recorded
+func (r *ValidationReport) HasIssues() bool {
+	return len(r.Issues) > 0 || r.Dropped > 0
+}
+
+// FirstError returns the error behind the first issue, or nil if the report is empty
+func (r *ValidationReport) FirstError() error {
+	if len(r.Issues) == 0 {
+		return nil
+	}
+	if r.Issues[0].Err != nil {
+		return r.Issues[0].Err
+	}
+	return errors.New(r.Issues[0].Message)
+}
+
+// Err returns the report as an error, or nil if there are no issues
+func (r *ValidationReport) Err() error {
+	if !r.HasIssues() {
+		return nil
+	}
+	return r
+}
+
+// Error summarizes the report so it can be returned as an error
+func (r *ValidationReport) Error() string {
+	total := len(r.Issues) + r.Dropped
+	if total == 0 {
+		return "no validation errors"
+	}
+	if total == 1 && len(r.Issues) == 1 {
+		return r.Issues[0].String()
+	}
+	return fmt.Sprintf("%d validation errors, first: %s", total, r.Issues[0].String())
+}
+
+// WriteText writes one line per issue followed by a summary line
+func (r *ValidationReport) WriteText(w io.Writer) error {
+	for _, issue := range r.Issues {
+		if _, err := fmt.Fprintln(w, issue.String()); err != nil {
+			return err
+		}
+	}
+	if r.Dropped > 0 {
+		if _, err := fmt.Fprintf(w, "... %d more issues not shown\n", r.Dropped); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d validation errors\n", len(r.Issues)+r.Dropped)
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *ValidationReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// ValidateFile runs the structure, balancing, Same Day ACH and payment rules
+// and returns every failure instead of stopping at the first one. Line numbers
+// are the positions the records occupy when the file is written by Writer; use
+// ValidateFileLines for a file read by Reader.
+func (v *Validator) ValidateFile(file *File) *ValidationReport {
+	return v.ValidateFileLines(file, nil)
+}
+
+// ValidateFileLines is ValidateFile reporting the lines records were read
+// from, as returned by Reader.RecordLines. Records missing from lines, such
+// as ones added after reading, are reported without a line number.
+func (v *Validator) ValidateFileLines(file *File, source RecordLines) *ValidationReport {
+	report := NewValidationReport()
+	lines := newRecordLocator(file, source)
+
+	if file.Header == nil {
+		report.AddError(NewFieldRequiredError("FileHeader").WithCode(CodeRecordMissingOrOutOfOrder),
+			NoIndex, NoIndex, string(RecordTypeFileHeader), 0)
+	} else {
+		report.AddError(v.ValidateFileHeader(file.Header), NoIndex, NoIndex, string(RecordTypeFileHeader), lines.fileHeader)
+	}
+
+	fileBalance := FileBalanceInfo{
+		TotalRecords: 2, // Header + Trailer
+	}
+
+	for i, schedule := range file.Schedules {
+		var balance ScheduleBalanceInfo
+		var scheduleType, headerCode string
+
+		switch s := schedule.(type) {
+		case *ACHSchedule:
+			v.collectACHSchedule(report, s, i, lines)
+			balance = v.calculateACHScheduleBalance(s)
+			scheduleType, headerCode = "ACH", string(RecordTypeACHScheduleHeader)
+		case *CheckSchedule:
+			v.collectCheckSchedule(report, s, i, lines)
+			balance = v.calculateCheckScheduleBalance(s)
+			scheduleType, headerCode = "Check", string(RecordTypeCheckScheduleHeader)
+		default:
+			report.AddError(v.validateSingleScheduleConsistency(schedule, i), i, NoIndex, "", lines.schedule(i))
+			continue
+		}
+
+		report.AddError(v.ValidateAgencySchedule(schedule, v.CustomAgencyRuleID), i, NoIndex, headerCode, lines.schedule(i))
+
+		for _, err := range v.scheduleTrailerErrors(schedule.GetTrailer(), balance, scheduleType) {
+			report.AddError(err, i, NoIndex, string(RecordTypeScheduleTrailer), lines.scheduleTrailer(i))
+		}
+		if s, ok := schedule.(*ACHSchedule); ok {
+			report.AddError(v.ValidatePrenoteSchedule(s), i, NoIndex, string(RecordTypeScheduleTrailer), lines.scheduleTrailer(i))
+		}
+
+		fileBalance.TotalRecords += balance.Records
+		fileBalance.TotalPayments += balance.Payments
+		fileBalance.TotalAmount += balance.Amount
+	}
+
+	for _, err := range v.fileTrailerErrors(file.Trailer, fileBalance) {
+		report.AddError(err, NoIndex, NoIndex, string(RecordTypeFileTrailer), lines.fileTrailer)
+	}
+
+	if file.Header != nil && file.Header.IsRequestedForSameDayACH == SDAFlagEnabled {
+		v.collectSameDayACH(report, file, lines)
+	}
+
+	report.AddError(v.ValidateAgencyFile(file, v.CustomAgencyRuleID), NoIndex, NoIndex, "", 0)
+
+	return report
+}
+
+// collectACHSchedule records payment, ordering and CTX issues for an ACH schedule
+func (v *Validator) collectACHSchedule(report *ValidationReport, schedule *ACHSchedule, index int, lines *recordLocator) {
+	var lastRTN string
+	ids := make(map[string]int)
+	for j, payment := range schedule.Payments {
+		line := lines.payment(index, j)
+
+		achPayment, ok := payment.(*ACHPayment)
+		if !ok {
+			report.AddError(ValidationError{
+				Field:   fmt.Sprintf("Schedule[%d]", index),
+				Rule:    "payment_type_consistency",
+				Message: "ACH schedule cannot contain non-ACH payments",
+				Code:    CodeInvalidRecord,
+			}, index, j, payment.GetRecordCode(), line)
+			continue
+		}
+
+		recordCode := string(RecordTypeACHPayment)
+		report.AddError(v.ValidateACHPayment(achPayment), index, j, recordCode, line)
+		report.AddError(v.ValidateACHAddenda(achPayment), index, j, recordCode, line)
+		report.AddError(v.ValidateCTXAddendum(achPayment), index, j, recordCode, line)
+		report.AddError(v.ValidateAgencySpecific(achPayment, v.CustomAgencyRuleID), index, j, recordCode, line)
+		report.AddError(checkPaymentIDUnique(ids, achPayment.PaymentID, j), index, j, recordCode, line)
+		v.collectCARS(report, achPayment, index, j, line, lines)
+
+		if j > 0 {
+			report.AddError(checkRoutingNumberOrder(lastRTN, achPayment.RoutingNumber), index, j, recordCode, line)
+		}
+		lastRTN = achPayment.RoutingNumber
+	}
+}
+
+// collectCheckSchedule records payment issues for a check schedule
+func (v *Validator) collectCheckSchedule(report *ValidationReport, schedule *CheckSchedule, index int, lines *recordLocator) {
+	ids := make(map[string]int)
+	for j, payment := range schedule.Payments {
+		line := lines.payment(index, j)
+
+		checkPayment, ok := payment.(*CheckPayment)
+		if !ok {
+			report.AddError(ValidationError{
+				Field:   fmt.Sprintf("Schedule[%d]", index),
+				Rule:    "payment_type_consistency",
+				Message: "Check schedule cannot contain non-Check payments",
+				Code:    CodeInvalidRecord,
+			}, index, j, payment.GetRecordCode(), line)
+			continue
+		}
+
+		recordCode := string(RecordTypeCheckPayment)
+		report.AddError(v.ValidateCheckPayment(checkPayment), index, j, recordCode, line)
+		report.AddError(v.ValidateAgencySpecific(checkPayment, v.CustomAgencyRuleID), index, j, recordCode, line)
+		report.AddError(checkPaymentIDUnique(ids, checkPayment.PaymentID, j), index, j, recordCode, line)
+		v.collectCARS(report, checkPayment, index, j, line, lines)
+	}
+}
+
+// collectCARS records the CARS TAS/BETC issues of the payment at line, each
+// on the line of its "G " record and the balance on the payment. Format and
+// balance problems are only reported with CheckCARSAccounting.
+func (v *Validator) collectCARS(report *ValidationReport, payment Payment, index, j, line int, lines *recordLocator) {
+	records := carsRecords(payment)
+	if len(records) == 0 {
+		return
+	}
+
+	paymentID := strings.TrimSpace(payment.GetPaymentID())
+	for k, car := range records {
+		for _, err := range carsRecordErrors(car, k, paymentID, v.CheckCARSAccounting) {
+			report.AddError(err, index, j, string(RecordTypeCARSTASBETC), lines.cars(index, j, k))
+		}
+	}
+	if v.CheckCARSAccounting {
+		report.AddError(carsBalanceError(payment), index, j, payment.GetRecordCode(), line)
+	}
+}
+
+// checkPaymentIDUnique reports a PaymentID used by an earlier payment of the
+// schedule; ids maps the IDs seen so far to their payment index
+func checkPaymentIDUnique(ids map[string]int, paymentID string, index int) error {
+	id := strings.TrimSpace(paymentID)
+	if id == "" {
+		return nil // Reported as required
+	}
+	if first, ok := ids[id]; ok {
+		return ValidationError{
+			Field:   "PaymentID",
+			Value:   id,
+			Rule:    "unique_in_schedule",
+			Message: fmt.Sprintf("payment ID is also used by payment %d of the schedule", first),
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
+		}
+	}
+	ids[id] = index
+	return nil
+}
+
+// collectSameDayACH records every Same Day ACH violation in the file
+func (v *Validator) collectSameDayACH(report *ValidationReport, file *File, lines *recordLocator) {
+	// Rule: Can only contain ACH schedules
+	for i, schedule := range file.Schedules {
+		if _, ok := schedule.(*ACHSchedule); !ok {
+			report.AddError(ValidationError{
+				Rule:    "sda_ach_only",
+				Message: "Same Day ACH files can only contain ACH schedules",
+				Code:    CodeSDANonACHSchedule,
+			}, i, NoIndex, string(RecordTypeCheckScheduleHeader), lines.schedule(i))
+		}
+	}
+
+	// Rule: Individual payments must be <= $1,000,000
+	maxSDAAmount := int64(MaxSDAAmountCents)
+	for i, schedule := range file.Schedules {
+		if achSchedule, ok := schedule.(*ACHSchedule); ok {
+			for j, payment := range achSchedule.Payments {
+				if achPayment, ok := payment.(*ACHPayment); ok && achPayment.Amount > maxSDAAmount {
+					report.AddError(ValidationError{
+						Field:   "Amount",
+						Value:   fmt.Sprintf("%d", achPayment.Amount),
+						Rule:    "sda_max_amount",
+						Message: fmt.Sprintf("payment amount exceeds Same Day ACH limit of $%d", maxSDAAmount/100),
+						Code:    CodeSDAAmountExceeded,
+					}, i, j, string(RecordTypeACHPayment), lines.payment(i, j))
+				}
+			}
+		}
+	}
+
+	// Rule: SEC code cannot be IAT
+	for i, schedule := range file.Schedules {
+		if achSchedule, ok := schedule.(*ACHSchedule); ok && achSchedule.Header != nil {
+			if achSchedule.Header.StandardEntryClassCode == "IAT" {
+				report.AddError(ValidationError{
+					Field:   "StandardEntryClassCode",
+					Value:   "IAT",
+					Rule:    "sda_no_iat",
+					Message: "IAT payments are not allowed for Same Day ACH",
+					Code:    CodeSDAIATNotAllowed,
+				}, i, NoIndex, string(RecordTypeACHScheduleHeader), lines.schedule(i))
+			}
+		}
+	}
+}
+
+// RecordLines maps the records of a File to the lines Reader read them from.
+// Keys are the *FileHeader, each Schedule, *ACHPayment, *CheckPayment,
+// *CARSTASBETC, *ScheduleTrailer and the *FileTrailer.
+type RecordLines map[interface{}]int
+
+// recordLocator maps schedules and payments to their lines: the source lines
+// when known, otherwise the lines they occupy when written
+type recordLocator struct {
+	fileHeader    int
+	scheduleLines []int
+	paymentLines  [][]int
+	carsLines     [][][]int
+	trailerLines  []int
+	fileTrailer   int
+}
+
+func newRecordLocator(file *File, source RecordLines) *recordLocator {
+	l := &recordLocator{
+		scheduleLines: make([]int, len(file.Schedules)),
+		paymentLines:  make([][]int, len(file.Schedules)),
+		carsLines:     make([][][]int, len(file.Schedules)),
+		trailerLines:  make([]int, len(file.Schedules)),
+	}
+	if source != nil {
+		l.fromSource(file, source)
+		return l
+	}
+
+	l.fileHeader = 1
+	line := 1
+	for i, schedule := range file.Schedules {
+		line++
+		l.scheduleLines[i] = line
+
+		payments := schedule.GetPayments()
+		l.paymentLines[i] = make([]int, len(payments))
+		l.carsLines[i] = make([][]int, len(payments))
+		for j, payment := range payments {
+			line++
+			l.paymentLines[i][j] = line
+
+			// CARS records follow the addenda or stub
+			first := line + 1
+			switch p := payment.(type) {
+			case *ACHPayment:
+				first += p.AddendaCount()
+			case *CheckPayment:
+				if p.Stub != nil {
+					first++
+				}
+			}
+			cars := carsRecords(payment)
+			l.carsLines[i][j] = make([]int, len(cars))
+			for k := range cars {
+				l.carsLines[i][j][k] = first + k
+			}
+
+			line += associatedRecordCount(payment)
+		}
+
+		line++
+		l.trailerLines[i] = line
+	}
+	l.fileTrailer = line + 1
+
+	return l
+}
+
+// fromSource looks every record up in the lines it was read from
+func (l *recordLocator) fromSource(file *File, source RecordLines) {
+	l.fileHeader = source[file.Header]
+	for i, schedule := range file.Schedules {
+		l.scheduleLines[i] = source[schedule]
+		l.trailerLines[i] = source[schedule.GetTrailer()]
+
+		payments := schedule.GetPayments()
+		l.paymentLines[i] = make([]int, len(payments))
+		l.carsLines[i] = make([][]int, len(payments))
+		for j, payment := range payments {
+			l.paymentLines[i][j] = source[payment]
+			cars := carsRecords(payment)
+			l.carsLines[i][j] = make([]int, len(cars))
+			for k, car := range cars {
+				l.carsLines[i][j][k] = source[car]
+			}
+		}
+	}
+	l.fileTrailer = source[file.Trailer]
+}
+
+// associatedRecordCount returns the number of records written after a payment record
+func associatedRecordCount(payment Payment) int {
+	count := 0
+	switch p := payment.(type) {
+	case *ACHPayment:
+		count += p.AddendaCount() + len(p.CARSTASBETC)
+		if p.DNP != nil {
+			count++
+		}
+	case *CheckPayment:
+		count += len(p.CARSTASBETC)
+		if p.Stub != nil {
+			count++
+		}
+		if p.DNP != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func (l *recordLocator) schedule(i int) int {
+	if l == nil || i < 0 || i >= len(l.scheduleLines) {
+		return 0
+	}
+	return l.scheduleLines[i]
+}
+
+func (l *recordLocator) scheduleTrailer(i int) int {
+	if l == nil || i < 0 || i >= len(l.trailerLines) {
+		return 0
+	}
+	return l.trailerLines[i]
+}
+
+func (l *recordLocator) payment(i, j int) int {
+	if l == nil || i < 0 || i >= len(l.paymentLines) || j < 0 || j >= len(l.paymentLines[i]) {
+		return 0
+	}
+	return l.paymentLines[i][j]
+}
+
+func (l *recordLocator) cars(i, j, k int) int {
+	if l == nil || i < 0 || i >= len(l.carsLines) || j < 0 || j >= len(l.carsLines[i]) || k < 0 || k >= len(l.carsLines[i][j]) {
+		return 0
+	}
+	return l.carsLines[i][j][k]
+}
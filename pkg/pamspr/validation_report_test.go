@@ -0,0 +1,293 @@
+package pamspr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateFileCollectsAllIssues(t *testing.T) {
+	validator := NewValidator()
+
+	file := createTestACHFile()
+	schedule := file.Schedules[0].(*ACHSchedule)
+	schedule.Payments[0].(*ACHPayment).PayeeName = ""
+	schedule.Trailer.ScheduleAmount = 1
+	file.Trailer.TotalCountPayments = 5
+
+	report := validator.ValidateFile(file)
+	if !report.HasIssues() {
+		t.Fatal("Expected issues in report")
+	}
+
+	expected := []struct {
+		scheduleIndex int
+		paymentIndex  int
+		recordCode    string
+		lineNumber    int
+		code          ErrorCode
+	}{
+		{0, 0, "02", 3, CodeInvalidPaymentData},              // Blank payee name
+		{0, 1, "02", 4, CodeInvalidPaymentData},              // Transaction code 27
+		{0, NoIndex, "T ", 5, CodeACHScheduleAmountMismatch}, // Schedule amount
+		{NoIndex, NoIndex, "E ", 6, CodeFileCountMismatch},   // File payment count
+	}
+
+	if len(report.Issues) != len(expected) {
+		for _, issue := range report.Issues {
+			t.Log(issue.String())
+		}
+		t.Fatalf("Expected %d issues, got %d", len(expected), len(report.Issues))
+	}
+
+	for i, want := range expected {
+		got := report.Issues[i]
+		if got.ScheduleIndex != want.scheduleIndex || got.PaymentIndex != want.paymentIndex {
+			t.Errorf("Issue %d: expected schedule %d payment %d, got %d/%d",
+				i, want.scheduleIndex, want.paymentIndex, got.ScheduleIndex, got.PaymentIndex)
+		}
+		if got.RecordCode != want.recordCode {
+			t.Errorf("Issue %d: expected record code %q, got %q", i, want.recordCode, got.RecordCode)
+		}
+		if got.LineNumber != want.lineNumber {
+			t.Errorf("Issue %d: expected line %d, got %d", i, want.lineNumber, got.LineNumber)
+		}
+		if got.Code != want.code {
+			t.Errorf("Issue %d: expected code %s, got %s", i, want.code, got.Code)
+		}
+	}
+}
+
+func TestValidateFileValid(t *testing.T) {
+	file := createTestCheckFile()
+
+	report := NewValidator().ValidateFile(file)
+	if report.HasIssues() {
+		t.Errorf("Unexpected issues: %v", report)
+	}
+	if report.Err() != nil {
+		t.Errorf("Expected nil Err() for empty report, got %v", report.Err())
+	}
+}
+
+func TestValidateFileDuplicatePaymentID(t *testing.T) {
+	file := createTestCheckFile()
+	schedule := file.Schedules[0].(*CheckSchedule)
+	second := *schedule.Payments[0].(*CheckPayment)
+	second.PaymentID = " CHK001"
+	second.Stub = nil
+	schedule.Payments = append(schedule.Payments, &second)
+
+	report := NewValidator().ValidateFile(file)
+	var found []ValidationIssue
+	for _, issue := range report.Issues {
+		if issue.Rule == "unique_in_schedule" {
+			found = append(found, issue)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("Expected one duplicate payment ID issue, got %v", report.Issues)
+	}
+	if got := found[0]; got.ScheduleIndex != 0 || got.PaymentIndex != 1 || got.Code != CodeInvalidRecord || got.Rejects != RejectionSchedule {
+		t.Errorf("Unexpected issue %s", got)
+	}
+}
+
+func TestValidateFileSameDayACH(t *testing.T) {
+	file := createTestACHFile()
+	file.Header.IsRequestedForSameDayACH = SDAFlagEnabled
+	schedule := file.Schedules[0].(*ACHSchedule)
+	schedule.Header.StandardEntryClassCode = "IAT"
+	for _, payment := range schedule.Payments {
+		payment.SetAmount(MaxSDAAmountCents + 1)
+	}
+
+	report := NewValidator().ValidateFile(file)
+
+	var amountIssues, iatIssues int
+	for _, issue := range report.Issues {
+		switch issue.Code {
+		case CodeSDAAmountExceeded:
+			amountIssues++
+		case CodeSDAIATNotAllowed:
+			iatIssues++
+		}
+	}
+	if amountIssues != 2 {
+		t.Errorf("Expected 2 Same Day ACH amount issues, got %d", amountIssues)
+	}
+	if iatIssues != 1 {
+		t.Errorf("Expected 1 Same Day ACH IAT issue, got %d", iatIssues)
+	}
+
+	// The single-error API still reports the first violation
+	err := NewValidator().ValidateSameDayACH(file)
+	if ve, ok := err.(ValidationError); !ok || ve.Code != CodeSDAAmountExceeded {
+		t.Errorf("Expected amount violation from ValidateSameDayACH, got %v", err)
+	}
+}
+
+func TestValidationReportOutput(t *testing.T) {
+	report := NewValidationReport()
+	report.AddError(ValidationError{
+		Field:   "PaymentID",
+		Rule:    "required",
+		Message: "payment ID is required",
+		Code:    CodeInvalidRecord,
+		Scope:   RejectionSchedule,
+	}, 1, 2, "12", 9)
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	expectedLine := `line 9: record "12" schedule[1] payment[2] PaymentID: payment ID is required (Error Reason Group 1 Message 6, schedule rejected)`
+	if !strings.Contains(text.String(), expectedLine) {
+		t.Errorf("Expected text report to contain %q, got:\n%s", expectedLine, text.String())
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded ValidationReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON report: %v", err)
+	}
+	if len(decoded.Issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %d", len(decoded.Issues))
+	}
+	issue := decoded.Issues[0]
+	if issue.Code != CodeInvalidRecord || issue.Rejects != RejectionSchedule || issue.LineNumber != 9 || issue.PaymentIndex != 2 {
+		t.Errorf("Unexpected decoded issue: %+v", issue)
+	}
+	if !strings.Contains(out.String(), `"rejects": "schedule"`) {
+		t.Errorf("Expected rejection scope by name in JSON, got:\n%s", out.String())
+	}
+}
+
+func TestValidationReportMaxIssues(t *testing.T) {
+	report := NewValidationReport()
+	report.MaxIssues = 2
+
+	for i := 0; i < 5; i++ {
+		report.AddError(NewFieldRequiredError("PayeeName"), 0, i, "02", i+3)
+	}
+
+	if len(report.Issues) != 2 || report.Dropped != 3 {
+		t.Errorf("Expected 2 kept and 3 dropped, got %d and %d", len(report.Issues), report.Dropped)
+	}
+	if !strings.HasPrefix(report.Error(), "5 validation errors") {
+		t.Errorf("Unexpected summary: %s", report.Error())
+	}
+}
+
+func TestReaderProcessFileReport(t *testing.T) {
+	reader := NewReader(strings.NewReader(createTestFileContent()))
+
+	if err := reader.ProcessFile(nil, nil, nil); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	report := reader.GetValidationReport()
+	if len(report.Issues) != 2 {
+		for _, issue := range report.Issues {
+			t.Log(issue.String())
+		}
+		t.Fatalf("Expected 2 issues, got %d", len(report.Issues))
+	}
+
+	payment := report.Issues[0]
+	if payment.LineNumber != 4 || payment.ScheduleIndex != 0 || payment.PaymentIndex != 1 || payment.Field != "ACH_TransactionCode" {
+		t.Errorf("Unexpected payment issue: %s", payment)
+	}
+
+	trailer := report.Issues[1]
+	if trailer.LineNumber != 6 || trailer.RecordCode != "E " || trailer.Code != CodeFileCountMismatch {
+		t.Errorf("Unexpected trailer issue: %s", trailer)
+	}
+
+	if len(reader.GetErrors()) != 2 {
+		t.Errorf("Expected report issues in GetErrors, got %d", len(reader.GetErrors()))
+	}
+}
+
+func TestReaderProcessFileRoutingOrder(t *testing.T) {
+	file := createTestACHFile()
+	schedule := file.Schedules[0].(*ACHSchedule)
+	schedule.Payments[0], schedule.Payments[1] = schedule.Payments[1], schedule.Payments[0]
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	reader := NewReader(strings.NewReader(buf.String()))
+	if err := reader.ProcessFile(nil, nil, nil); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	var streamed []ValidationIssue
+	for _, issue := range reader.GetValidationReport().Issues {
+		if issue.Code == CodeRoutingNumberOrder {
+			streamed = append(streamed, issue)
+		}
+	}
+	if len(streamed) != 1 {
+		t.Fatalf("Expected one routing order issue, got %d", len(streamed))
+	}
+	if got := streamed[0]; got.LineNumber != 4 || got.ScheduleIndex != 0 || got.PaymentIndex != 1 || got.Value != "021000021" {
+		t.Errorf("Unexpected routing order issue: %s", got)
+	}
+
+	// The in-memory report flags the same payment
+	var inMemory []ValidationIssue
+	for _, issue := range NewValidator().ValidateFile(file).Issues {
+		if issue.Code == CodeRoutingNumberOrder {
+			inMemory = append(inMemory, issue)
+		}
+	}
+	if len(inMemory) != 1 || inMemory[0].LineNumber != streamed[0].LineNumber {
+		t.Errorf("Expected ValidateFile to report the same issue, got %+v", inMemory)
+	}
+}
+
+func TestValidateFileLinesFromReader(t *testing.T) {
+	file := createTestMixedFile()
+	file.Schedules[0].(*ACHSchedule).Payments[1].(*ACHPayment).PayeeName = ""
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// H, 01, 02, 02, T, 11, 12, 13, T, E; drop the first payment on line 3
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	lines[2] = lines[2][:400]
+
+	config := DefaultConfig()
+	config.RecoveryMode = true
+	reader := NewReaderWithConfig(strings.NewReader(strings.Join(lines, "\n")), config)
+	read, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	// Written out again the payment would be on line 3; the report points at the source
+	found := false
+	for _, issue := range NewValidator().ValidateFileLines(read, reader.RecordLines()).Issues {
+		if issue.LineNumber == 0 {
+			t.Errorf("Issue without a line number: %s", issue)
+		}
+		if issue.Field == "PayeeName" {
+			found = true
+			if issue.LineNumber != 4 || issue.PaymentIndex != 0 {
+				t.Errorf("Expected the payee name issue on line 4, got %s", issue)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected a payee name issue")
+	}
+}
@@ -1,6 +1,7 @@
 package pamspr
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -13,11 +14,43 @@ type ValidationError struct {
 	Value   string
 	Rule    string
 	Message string
+
+	// Code is the Treasury error reason for the rule (zero if the spec defines none)
+	Code ErrorCode
+	// Scope overrides the catalog's default rejection scope for Code when set
+	Scope RejectionScope
 }
 
 func (e ValidationError) Error() string {
-	return fmt.Sprintf("validation error: field=%s, value=%s, rule=%s, message=%s",
+	msg := fmt.Sprintf("validation error: field=%s, value=%s, rule=%s, message=%s",
 		e.Field, e.Value, e.Rule, e.Message)
+	if !e.Code.IsZero() {
+		msg += fmt.Sprintf(" (%s, %s rejected)", e.Code, e.Rejection())
+	}
+	return msg
+}
+
+// Rejection reports whether PAM rejects the payment, the schedule or the whole file
+func (e ValidationError) Rejection() RejectionScope {
+	if e.Scope != RejectionUnknown {
+		return e.Scope
+	}
+	if entry, ok := ErrorCatalog[e.Code]; ok {
+		return entry.Scope
+	}
+	return RejectionUnknown
+}
+
+// WithCode returns a copy of the error tagged with a Treasury error code
+func (e ValidationError) WithCode(code ErrorCode) ValidationError {
+	e.Code = code
+	return e
+}
+
+// WithScope returns a copy of the error with an explicit rejection scope
+func (e ValidationError) WithScope(scope RejectionScope) ValidationError {
+	e.Scope = scope
+	return e
 }
 
 // NewValidationError creates a ValidationError with consistent formatting
@@ -70,12 +103,26 @@ func WrapValidationError(field, value, rule string, err error) ValidationError {
 	}
 }
 
+// withDefaultCode tags a ValidationError that has no Treasury code yet
+func withDefaultCode(err error, code ErrorCode) error {
+	var ve ValidationError
+	if errors.As(err, &ve) && ve.Code.IsZero() {
+		return ve.WithCode(code)
+	}
+	return err
+}
+
 // Validator provides validation methods for PAM SPR records
 type Validator struct {
 	// Configuration
 	AllowedPaymentTypes map[string]bool
 	ValidALCs           map[string]bool
-	CustomAgencyRuleID  string // Agency-specific rule ID (e.g., "SSA-A", "SSA-Daily")
+	CustomAgencyRuleID  string        // Agency-specific rule ID (e.g., "SSA-A", "SSA-Daily")
+	Rules               *RuleRegistry // Agency rule sets by Custom Agency Rule ID (nil for the built-in rules)
+	Encoding            Encoding      // Code page for the hexadecimal character rule
+	// CheckCARSAccounting adds the CARS TAS/BETC format and balance checks to
+	// ValidateFile. PAM passes TAS/BETC values on to CARS without rejecting them.
+	CheckCARSAccounting bool
 }
 
 // NewValidator creates a new validator with default configuration
@@ -98,6 +145,7 @@ func NewValidator() *Validator {
 			"Vendor":          true,
 		},
 		ValidALCs: make(map[string]bool), // Populated from agency configuration
+		Rules:     DefaultRuleRegistry(),
 	}
 }
 
@@ -113,6 +161,7 @@ func (v *Validator) ValidateFileHeader(header *FileHeader) error {
 			Value:   header.RecordCode,
 			Rule:    "exact_match",
 			Message: "must be 'H '",
+			Code:    CodeInvalidRecord,
 		}
 	}
 
@@ -123,6 +172,7 @@ func (v *Validator) ValidateFileHeader(header *FileHeader) error {
 			Value:   header.StandardPaymentVersion,
 			Rule:    "version",
 			Message: fmt.Sprintf("current published version must be '%s'", CurrentSPRVersion),
+			Code:    CodeInvalidRecord,
 		}
 	}
 
@@ -133,6 +183,7 @@ func (v *Validator) ValidateFileHeader(header *FileHeader) error {
 			Value:   header.IsRequestedForSameDayACH,
 			Rule:    "valid_values",
 			Message: fmt.Sprintf("must be '%s', '%s', or blank", SDAFlagDisabled, SDAFlagEnabled),
+			Code:    CodeInvalidRecord,
 		}
 	}
 
@@ -148,6 +199,7 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 			Value:   fmt.Sprintf("%d", payment.Amount),
 			Rule:    "positive",
 			Message: "amount cannot be negative",
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
@@ -157,12 +209,13 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 			Field:   "PayeeName",
 			Rule:    "required",
 			Message: "payee name is required",
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
 	// Routing number validation (9 digits, valid checksum)
 	if err := v.validateRoutingNumber(payment.RoutingNumber); err != nil {
-		return WrapValidationError("RoutingNumber", payment.RoutingNumber, "routing_number", err)
+		return WrapValidationError("RoutingNumber", payment.RoutingNumber, "routing_number", err).WithCode(CodeInvalidPaymentData)
 	}
 
 	// Account number validation
@@ -172,6 +225,7 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 			Value:   payment.AccountNumber,
 			Rule:    "required",
 			Message: "account number cannot be blank or all zeros",
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
@@ -182,8 +236,12 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 			Value:   payment.ACH_TransactionCode,
 			Rule:    "valid_values",
 			Message: "invalid ACH transaction code",
+			Code:    CodeInvalidPaymentData,
 		}
 	}
+	if err := v.validateTransactionCodeAmount(payment); err != nil {
+		return err
+	}
 
 	// TIN validation
 	if payment.TIN != "" && !v.isValidTIN(payment.TIN) {
@@ -192,6 +250,7 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 			Value:   payment.TIN,
 			Rule:    "tin_format",
 			Message: fmt.Sprintf("TIN must be %d numeric digits", TINLength),
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
@@ -201,6 +260,8 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 			Field:   "PaymentID",
 			Rule:    "required",
 			Message: "payment ID is required",
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
 		}
 	}
 
@@ -211,6 +272,7 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 				Field:   "PayeeAddressLine1",
 				Rule:    "required_for_iat",
 				Message: "address line 1 is required for IAT payments",
+				Code:    CodeInvalidPaymentData,
 			}
 		}
 		if strings.TrimSpace(payment.CityName) == "" {
@@ -218,6 +280,7 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 				Field:   "CityName",
 				Rule:    "required_for_iat",
 				Message: "city name is required for IAT payments",
+				Code:    CodeInvalidPaymentData,
 			}
 		}
 		if strings.TrimSpace(payment.CountryCodeText) == "" || payment.CountryCodeText == CountryCodeEmpty {
@@ -225,6 +288,7 @@ func (v *Validator) ValidateACHPayment(payment *ACHPayment) error {
 				Field:   "CountryCodeText",
 				Rule:    "required_for_iat",
 				Message: "valid country code is required for IAT payments",
+				Code:    CodeInvalidPaymentData,
 			}
 		}
 	}
@@ -241,6 +305,7 @@ func (v *Validator) ValidateCheckPayment(payment *CheckPayment) error {
 			Value:   fmt.Sprintf("%d", payment.Amount),
 			Rule:    "positive_non_zero",
 			Message: "check amount must be greater than zero",
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
@@ -250,6 +315,7 @@ func (v *Validator) ValidateCheckPayment(payment *CheckPayment) error {
 			Field:   "PayeeName",
 			Rule:    "required",
 			Message: "payee name is required",
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
@@ -266,6 +332,7 @@ func (v *Validator) ValidateCheckPayment(payment *CheckPayment) error {
 			Value:   payment.TIN,
 			Rule:    "tin_format",
 			Message: fmt.Sprintf("TIN must be %d numeric digits", TINLength),
+			Code:    CodeInvalidPaymentData,
 		}
 	}
 
@@ -275,6 +342,8 @@ func (v *Validator) ValidateCheckPayment(payment *CheckPayment) error {
 			Field:   "PaymentID",
 			Rule:    "required",
 			Message: "payment ID is required",
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
 		}
 	}
 
@@ -287,50 +356,9 @@ func (v *Validator) ValidateSameDayACH(file *File) error {
 		return nil // Not a Same Day ACH file
 	}
 
-	// Rule: Can only contain ACH schedules
-	for _, schedule := range file.Schedules {
-		if _, ok := schedule.(*ACHSchedule); !ok {
-			return ValidationError{
-				Rule:    "sda_ach_only",
-				Message: "Same Day ACH files can only contain ACH schedules",
-			}
-		}
-	}
-
-	// Rule: Individual payments must be <= $1,000,000
-	maxSDAAmount := int64(MaxSDAAmountCents)
-	for _, schedule := range file.Schedules {
-		if achSchedule, ok := schedule.(*ACHSchedule); ok {
-			for _, payment := range achSchedule.Payments {
-				if achPayment, ok := payment.(*ACHPayment); ok {
-					if achPayment.Amount > maxSDAAmount {
-						return ValidationError{
-							Field:   "Amount",
-							Value:   fmt.Sprintf("%d", achPayment.Amount),
-							Rule:    "sda_max_amount",
-							Message: fmt.Sprintf("payment amount exceeds Same Day ACH limit of $%d", maxSDAAmount/100),
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Rule: SEC code cannot be IAT
-	for _, schedule := range file.Schedules {
-		if achSchedule, ok := schedule.(*ACHSchedule); ok {
-			if achSchedule.Header.StandardEntryClassCode == "IAT" {
-				return ValidationError{
-					Field:   "StandardEntryClassCode",
-					Value:   "IAT",
-					Rule:    "sda_no_iat",
-					Message: "IAT payments are not allowed for Same Day ACH",
-				}
-			}
-		}
-	}
-
-	return nil
+	report := NewValidationReport()
+	v.collectSameDayACH(report, file, nil)
+	return report.FirstError()
 }
 
 // Balancing Validations - moved to validator_balance.go for better organization
@@ -383,16 +411,15 @@ func (v *Validator) isValidTIN(tin string) bool {
 	return err == nil
 }
 
+// validateACHPaymentOrder returns the first payment out of routing number order
 func (v *Validator) validateACHPaymentOrder(schedule *ACHSchedule) error {
-	if len(schedule.Payments) < 2 {
-		return nil // No order to validate
-	}
-
 	var lastRTN string
 	for i, payment := range schedule.Payments {
 		if achPayment, ok := payment.(*ACHPayment); ok {
-			if i > 0 && achPayment.RoutingNumber < lastRTN {
-				return fmt.Errorf("ACH payments must be in routing number order")
+			if i > 0 {
+				if err := checkRoutingNumberOrder(lastRTN, achPayment.RoutingNumber); err != nil {
+					return err
+				}
 			}
 			lastRTN = achPayment.RoutingNumber
 		}
@@ -400,26 +427,45 @@ func (v *Validator) validateACHPaymentOrder(schedule *ACHSchedule) error {
 	return nil
 }
 
+// checkRoutingNumberOrder reports an ACH payment whose routing number sorts before
+// the previous payment's in the same schedule (Error Reason Group 1 Message 7)
+func checkRoutingNumberOrder(previous, current string) error {
+	if current >= previous {
+		return nil
+	}
+	return ValidationError{
+		Field:   "RoutingNumber",
+		Value:   current,
+		Rule:    "routing_number_order",
+		Message: fmt.Sprintf("ACH payments must be in routing number order (follows %s)", previous),
+		Code:    CodeRoutingNumberOrder,
+	}
+}
+
 // CTX Validation Rules
 func (v *Validator) ValidateCTXAddendum(payment *ACHPayment) error {
+	return withDefaultCode(v.validateCTXAddendum(payment), CodeInvalidPaymentData)
+}
+
+func (v *Validator) validateCTXAddendum(payment *ACHPayment) error {
 	if payment.StandardEntryClassCode != "CTX" {
 		return nil
 	}
 
 	// CTX must have at least one addendum
-	if len(payment.Addenda) == 0 {
+	if len(payment.CTXAddenda) == 0 {
 		return ValidationError{
-			Field:   "Addenda",
+			Field:   "CTXAddenda",
 			Rule:    "ctx_required",
 			Message: "CTX payments must have at least one addendum record",
 		}
 	}
 
 	// First addendum must start with ISA
-	if len(payment.Addenda) > 0 && len(payment.Addenda[0].AddendaInformation) >= CTXEDISegmentLength {
-		if payment.Addenda[0].AddendaInformation[:CTXEDISegmentLength] != CTXEDISegmentIdentifier {
+	if len(payment.CTXAddenda[0].AddendaInformation) >= CTXEDISegmentLength {
+		if payment.CTXAddenda[0].AddendaInformation[:CTXEDISegmentLength] != CTXEDISegmentIdentifier {
 			return ValidationError{
-				Field:   "Addenda[0]",
+				Field:   "CTXAddenda[0]",
 				Rule:    "ctx_isa_required",
 				Message: "first CTX addendum must start with ISA segment",
 			}
@@ -427,10 +473,10 @@ func (v *Validator) ValidateCTXAddendum(payment *ACHPayment) error {
 	}
 
 	// Validate EDI structure
-	for _, addendum := range payment.Addenda {
+	for _, addendum := range payment.CTXAddenda {
 		if addendum.RecordCode != string(RecordTypeACHAddendumCTX) {
 			return ValidationError{
-				Field:   "Addenda.RecordCode",
+				Field:   "CTXAddenda.RecordCode",
 				Value:   addendum.RecordCode,
 				Rule:    "ctx_record_code",
 				Message: fmt.Sprintf("CTX addenda must use record code '%s'", RecordTypeACHAddendumCTX),
@@ -438,24 +484,107 @@ func (v *Validator) ValidateCTXAddendum(payment *ACHPayment) error {
 		}
 	}
 
+	// Check the X12 envelope, segment counts and control numbers
+	_, err := payment.DecodeCTXRemittance()
+	return err
+}
+
+// ValidateACHAddenda checks addendum record codes and PaymentIDs, and the number
+// of "03" and "04" records allowed for the payment's SEC code
+func (v *Validator) ValidateACHAddenda(payment *ACHPayment) error {
+	paymentID := strings.TrimSpace(payment.PaymentID)
+
+	for i, addendum := range payment.Addenda {
+		if err := validateAddendumRecord(fmt.Sprintf("Addenda[%d]", i), addendum.RecordCode, addendum.PaymentID,
+			RecordTypeACHAddendum, paymentID); err != nil {
+			return err
+		}
+	}
+	for i, addendum := range payment.CTXAddenda {
+		if err := validateAddendumRecord(fmt.Sprintf("CTXAddenda[%d]", i), addendum.RecordCode, addendum.PaymentID,
+			RecordTypeACHAddendumCTX, paymentID); err != nil {
+			return err
+		}
+	}
+
+	return ValidateAddendaCount(payment.StandardEntryClassCode, len(payment.Addenda), len(payment.CTXAddenda))
+}
+
+// ValidateAddendaCount checks the "03" and "04" record counts against the limits
+// for an SEC code. Unknown SEC codes are not checked. The CTX minimum of one
+// "04" record is enforced by ValidateCTXAddendum.
+func ValidateAddendaCount(secCode string, addenda, ctxAddenda int) error {
+	maxAddenda, maxCTXAddenda, ok := addendaLimits(secCode)
+	if !ok {
+		return nil
+	}
+
+	if addenda > maxAddenda {
+		return ValidationError{
+			Field:   "Addenda",
+			Value:   strconv.Itoa(addenda),
+			Rule:    "addenda_count",
+			Message: fmt.Sprintf("%s payments allow at most %d '%s' addendum records, got %d", secCode, maxAddenda, RecordTypeACHAddendum, addenda),
+			Code:    CodeRecordMissingOrOutOfOrder,
+		}
+	}
+	if ctxAddenda > maxCTXAddenda {
+		return ValidationError{
+			Field:   "CTXAddenda",
+			Value:   strconv.Itoa(ctxAddenda),
+			Rule:    "addenda_count",
+			Message: fmt.Sprintf("%s payments allow at most %d '%s' addendum records, got %d", secCode, maxCTXAddenda, RecordTypeACHAddendumCTX, ctxAddenda),
+			Code:    CodeRecordMissingOrOutOfOrder,
+		}
+	}
+	return nil
+}
+
+// addendaLimits returns the maximum "03" and "04" records per payment for an SEC code
+func addendaLimits(secCode string) (addenda, ctxAddenda int, ok bool) {
+	switch StandardEntryClassCode(strings.TrimSpace(secCode)) {
+	case SECCodePPD, SECCodeCCD:
+		return MaxPPDCCDAddenda, 0, true
+	case SECCodeIAT:
+		return MaxIATAddenda, 0, true
+	case SECCodeCTX:
+		return 0, MaxCTXAddenda, true
+	}
+	return 0, 0, false
+}
+
+// validateAddendumRecord checks an addendum's record code and that it belongs to its payment
+func validateAddendumRecord(field, recordCode, paymentID string, expected RecordType, expectedPaymentID string) error {
+	if recordCode != string(expected) {
+		return ValidationError{
+			Field:   field + ".RecordCode",
+			Value:   recordCode,
+			Rule:    "addendum_record_code",
+			Message: fmt.Sprintf("addendum must use record code '%s'", expected),
+			Code:    CodeInvalidRecord,
+		}
+	}
+	if strings.TrimSpace(paymentID) != expectedPaymentID {
+		return ValidationError{
+			Field:   field + ".PaymentID",
+			Value:   paymentID,
+			Rule:    "addendum_payment_id",
+			Message: fmt.Sprintf("addendum PaymentID must match the payment data record (%s)", expectedPaymentID),
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
+		}
+	}
 	return nil
 }
 
 // Agency-specific validations based on Custom Agency Rule ID
 func (v *Validator) ValidateAgencySpecific(payment Payment, customAgencyRuleID string) error {
-	switch customAgencyRuleID {
-	case "IRS":
-		return v.validateIRSPayment(payment)
-	case "VA", "VACP":
-		return v.validateVAPayment(payment)
-	case "SSA", "SSA-Daily", "SSA-A":
-		return v.validateSSAPayment(payment)
-	case "RRB":
-		return v.validateRRBPayment(payment)
-	case "CCC":
-		return v.validateCCCPayment(payment)
+	set, ok := v.lookupRuleSet(customAgencyRuleID)
+	if !ok {
+		return nil
 	}
-	return nil
+	// Agency reconcilement errors mark the payment invalid
+	return withDefaultCode(set.ValidatePayment(v, payment, customAgencyRuleID), CodeInvalidPaymentData)
 }
 
 func (v *Validator) validateIRSPayment(payment Payment) error {
@@ -788,14 +917,19 @@ func (v *Validator) validateCCCPayment(payment Payment) error {
 	return nil
 }
 
-// Hexadecimal character validation
+// ValidateHexCharacters checks every byte of data is above HEX "3F" in the
+// validator's EBCDIC code page (Error Reason Group 1 Message 5). Data is one
+// ISO-8859-1 byte per column, as returned by the Reader.
 func (v *Validator) ValidateHexCharacters(data string) error {
-	for i, r := range data {
-		if r < MinHexCharacter { // Invalid hex characters are below 0x40
+	table := v.Encoding.hexTable()
+	for i := 0; i < len(data); i++ {
+		if ebcdic := table[data[i]]; ebcdic < MinHexCharacter {
 			return ValidationError{
 				Field:   "data",
+				Value:   fmt.Sprintf("0x%02X", data[i]),
 				Rule:    "hex_validation",
-				Message: fmt.Sprintf("invalid hex character at position %d: 0x%02X", i, r),
+				Message: fmt.Sprintf("invalid hex character at position %d: %s 0x%02X", i+1, v.Encoding.hexTableName(), ebcdic),
+				Code:    CodeInvalidHexCharacter,
 			}
 		}
 	}
@@ -813,6 +947,8 @@ func (v *Validator) ValidateScheduleNumber(scheduleNumber string) error {
 			Field:   "ScheduleNumber",
 			Rule:    "required",
 			Message: "schedule number cannot be blank",
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
 		}
 	}
 
@@ -822,6 +958,8 @@ func (v *Validator) ValidateScheduleNumber(scheduleNumber string) error {
 			Value:   scheduleNumber,
 			Rule:    "valid_characters",
 			Message: "schedule number can only contain A-Z, 0-9, and dash (-)",
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
 		}
 	}
 
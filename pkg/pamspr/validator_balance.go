@@ -1,6 +1,9 @@
 package pamspr
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // ScheduleBalanceInfo holds balance calculation results for a schedule
 type ScheduleBalanceInfo struct {
@@ -30,7 +33,7 @@ func (v *Validator) calculateACHScheduleBalance(schedule *ACHSchedule) ScheduleB
 			balance.Amount += achPayment.Amount
 
 			// Count associated records
-			balance.Records += int64(len(achPayment.Addenda))
+			balance.Records += int64(achPayment.AddendaCount())
 			balance.Records += int64(len(achPayment.CARSTASBETC))
 			if achPayment.DNP != nil {
 				balance.Records++
@@ -41,6 +44,17 @@ func (v *Validator) calculateACHScheduleBalance(schedule *ACHSchedule) ScheduleB
 	return balance
 }
 
+// scheduleBalance calculates balance information for a schedule of either type
+func (v *Validator) scheduleBalance(schedule Schedule) ScheduleBalanceInfo {
+	switch s := schedule.(type) {
+	case *ACHSchedule:
+		return v.calculateACHScheduleBalance(s)
+	case *CheckSchedule:
+		return v.calculateCheckScheduleBalance(s)
+	}
+	return ScheduleBalanceInfo{}
+}
+
 // calculateCheckScheduleBalance calculates balance information for a check schedule
 func (v *Validator) calculateCheckScheduleBalance(schedule *CheckSchedule) ScheduleBalanceInfo {
 	balance := ScheduleBalanceInfo{
@@ -70,61 +84,95 @@ func (v *Validator) calculateCheckScheduleBalance(schedule *CheckSchedule) Sched
 
 // validateScheduleTrailer validates that schedule trailer matches calculated values
 func (v *Validator) validateScheduleTrailer(trailer *ScheduleTrailer, balance ScheduleBalanceInfo, scheduleType string) error {
+	return firstError(v.scheduleTrailerErrors(trailer, balance, scheduleType))
+}
+
+// scheduleTrailerErrors returns every mismatch between a schedule trailer and its payments
+func (v *Validator) scheduleTrailerErrors(trailer *ScheduleTrailer, balance ScheduleBalanceInfo, scheduleType string) []error {
 	if trailer == nil {
-		return NewFieldRequiredError(fmt.Sprintf("%sScheduleTrailer", scheduleType))
+		return []error{NewFieldRequiredError(fmt.Sprintf("%sScheduleTrailer", scheduleType)).WithCode(CodeRecordMissingOrOutOfOrder)}
+	}
+
+	countCode, amountCode := CodeACHScheduleCountMismatch, CodeACHScheduleAmountMismatch
+	if scheduleType == "Check" {
+		countCode, amountCode = CodeCheckScheduleCountMismatch, CodeCheckScheduleAmountMismatch
 	}
 
+	var errs []error
 	if trailer.ScheduleCount != balance.Payments {
-		return ValidationError{
+		errs = append(errs, ValidationError{
 			Field:   "ScheduleTrailer.ScheduleCount",
 			Value:   fmt.Sprintf("%d", trailer.ScheduleCount),
 			Rule:    "balance",
 			Message: fmt.Sprintf("expected %d payments, got %d", balance.Payments, trailer.ScheduleCount),
-		}
+			Code:    countCode,
+		})
 	}
 
 	if trailer.ScheduleAmount != balance.Amount {
-		return ValidationError{
+		errs = append(errs, ValidationError{
 			Field:   "ScheduleTrailer.ScheduleAmount",
 			Value:   fmt.Sprintf("%d", trailer.ScheduleAmount),
 			Rule:    "balance",
 			Message: fmt.Sprintf("expected amount %d, got %d", balance.Amount, trailer.ScheduleAmount),
-		}
+			Code:    amountCode,
+		})
 	}
 
-	return nil
+	return errs
 }
 
 // validateFileTrailer validates that file trailer matches calculated values
 func (v *Validator) validateFileTrailer(trailer *FileTrailer, balance FileBalanceInfo) error {
+	return firstError(v.fileTrailerErrors(trailer, balance))
+}
+
+// fileTrailerErrors returns every mismatch between the file trailer and the file contents
+func (v *Validator) fileTrailerErrors(trailer *FileTrailer, balance FileBalanceInfo) []error {
+	if trailer == nil {
+		return []error{NewFieldRequiredError("FileTrailer").WithCode(CodeRecordMissingOrOutOfOrder)}
+	}
+
+	var errs []error
 	if trailer.TotalCountRecords != balance.TotalRecords {
-		return ValidationError{
+		errs = append(errs, ValidationError{
 			Field:   "FileTrailer.TotalCountRecords",
 			Value:   fmt.Sprintf("%d", trailer.TotalCountRecords),
 			Rule:    "balance",
 			Message: fmt.Sprintf("expected %d records, got %d", balance.TotalRecords, trailer.TotalCountRecords),
-		}
+			Code:    CodeFileCountMismatch,
+		})
 	}
 
 	if trailer.TotalCountPayments != balance.TotalPayments {
-		return ValidationError{
+		errs = append(errs, ValidationError{
 			Field:   "FileTrailer.TotalCountPayments",
 			Value:   fmt.Sprintf("%d", trailer.TotalCountPayments),
 			Rule:    "balance",
 			Message: fmt.Sprintf("expected %d payments, got %d", balance.TotalPayments, trailer.TotalCountPayments),
-		}
+			Code:    CodeFileCountMismatch,
+		})
 	}
 
 	if trailer.TotalAmountPayments != balance.TotalAmount {
-		return ValidationError{
+		errs = append(errs, ValidationError{
 			Field:   "FileTrailer.TotalAmountPayments",
 			Value:   fmt.Sprintf("%d", trailer.TotalAmountPayments),
 			Rule:    "balance",
 			Message: fmt.Sprintf("expected amount %d, got %d", balance.TotalAmount, trailer.TotalAmountPayments),
-		}
+			Code:    CodeFileAmountMismatch,
+		})
 	}
 
-	return nil
+	return errs
+}
+
+// firstError returns the first error of errs, or nil
+func firstError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
 }
 
 // ValidateBalancing validates that all totals in the file are balanced
@@ -144,6 +192,9 @@ func (v *Validator) ValidateBalancing(file *File) error {
 		case *ACHSchedule:
 			scheduleBalance = v.calculateACHScheduleBalance(s)
 			err = v.validateScheduleTrailer(s.Trailer, scheduleBalance, "ACH")
+			if err == nil {
+				err = v.ValidatePrenoteSchedule(s)
+			}
 
 		case *CheckSchedule:
 			scheduleBalance = v.calculateCheckScheduleBalance(s)
@@ -163,3 +214,83 @@ func (v *Validator) ValidateBalancing(file *File) error {
 	// Validate file trailer
 	return v.validateFileTrailer(file.Trailer, fileBalance)
 }
+
+// validateTransactionCodeAmount applies the zero dollar rules of section 1.5
+// to a payment. Zero amounts must be prenotes or CTX (Error Reason Group 4
+// Message 4), and CTX payments with an amount must not use a zero dollar
+// credit code (Error Reason Group 4 Message 3).
+func (v *Validator) validateTransactionCodeAmount(payment *ACHPayment) error {
+	code := payment.ACH_TransactionCode
+	ctx := payment.StandardEntryClassCode == string(SECCodeCTX)
+
+	if payment.Amount == 0 && !PrenoteTransactionCodes[code] && !ctx {
+		return ValidationError{
+			Field:   "Amount",
+			Value:   "0",
+			Rule:    "zero_amount_prenote_or_ctx",
+			Message: fmt.Sprintf("zero dollar payment must use a prenote transaction code or SEC code CTX, got transaction code %s", code),
+			Code:    CodeZeroAmountNotPrenote,
+		}
+	}
+
+	if payment.Amount > 0 && ctx && ZeroDollarCreditTransactionCodes[code] {
+		return ValidationError{
+			Field:   "ACH_TransactionCode",
+			Value:   code,
+			Rule:    "ctx_zero_dollar_credit",
+			Message: fmt.Sprintf("CTX payment of %s cannot use zero dollar credit transaction code %s", FormatCents(payment.Amount), code),
+			Code:    CodeCTXZeroDollarCredit,
+		}
+	}
+
+	return nil
+}
+
+// prenoteBalance tracks the payments of an ACH schedule for the prenote rule
+type prenoteBalance struct {
+	prenotes     int
+	nonZero      int
+	firstNonZero int // Index of the first payment with an amount
+	firstID      string
+	firstAmount  int64
+}
+
+// add counts a payment at index in the schedule
+func (b *prenoteBalance) add(payment *ACHPayment, index int) {
+	if PrenoteTransactionCodes[payment.ACH_TransactionCode] {
+		b.prenotes++
+	}
+	if payment.Amount != 0 {
+		if b.nonZero == 0 {
+			b.firstNonZero, b.firstID, b.firstAmount = index, strings.TrimSpace(payment.PaymentID), payment.Amount
+		}
+		b.nonZero++
+	}
+}
+
+// err reports a schedule with prenotes and payments with an amount
+// (Error Reason Group 4 Message 5)
+func (b *prenoteBalance) err() error {
+	if b.prenotes == 0 || b.nonZero == 0 {
+		return nil
+	}
+	return ValidationError{
+		Field:   "Amount",
+		Value:   fmt.Sprintf("%d", b.firstAmount),
+		Rule:    "prenote_zero_amounts",
+		Message: fmt.Sprintf("schedule has prenote transaction codes, so every payment must be zero dollars; %d payment(s) have an amount, the first is payment %d (%s)", b.nonZero, b.firstNonZero, b.firstID),
+		Code:    CodePrenoteNonZeroAmount,
+	}
+}
+
+// ValidatePrenoteSchedule checks that an ACH schedule with prenote
+// transaction codes has only zero dollar payments
+func (v *Validator) ValidatePrenoteSchedule(schedule *ACHSchedule) error {
+	var balance prenoteBalance
+	for i, payment := range schedule.Payments {
+		if p, ok := payment.(*ACHPayment); ok {
+			balance.add(p, i)
+		}
+	}
+	return balance.err()
+}
@@ -0,0 +1,224 @@
+package pamspr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CARS availability type codes (G.08)
+const (
+	AvailabilityTypeAnnual   = ""  // Annual and multi-year accounts, with both periods
+	AvailabilityTypeNoYear   = "X" // No-year accounts, without an ending period
+	AvailabilityTypeCentral  = "A" // Treasury central summary general ledger accounts
+	AvailabilityTypeClearing = "F" // Clearing and suspense accounts
+	AvailabilityTypeCanceled = "C" // Canceled accounts
+)
+
+// carsCreditFlag is IsCredit for a credit; blank and "0" are debits
+const carsCreditFlag = "1"
+
+// SignedAmount returns the amount as PAM stores it, negative for credits
+func (c *CARSTASBETC) SignedAmount() int64 {
+	if c.IsCredit == carsCreditFlag {
+		return -c.AccountClassificationAmount
+	}
+	return c.AccountClassificationAmount
+}
+
+// TAS returns the component Treasury Account Symbol: the sub-level prefix,
+// allocation transfer agency, agency, beginning and ending periods,
+// availability type, main account and sub-account, each padded to its field
+// width as in positions 23-46 of the record
+func (c *CARSTASBETC) TAS() string {
+	var sb strings.Builder
+	for _, part := range []struct {
+		value  string
+		length int
+	}{
+		{c.SubLevelPrefixCode, 2},
+		{c.AllocationTransferAgencyID, 3},
+		{c.AgencyIdentifier, 3},
+		{c.BeginningPeriodOfAvailability, 4},
+		{c.EndingPeriodOfAvailability, 4},
+		{c.AvailabilityTypeCode, 1},
+		{c.MainAccountCode, 4},
+		{c.SubAccountCode, 3},
+	} {
+		sb.WriteString(PadRight(strings.TrimSpace(part.value), part.length, ' '))
+	}
+	return sb.String()
+}
+
+// BETC returns the Business Event Type Code without padding
+func (c *CARSTASBETC) BETC() string {
+	return strings.TrimSpace(c.BusinessEventTypeCode)
+}
+
+// carsRecords returns the CARS TAS/BETC records of a payment
+func carsRecords(payment Payment) []*CARSTASBETC {
+	switch p := payment.(type) {
+	case *ACHPayment:
+		return p.CARSTASBETC
+	case *CheckPayment:
+		return p.CARSTASBETC
+	}
+	return nil
+}
+
+// ValidateCARSTASBETC checks the CARS TAS/BETC records of a payment. Each
+// must carry the payment's PaymentID (Error Reason Group 1 Message 6) and a
+// well formed TAS and BETC, and their amounts, debits less credits, must add
+// up to the payment amount. PAM reports TAS/BETC differences to CARS rather
+// than rejecting them, so only the PaymentID rule has a Treasury error code,
+// and ValidateFile runs the others only with CheckCARSAccounting.
+func (v *Validator) ValidateCARSTASBETC(payment Payment) error {
+	return firstError(v.carsErrors(payment))
+}
+
+// carsErrors returns every CARS TAS/BETC problem of a payment
+func (v *Validator) carsErrors(payment Payment) []error {
+	var errs []error
+	paymentID := strings.TrimSpace(payment.GetPaymentID())
+	for i, car := range carsRecords(payment) {
+		errs = append(errs, carsRecordErrors(car, i, paymentID, true)...)
+	}
+	if err := carsBalanceError(payment); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// carsRecordErrors checks the record at index in a payment's CARS TAS/BETC
+// records; the IsCredit, TAS and BETC checks run only with accounting
+func carsRecordErrors(car *CARSTASBETC, index int, paymentID string, accounting bool) []error {
+	field := func(name string) string {
+		return fmt.Sprintf("CARSTASBETC[%d].%s", index, name)
+	}
+
+	var errs []error
+	if id := strings.TrimSpace(car.PaymentID); id != paymentID {
+		errs = append(errs, ValidationError{
+			Field:   field("PaymentID"),
+			Value:   id,
+			Rule:    "matches_payment",
+			Message: fmt.Sprintf("must match the payment's PaymentID %s", paymentID),
+			Code:    CodeInvalidRecord,
+			Scope:   RejectionSchedule,
+		})
+	}
+	if !accounting {
+		return errs
+	}
+
+	if car.IsCredit != "" && car.IsCredit != "0" && car.IsCredit != carsCreditFlag {
+		errs = append(errs, ValidationError{
+			Field:   field("IsCredit"),
+			Value:   car.IsCredit,
+			Rule:    "valid_values",
+			Message: "must be '0', '1' or blank",
+		})
+	}
+	for _, err := range tasErrors(car) {
+		err.Field = field(err.Field)
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// carsBalanceError checks that a payment's CARS TAS/BETC amounts, debits
+// less credits, add up to the payment amount. Payments without CARS records
+// are not checked.
+func carsBalanceError(payment Payment) error {
+	records := carsRecords(payment)
+	if len(records) == 0 {
+		return nil // The record is optional
+	}
+
+	var net int64
+	for _, car := range records {
+		net += car.SignedAmount()
+	}
+	if net == payment.GetAmount() {
+		return nil
+	}
+	return ValidationError{
+		Field:   "CARSTASBETC.AccountClassificationAmount",
+		Value:   fmt.Sprintf("%d", net),
+		Rule:    "cars_balance",
+		Message: fmt.Sprintf("debits less credits total %s, expected the payment amount %s", FormatCents(net), FormatCents(payment.GetAmount())),
+	}
+}
+
+// tasErrors checks the TAS components and BETC of a record
+func tasErrors(car *CARSTASBETC) []ValidationError {
+	var errs []ValidationError
+	digits := func(name, value string, length int, required bool) {
+		value = strings.TrimSpace(value)
+		if value == "" && !required {
+			return
+		}
+		if len(value) != length || strings.Trim(value, "0123456789") != "" {
+			errs = append(errs, ValidationError{
+				Field:   name,
+				Value:   value,
+				Rule:    "tas_format",
+				Message: fmt.Sprintf("must be %d digits", length),
+			})
+		}
+	}
+
+	digits("SubLevelPrefixCode", car.SubLevelPrefixCode, 2, false)
+	digits("AllocationTransferAgencyID", car.AllocationTransferAgencyID, 3, false)
+	digits("AgencyIdentifier", car.AgencyIdentifier, 3, true)
+	digits("MainAccountCode", car.MainAccountCode, 4, true)
+	digits("SubAccountCode", car.SubAccountCode, 3, false)
+
+	begin := strings.TrimSpace(car.BeginningPeriodOfAvailability)
+	end := strings.TrimSpace(car.EndingPeriodOfAvailability)
+	switch availability := strings.TrimSpace(car.AvailabilityTypeCode); availability {
+	case AvailabilityTypeAnnual:
+		before := len(errs)
+		digits("BeginningPeriodOfAvailability", begin, 4, true)
+		digits("EndingPeriodOfAvailability", end, 4, true)
+		if len(errs) == before && begin > end {
+			errs = append(errs, ValidationError{
+				Field:   "EndingPeriodOfAvailability",
+				Value:   end,
+				Rule:    "tas_format",
+				Message: fmt.Sprintf("ends before the beginning period %s", begin),
+			})
+		}
+	case AvailabilityTypeNoYear:
+		digits("BeginningPeriodOfAvailability", begin, 4, false)
+		if end != "" {
+			errs = append(errs, ValidationError{
+				Field:   "EndingPeriodOfAvailability",
+				Value:   end,
+				Rule:    "tas_format",
+				Message: "must be blank for no-year accounts",
+			})
+		}
+	case AvailabilityTypeCentral, AvailabilityTypeClearing, AvailabilityTypeCanceled:
+		digits("BeginningPeriodOfAvailability", begin, 4, false)
+		digits("EndingPeriodOfAvailability", end, 4, false)
+	default:
+		errs = append(errs, ValidationError{
+			Field:   "AvailabilityTypeCode",
+			Value:   availability,
+			Rule:    "valid_values",
+			Message: "must be blank, 'X', 'A', 'F' or 'C'",
+		})
+	}
+
+	betc := car.BETC()
+	if betc == "" || strings.Trim(betc, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") != "" {
+		errs = append(errs, ValidationError{
+			Field:   "BusinessEventTypeCode",
+			Value:   betc,
+			Rule:    "betc_format",
+			Message: "must be 1-8 upper case letters or digits",
+		})
+	}
+
+	return errs
+}
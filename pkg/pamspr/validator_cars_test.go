@@ -0,0 +1,194 @@
+package pamspr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testCARS(paymentID string, amount int64, credit bool) *CARSTASBETC {
+	car := &CARSTASBETC{
+		RecordCode:                    "G ",
+		PaymentID:                     paymentID,
+		AgencyIdentifier:              "020",
+		BeginningPeriodOfAvailability: "2026",
+		EndingPeriodOfAvailability:    "2026",
+		MainAccountCode:               "1801",
+		BusinessEventTypeCode:         "DISB",
+		AccountClassificationAmount:   amount,
+	}
+	if credit {
+		car.IsCredit = "1"
+	}
+	return car
+}
+
+func TestCARSTASBETCTAS(t *testing.T) {
+	car := testCARS("PAY001", 100, false)
+	car.SubLevelPrefixCode = "20"
+	car.SubAccountCode = "000"
+	if got, want := car.TAS(), "20   02020262026 1801000"; got != want {
+		t.Errorf("TAS() = %q, want %q", got, want)
+	}
+
+	car = testCARS("PAY001", 100, false)
+	car.BeginningPeriodOfAvailability, car.EndingPeriodOfAvailability, car.AvailabilityTypeCode = "", "", "X"
+	if got, want := car.TAS(), "     020        X1801   "; got != want {
+		t.Errorf("TAS() = %q, want %q", got, want)
+	}
+	if len(car.TAS()) != 24 {
+		t.Errorf("TAS() is %d characters, want 24", len(car.TAS()))
+	}
+}
+
+func TestValidateCARSTASBETC(t *testing.T) {
+	validator := NewValidator()
+
+	tests := []struct {
+		name   string
+		modify func(p *ACHPayment)
+		field  string // Expected field in error, empty if valid
+		code   ErrorCode
+	}{
+		{"No CARS records", func(p *ACHPayment) { p.CARSTASBETC = nil }, "", ErrorCode{}},
+		{"Balanced", func(p *ACHPayment) {}, "", ErrorCode{}},
+		{"Balanced with a credit", func(p *ACHPayment) {
+			p.CARSTASBETC = append(p.CARSTASBETC, testCARS("PAY001", 2500, false), testCARS("PAY001", 2500, true))
+		}, "", ErrorCode{}},
+		{"Out of balance", func(p *ACHPayment) {
+			p.CARSTASBETC[0].AccountClassificationAmount = 99999
+		}, "CARSTASBETC.AccountClassificationAmount", ErrorCode{}},
+		{"Credit counted against the payment", func(p *ACHPayment) {
+			p.CARSTASBETC[0].IsCredit = "1"
+		}, "CARSTASBETC.AccountClassificationAmount", ErrorCode{}},
+		{"Other PaymentID", func(p *ACHPayment) {
+			p.CARSTASBETC[0].PaymentID = "PAY002"
+		}, "CARSTASBETC[0].PaymentID", CodeInvalidRecord},
+		{"Invalid IsCredit", func(p *ACHPayment) {
+			p.CARSTASBETC[0].IsCredit = "Y"
+		}, "CARSTASBETC[0].IsCredit", ErrorCode{}},
+		{"Missing agency", func(p *ACHPayment) {
+			p.CARSTASBETC[0].AgencyIdentifier = ""
+		}, "CARSTASBETC[0].AgencyIdentifier", ErrorCode{}},
+		{"Alphabetic main account", func(p *ACHPayment) {
+			p.CARSTASBETC[0].MainAccountCode = "18A1"
+		}, "CARSTASBETC[0].MainAccountCode", ErrorCode{}},
+		{"Short sub-account", func(p *ACHPayment) {
+			p.CARSTASBETC[0].SubAccountCode = "1"
+		}, "CARSTASBETC[0].SubAccountCode", ErrorCode{}},
+		{"Annual without ending period", func(p *ACHPayment) {
+			p.CARSTASBETC[0].EndingPeriodOfAvailability = ""
+		}, "CARSTASBETC[0].EndingPeriodOfAvailability", ErrorCode{}},
+		{"Multi-year", func(p *ACHPayment) {
+			p.CARSTASBETC[0].EndingPeriodOfAvailability = "2028"
+		}, "", ErrorCode{}},
+		{"Ends before it begins", func(p *ACHPayment) {
+			p.CARSTASBETC[0].EndingPeriodOfAvailability = "2025"
+		}, "CARSTASBETC[0].EndingPeriodOfAvailability", ErrorCode{}},
+		{"No-year", func(p *ACHPayment) {
+			p.CARSTASBETC[0].EndingPeriodOfAvailability = ""
+			p.CARSTASBETC[0].AvailabilityTypeCode = "X"
+		}, "", ErrorCode{}},
+		{"No-year with ending period", func(p *ACHPayment) {
+			p.CARSTASBETC[0].AvailabilityTypeCode = "X"
+		}, "CARSTASBETC[0].EndingPeriodOfAvailability", ErrorCode{}},
+		{"Clearing account", func(p *ACHPayment) {
+			p.CARSTASBETC[0].BeginningPeriodOfAvailability = ""
+			p.CARSTASBETC[0].EndingPeriodOfAvailability = ""
+			p.CARSTASBETC[0].AvailabilityTypeCode = "F"
+		}, "", ErrorCode{}},
+		{"Unknown availability type", func(p *ACHPayment) {
+			p.CARSTASBETC[0].AvailabilityTypeCode = "Z"
+		}, "CARSTASBETC[0].AvailabilityTypeCode", ErrorCode{}},
+		{"Missing BETC", func(p *ACHPayment) {
+			p.CARSTASBETC[0].BusinessEventTypeCode = ""
+		}, "CARSTASBETC[0].BusinessEventTypeCode", ErrorCode{}},
+		{"Lower case BETC", func(p *ACHPayment) {
+			p.CARSTASBETC[0].BusinessEventTypeCode = "disb"
+		}, "CARSTASBETC[0].BusinessEventTypeCode", ErrorCode{}},
+		{"Second record", func(p *ACHPayment) {
+			p.CARSTASBETC = append(p.CARSTASBETC, testCARS("PAY001", 0, false))
+			p.CARSTASBETC[1].MainAccountCode = ""
+		}, "CARSTASBETC[1].MainAccountCode", ErrorCode{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &ACHPayment{
+				PaymentID:   "PAY001",
+				Amount:      100000,
+				CARSTASBETC: []*CARSTASBETC{testCARS("PAY001", 100000, false)},
+			}
+			tt.modify(payment)
+
+			err := validator.ValidateCARSTASBETC(payment)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var ve ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Expected a ValidationError on %s, got %v", tt.field, err)
+			}
+			if ve.Field != tt.field {
+				t.Errorf("Expected field %s, got %s (%v)", tt.field, ve.Field, err)
+			}
+			if ve.Code != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, ve.Code)
+			}
+		})
+	}
+}
+
+func TestValidateFileCARS(t *testing.T) {
+	file := createTestMixedFile()
+
+	ach := file.Schedules[0].(*ACHSchedule).Payments[0].(*ACHPayment)
+	ach.Addenda = []*ACHAddendum{{RecordCode: "03", PaymentID: ach.PaymentID, AddendaInformation: "INVOICE 1"}}
+	ach.CARSTASBETC = []*CARSTASBETC{
+		testCARS(ach.PaymentID, ach.Amount, false),
+		testCARS(ach.PaymentID, 100, false),
+	}
+	ach.CARSTASBETC[1].BusinessEventTypeCode = ""
+
+	check := file.Schedules[1].(*CheckSchedule).Payments[0].(*CheckPayment)
+	check.CARSTASBETC = []*CARSTASBETC{testCARS(check.PaymentID, check.Amount, false)}
+	check.CARSTASBETC[0].PaymentID = "OTHER"
+
+	// Without CheckCARSAccounting only the PaymentID rule rejects
+	for _, issue := range NewValidator().ValidateFile(file).Issues {
+		if strings.HasPrefix(issue.Field, "CARSTASBETC") && issue.Field != "CARSTASBETC[0].PaymentID" {
+			t.Errorf("Unexpected issue without CheckCARSAccounting: %s", issue)
+		}
+	}
+
+	validator := NewValidator()
+	validator.CheckCARSAccounting = true
+	report := validator.ValidateFile(file)
+
+	lines := newRecordLocator(file, nil)
+	tests := []struct {
+		field  string
+		record string
+		line   int
+	}{
+		// CARS records follow the addendum of the ACH payment and the stub of the check
+		{"CARSTASBETC[1].BusinessEventTypeCode", "G ", lines.payment(0, 0) + 3},
+		{"CARSTASBETC.AccountClassificationAmount", "02", lines.payment(0, 0)},
+		{"CARSTASBETC[0].PaymentID", "G ", lines.payment(1, 0) + 2},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, issue := range report.Issues {
+			if issue.Field == tt.field && issue.RecordCode == tt.record && issue.LineNumber == tt.line {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s on record %q at line %d, got %v", tt.field, tt.record, tt.line, report.Issues)
+		}
+	}
+}
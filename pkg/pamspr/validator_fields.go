@@ -0,0 +1,124 @@
+package pamspr
+
+import "fmt"
+
+// FieldError is a ValidationError located at a column of a fixed-width record
+type FieldError struct {
+	ValidationError
+	RecordCode string
+	Start      int // 1-based first column of the field
+	End        int // 1-based last column of the field
+	Column     int // 1-based column of the offending character
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("record %q field %s (columns %d-%d): %s", e.RecordCode, e.Field, e.Start, e.End, e.ValidationError.Error())
+}
+
+// Unwrap returns the underlying ValidationError
+func (e *FieldError) Unwrap() error {
+	return e.ValidationError
+}
+
+// ValidateRecordFields checks every field of a raw record against its type and
+// allowed character set from GetFieldDefinitions, returning one FieldError per
+// failing field. Characters at or below HEX "3F" in the validator's code page
+// fail the hexadecimal character rule (Error Reason Group 1 Message 5); letters
+// in N fields and digits in A fields
+// fail as invalid payment data on payment records and invalid records elsewhere.
+// Records with an unknown code are only checked against the hexadecimal rule.
+func (v *Validator) ValidateRecordFields(line string) []error {
+	recordCode := firstN(line, RecordCodeLength)
+	fields := GetOrderedFieldDefinitions(recordCode)
+	if fields == nil {
+		if err := v.ValidateHexCharacters(line); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, field := range fields {
+		if field.Start > len(line) {
+			break
+		}
+		value := line[field.Start-1 : min(field.End, len(line))]
+		if err := v.validateFieldValue(recordCode, field, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (v *Validator) validateFieldValue(recordCode string, field NamedField, value string) error {
+	table := v.Encoding.hexTable()
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		if table[b] < MinHexCharacter {
+			return newFieldError(recordCode, field, value, i, "character_set",
+				fmt.Sprintf("column %d: character 0x%02X (%s 0x%02X) is not an allowed character",
+					field.Start+i, b, v.Encoding.hexTableName(), table[b]),
+				CodeInvalidHexCharacter)
+		}
+	}
+
+	code := CodeInvalidRecord
+	if isPaymentRecordCode(recordCode) {
+		code = CodeInvalidPaymentData
+	}
+
+	switch field.Type {
+	case FieldTypeNumeric:
+		if field.DefaultZero || (!field.Required && isBlank(value)) {
+			return nil
+		}
+		for i := 0; i < len(value); i++ {
+			if !CharsetNumeric.Allows(value[i]) {
+				return newFieldError(recordCode, field, value, i, "field_type",
+					fmt.Sprintf("column %d: %q is not allowed in numeric (N) field", field.Start+i, value[i]), code)
+			}
+		}
+	case FieldTypeAlphabetic:
+		for i := 0; i < len(value); i++ {
+			if !CharsetAlphabetic.Allows(value[i]) {
+				return newFieldError(recordCode, field, value, i, "field_type",
+					fmt.Sprintf("column %d: %q is not allowed in alphabetic (A) field", field.Start+i, value[i]), code)
+			}
+		}
+	}
+	return nil
+}
+
+func newFieldError(recordCode string, field NamedField, value string, offset int, rule, message string, code ErrorCode) *FieldError {
+	return &FieldError{
+		ValidationError: ValidationError{
+			Field:   field.Name,
+			Value:   value,
+			Rule:    rule,
+			Message: message,
+			Code:    code,
+		},
+		RecordCode: recordCode,
+		Start:      field.Start,
+		End:        field.End,
+		Column:     field.Start + offset,
+	}
+}
+
+// isPaymentRecordCode reports whether a record is a payment or one of its associated records
+func isPaymentRecordCode(recordCode string) bool {
+	switch recordCode {
+	case "02", "03", "04", "12", "13", "G ", "DD":
+		return true
+	}
+	return false
+}
+
+func isBlank(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			return false
+		}
+	}
+	return true
+}
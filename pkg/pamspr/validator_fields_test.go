@@ -0,0 +1,113 @@
+package pamspr
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateRecordFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestMixedFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	buf.Reset()
+	if err := NewWriter(&buf).Write(createTestFileWithCARS()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var cars string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "G ") {
+			cars = line
+		}
+	}
+
+	// set replaces the characters of a record starting at a 1-based column
+	set := func(line string, column int, value string) string {
+		return line[:column-1] + value + line[column-1+len(value):]
+	}
+
+	tests := []struct {
+		name   string
+		line   string
+		field  string
+		column int
+		rule   string
+		code   ErrorCode
+	}{
+		{"Valid header", lines[0], "", 0, "", ErrorCode{}},
+		{"Valid payment", lines[2], "", 0, "", ErrorCode{}},
+		{"Valid trailer", lines[len(lines)-1], "", 0, "", ErrorCode{}},
+		{"Letter in amount", set(lines[2], 25, "X"), "Amount", 25, "field_type", CodeInvalidPaymentData},
+		{"Blank routing number", set(lines[2], 187, "         "), "RoutingNumber", 187, "field_type", CodeInvalidPaymentData},
+		{"Digit in SEC code", set(lines[1], 46, "PP1"), "StandardEntryClassCode", 48, "field_type", CodeInvalidRecord},
+		{"Letter in ALC", set(lines[1], 49, "1234567A"), "AgencyLocationCode", 56, "field_type", CodeInvalidRecord},
+		{"Tab in payee name", set(lines[2], 40, "\t"), "PayeeName", 40, "character_set", CodeInvalidHexCharacter},
+		{"Accented letter in payee name", set(lines[2], 40, "\xC9"), "", 0, "", ErrorCode{}}, // CP037 0x71
+		{"DEL in reconcilement", set(lines[2], 280, "\x7F"), "Reconcilement", 280, "character_set", CodeInvalidHexCharacter},
+		{"Control character in filler", set(lines[0], 800, "\x01"), "Filler", 800, "character_set", CodeInvalidHexCharacter},
+		{"Non-numeric CARS amount defaults to zero", set(cars, 55, "ABC"), "", 0, "", ErrorCode{}},
+		{"Letter in record count", set(lines[len(lines)-1], 5, "O"), "TotalCountRecords", 5, "field_type", CodeInvalidRecord},
+	}
+
+	validator := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validator.ValidateRecordFields(tt.line)
+			if tt.field == "" {
+				if len(errs) != 0 {
+					t.Fatalf("Unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("Expected 1 error, got %d: %v", len(errs), errs)
+			}
+
+			var fieldErr *FieldError
+			if !errors.As(errs[0], &fieldErr) {
+				t.Fatalf("Expected FieldError, got %T", errs[0])
+			}
+			if fieldErr.Field != tt.field || fieldErr.Column != tt.column || fieldErr.Rule != tt.rule {
+				t.Errorf("Expected %s column %d %s, got %s column %d %s",
+					tt.field, tt.column, tt.rule, fieldErr.Field, fieldErr.Column, fieldErr.Rule)
+			}
+			var ve ValidationError
+			if !errors.As(errs[0], &ve) || ve.Code != tt.code {
+				t.Errorf("Expected code %s, got %v", tt.code, errs[0])
+			}
+		})
+	}
+
+	// Unknown records fall back to the hexadecimal rule
+	if errs := validator.ValidateRecordFields("XX\x01"); len(errs) != 1 {
+		t.Errorf("Expected hex error for unknown record, got %v", errs)
+	}
+}
+
+func TestReaderReportsFieldErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(createTestACHFile()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	lines[2] = lines[2][:19] + "12345X7890" + lines[2][29:] // Amount
+
+	reader := NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	if err := reader.ProcessFile(nil, nil, nil); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	for _, issue := range reader.GetValidationReport().Issues {
+		if issue.Field == "Amount" && issue.Rule == "field_type" {
+			if issue.LineNumber != 3 || !strings.Contains(issue.Message, "column 25") {
+				t.Errorf("Unexpected issue: %s", issue)
+			}
+			return
+		}
+	}
+	t.Error("Expected a field_type issue for Amount")
+}
@@ -30,10 +30,10 @@ func (v *Validator) ValidateFileStructure(file *File) error {
 // validateRequiredComponents ensures file has required header and trailer
 func (v *Validator) validateRequiredComponents(file *File) error {
 	if file.Header == nil {
-		return NewFieldRequiredError("FileHeader")
+		return NewFieldRequiredError("FileHeader").WithCode(CodeRecordMissingOrOutOfOrder)
 	}
 	if file.Trailer == nil {
-		return NewFieldRequiredError("FileTrailer")
+		return NewFieldRequiredError("FileTrailer").WithCode(CodeRecordMissingOrOutOfOrder)
 	}
 	return nil
 }
@@ -60,6 +60,7 @@ func (v *Validator) validateSingleScheduleConsistency(schedule Schedule, index i
 			Field:   fmt.Sprintf("Schedule[%d]", index),
 			Rule:    "schedule_type",
 			Message: "unknown schedule type",
+			Code:    CodeInvalidRecord,
 		}
 	}
 }
@@ -72,6 +73,7 @@ func (v *Validator) validateACHScheduleConsistency(schedule *ACHSchedule, index
 				Field:   fmt.Sprintf("Schedule[%d]", index),
 				Rule:    "payment_type_consistency",
 				Message: "ACH schedule cannot contain non-ACH payments",
+				Code:    CodeInvalidRecord,
 			}
 		}
 	}
@@ -86,6 +88,7 @@ func (v *Validator) validateCheckScheduleConsistency(schedule *CheckSchedule, in
 				Field:   fmt.Sprintf("Schedule[%d]", index),
 				Rule:    "payment_type_consistency",
 				Message: "Check schedule cannot contain non-Check payments",
+				Code:    CodeInvalidRecord,
 			}
 		}
 	}
@@ -112,20 +115,21 @@ func (v *Validator) validateACHRules(file *File) error {
 
 // validateACHSchedulePaymentOrder validates ACH payments are in routing number order
 func (v *Validator) validateACHSchedulePaymentOrder(schedule *ACHSchedule, index int) error {
-	if err := v.validateACHPaymentOrder(schedule); err != nil {
-		return ValidationError{
-			Field:   fmt.Sprintf("Schedule[%d]", index),
-			Rule:    "routing_number_order",
-			Message: err.Error(),
-		}
+	err := v.validateACHPaymentOrder(schedule)
+	if ve, ok := err.(ValidationError); ok {
+		ve.Field = fmt.Sprintf("Schedule[%d]", index)
+		return ve
 	}
-	return nil
+	return err
 }
 
-// validateACHScheduleCTXPayments validates CTX addenda for all payments in a schedule
+// validateACHScheduleCTXPayments validates addenda and CTX data for all payments in a schedule
 func (v *Validator) validateACHScheduleCTXPayments(schedule *ACHSchedule) error {
 	for _, payment := range schedule.Payments {
 		if achPayment, ok := payment.(*ACHPayment); ok {
+			if err := v.ValidateACHAddenda(achPayment); err != nil {
+				return err
+			}
 			if err := v.ValidateCTXAddendum(achPayment); err != nil {
 				return err
 			}
@@ -1,6 +1,8 @@
 package pamspr
 
 import (
+	"bytes"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -534,6 +536,152 @@ func TestValidateBalancing(t *testing.T) {
 	}
 }
 
+func TestTransactionCodeAmountRules(t *testing.T) {
+	validator := NewValidator()
+
+	payment := func(code string, amount int64, sec StandardEntryClassCode) *ACHPayment {
+		return &ACHPayment{
+			Amount:                 amount,
+			PayeeName:              "JOHN DOE",
+			RoutingNumber:          "021000021",
+			AccountNumber:          "1234567890",
+			ACH_TransactionCode:    code,
+			PaymentID:              "PAY001",
+			StandardEntryClassCode: string(sec),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		payments []*ACHPayment
+		code     ErrorCode // Expected from the payments or the schedule, zero if valid
+	}{
+		{"Prenote schedule", []*ACHPayment{payment("23", 0, SECCodePPD), payment("33", 0, SECCodePPD)}, ErrorCode{}},
+		{"Zero dollar CTX", []*ACHPayment{payment("24", 0, SECCodeCTX)}, ErrorCode{}},
+		{"CTX with an amount", []*ACHPayment{payment("22", 100, SECCodeCTX)}, ErrorCode{}},
+		{"Zero dollar credit code outside CTX", []*ACHPayment{payment("24", 100, SECCodeCCD)}, ErrorCode{}},
+		{"Zero amount not prenote", []*ACHPayment{payment("22", 0, SECCodePPD)}, CodeZeroAmountNotPrenote},
+		{"CTX zero dollar credit with an amount", []*ACHPayment{payment("34", 100, SECCodeCTX)}, CodeCTXZeroDollarCredit},
+		{"Prenote schedule with an amount", []*ACHPayment{payment("23", 0, SECCodePPD), payment("22", 500, SECCodePPD)}, CodePrenoteNonZeroAmount},
+		{"Prenote with an amount", []*ACHPayment{payment("53", 500, SECCodePPD)}, CodePrenoteNonZeroAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := &ACHSchedule{Header: &ACHScheduleHeader{}}
+			var err error
+			for _, p := range tt.payments {
+				schedule.Payments = append(schedule.Payments, p)
+				if err == nil {
+					err = validator.ValidateACHPayment(p)
+				}
+			}
+			if err == nil {
+				err = validator.ValidatePrenoteSchedule(schedule)
+			}
+
+			var ve ValidationError
+			switch {
+			case tt.code.IsZero() && err != nil:
+				t.Errorf("Unexpected error: %v", err)
+			case !tt.code.IsZero() && (!errors.As(err, &ve) || ve.Code != tt.code):
+				t.Errorf("Expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestReaderPrenoteSchedule(t *testing.T) {
+	file := createTestACHFile()
+	payments := file.Schedules[0].(*ACHSchedule).Payments
+	payments[0].(*ACHPayment).ACH_TransactionCode = "23"
+	payments[0].(*ACHPayment).Amount = 0
+	payments[1].(*ACHPayment).ACH_TransactionCode = "32"
+	payments[0].(*ACHPayment).TIN = "123456789"
+	payments[1].(*ACHPayment).TIN = "123456789"
+	file.Schedules[0].(*ACHSchedule).Trailer.ScheduleAmount = 200000
+	file.Trailer.TotalAmountPayments = 200000
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	reader := NewReader(&buf)
+	reader.ProcessFile(nil, nil, nil)
+	issues := reader.GetValidationReport().Issues
+	if len(issues) != 1 || issues[0].Code != CodePrenoteNonZeroAmount || issues[0].LineNumber != 5 {
+		t.Errorf("Expected one prenote issue on the schedule trailer, got %v", issues)
+	}
+
+	report := NewValidator().ValidateFile(file)
+	if len(report.Issues) != 1 || report.Issues[0].String() != issues[0].String() {
+		t.Errorf("Expected ValidateFile to report the same issue, got %v", report.Issues)
+	}
+}
+
+func TestValidateACHAddenda(t *testing.T) {
+	validator := NewValidator()
+
+	addenda := func(n int) []*ACHAddendum {
+		records := make([]*ACHAddendum, n)
+		for i := range records {
+			records[i] = &ACHAddendum{RecordCode: "03", PaymentID: "PAY001", AddendaInformation: "REMITTANCE"}
+		}
+		return records
+	}
+	ctxAddenda := func(n int) []*CTXAddendum {
+		records := make([]*CTXAddendum, n)
+		for i := range records {
+			records[i] = &CTXAddendum{RecordCode: "04", PaymentID: "PAY001"}
+		}
+		return records
+	}
+
+	tests := []struct {
+		name       string
+		sec        string
+		addenda    []*ACHAddendum
+		ctxAddenda []*CTXAddendum
+		errRule    string
+	}{
+		{"PPD without addenda", "PPD", nil, nil, ""},
+		{"PPD with one addendum", "PPD", addenda(1), nil, ""},
+		{"PPD with two addenda", "PPD", addenda(2), nil, "addenda_count"},
+		{"CCD with two addenda", "CCD", addenda(2), nil, "addenda_count"},
+		{"CCD with CTX addendum", "CCD", nil, ctxAddenda(1), "addenda_count"},
+		{"IAT with two addenda", "IAT", addenda(2), nil, ""},
+		{"IAT with three addenda", "IAT", addenda(3), nil, "addenda_count"},
+		{"CTX with 999 addenda", "CTX", nil, ctxAddenda(MaxCTXAddenda), ""},
+		{"CTX with 1000 addenda", "CTX", nil, ctxAddenda(MaxCTXAddenda + 1), "addenda_count"},
+		{"CTX with 03 addendum", "CTX", addenda(1), ctxAddenda(1), "addenda_count"},
+		{"Unknown SEC code", "", addenda(3), nil, ""},
+		{"Wrong record code", "PPD", []*ACHAddendum{{RecordCode: "04", PaymentID: "PAY001"}}, nil, "addendum_record_code"},
+		{"Wrong payment ID", "PPD", []*ACHAddendum{{RecordCode: "03", PaymentID: "PAY002"}}, nil, "addendum_payment_id"},
+		{"Padded payment ID", "PPD", []*ACHAddendum{{RecordCode: "03", PaymentID: "PAY001              "}}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &ACHPayment{PaymentID: "PAY001", StandardEntryClassCode: tt.sec, Addenda: tt.addenda, CTXAddenda: tt.ctxAddenda}
+			err := validator.ValidateACHAddenda(payment)
+			if tt.errRule == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			ve, ok := err.(ValidationError)
+			if !ok || ve.Rule != tt.errRule {
+				t.Fatalf("Expected %s error, got %v", tt.errRule, err)
+			}
+			if ve.Code.IsZero() {
+				t.Errorf("Expected a Treasury error code on %v", err)
+			}
+		})
+	}
+}
+
 func TestValidateCTXAddendum(t *testing.T) {
 	validator := NewValidator()
 
@@ -553,7 +701,7 @@ func TestValidateCTXAddendum(t *testing.T) {
 			name: "CTX without addendum",
 			payment: &ACHPayment{
 				StandardEntryClassCode: "CTX",
-				Addenda:                []*ACHAddendum{},
+				CTXAddenda:             []*CTXAddendum{},
 			},
 			expectErr: true,
 		},
@@ -561,10 +709,10 @@ func TestValidateCTXAddendum(t *testing.T) {
 			name: "CTX with valid addendum",
 			payment: &ACHPayment{
 				StandardEntryClassCode: "CTX",
-				Addenda: []*ACHAddendum{
+				CTXAddenda: []*CTXAddendum{
 					{
 						RecordCode:         "04",
-						AddendaInformation: "ISA*00*          *00*          ",
+						AddendaInformation: sampleCTXEDI,
 					},
 				},
 			},
@@ -574,7 +722,7 @@ func TestValidateCTXAddendum(t *testing.T) {
 			name: "CTX with wrong record code",
 			payment: &ACHPayment{
 				StandardEntryClassCode: "CTX",
-				Addenda: []*ACHAddendum{
+				CTXAddenda: []*CTXAddendum{
 					{
 						RecordCode:         "03",
 						AddendaInformation: "ISA*00*          *00*          ",
@@ -587,7 +735,7 @@ func TestValidateCTXAddendum(t *testing.T) {
 			name: "CTX without ISA",
 			payment: &ACHPayment{
 				StandardEntryClassCode: "CTX",
-				Addenda: []*ACHAddendum{
+				CTXAddenda: []*CTXAddendum{
 					{
 						RecordCode:         "04",
 						AddendaInformation: "BPR*00*          *00*          ",
@@ -612,23 +760,30 @@ func TestValidateCTXAddendum(t *testing.T) {
 }
 
 func TestValidateHexCharacters(t *testing.T) {
-	validator := NewValidator()
-
 	tests := []struct {
 		name      string
+		encoding  Encoding
 		data      string
 		expectErr bool
 	}{
-		{"Valid ASCII", "HELLO@WORLD@ABC", false},
-		{"Valid special chars", "TEST@^_{}|~", false},
-		{"Invalid hex 0x00", "TEST\x00DATA", true},
-		{"Invalid hex 0x1F", "TEST\x1FDATA", true},
-		{"Invalid hex 0x3F", "TEST\x3FDATA", true},
-		{"Valid hex 0x40", "TEST@DATA", false},
+		{"Valid ASCII", EncodingASCII, "HELLO@WORLD@ABC", false},
+		{"Valid special chars", EncodingASCII, "TEST@^_{}|~", false},
+		{"Space and digits", EncodingASCII, "PAY 0123456789", false},
+		{"Question mark is EBCDIC 0x6F", EncodingASCII, "TEST?DATA", false},
+		{"Latin-1 letter", EncodingASCII, "CAF\xC9", false},
+		{"Invalid hex 0x00", EncodingASCII, "TEST\x00DATA", true},
+		{"Invalid hex 0x1F", EncodingASCII, "TEST\x1FDATA", true},
+		{"Tab is EBCDIC 0x05", EncodingASCII, "TEST\tDATA", true},
+		{"Carriage return is EBCDIC 0x0D", EncodingASCII, "TEST\r", true},
+		{"C1 control is EBCDIC 0x15", EncodingASCII, "TEST\x85", true},
+		{"CP1047 brackets", EncodingCP1047, "[TEST]", false},
+		{"CP1047 control", EncodingCP1047, "TEST\x1F", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			validator := NewValidator()
+			validator.Encoding = tt.encoding
 			err := validator.ValidateHexCharacters(tt.data)
 			if tt.expectErr && err == nil {
 				t.Error("Expected error but got none")
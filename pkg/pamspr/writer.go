@@ -2,6 +2,7 @@ package pamspr
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -15,11 +16,30 @@ type WriterConfig struct {
 	// EnableValidation enables validation during writing (default: true)
 	EnableValidation bool
 
+	// SortACHPayments writes the payments of each ACH schedule in routing number
+	// order, as SortACHSchedule would, without reordering the File (default: false)
+	SortACHPayments bool
+
 	// FlushInterval controls how often to flush buffer (default: every 100 records)
 	FlushInterval int
 
 	// ChecksumValidation enables checksum calculation during writing
 	ChecksumValidation bool
+
+	// LineTerminator ends each record: LF (default), CRLF, or none for
+	// fixed 850-byte blocks
+	LineTerminator LineTerminator
+
+	// Progress is called with the running totals every ProgressInterval records
+	// and once more when Write returns (optional)
+	Progress ProgressCallback
+
+	// ProgressInterval is the number of records between Progress calls (default: 1000)
+	ProgressInterval int
+
+	// Encoding is the output character set; records are written one ISO-8859-1
+	// byte per column and transcoded to EBCDIC if requested (default: ASCII)
+	Encoding Encoding
 }
 
 // DefaultWriterConfig returns sensible defaults
@@ -44,8 +64,16 @@ type Writer struct {
 	paymentCount  int64
 	scheduleCount int64
 	totalAmount   int64
+	bytesWritten  int64
 	errors        []error
 	isFinalized   bool
+	ctx           context.Context // Set by WriteContext
+
+	// Open schedule for BeginSchedule/AddPayment/EndSchedule
+	openSchedule      Schedule
+	schedulePayments  int64
+	scheduleAmount    int64
+	lastRoutingNumber string
 
 	// Memory-efficient formatting buffer
 	lineBuffer strings.Builder
@@ -60,17 +88,22 @@ func NewWriter(w io.Writer) *Writer {
 // NewWriterWithConfig creates a writer with custom configuration
 func NewWriterWithConfig(w io.Writer, config *WriterConfig) *Writer {
 
+	out := newEncodingWriter(w, config.Encoding)
+
 	var buffer *bufio.Writer
 	if config.BufferSize > 0 {
-		buffer = bufio.NewWriterSize(w, config.BufferSize)
+		buffer = bufio.NewWriterSize(out, config.BufferSize)
 	} else {
-		buffer = bufio.NewWriter(w)
+		buffer = bufio.NewWriter(out)
 	}
 
+	validator := NewValidator()
+	validator.Encoding = config.Encoding
+
 	writer := &Writer{
 		writer:    w,
 		buffer:    buffer,
-		validator: NewValidator(),
+		validator: validator,
 		config:    config,
 		errors:    make([]error, 0),
 	}
@@ -155,6 +188,16 @@ func (w *Writer) WritePayment(payment Payment) error {
 			}
 		}
 
+		for _, addendum := range p.CTXAddenda {
+			addendumLine, err := w.formatCTXAddendum(addendum)
+			if err != nil {
+				return fmt.Errorf("formatting CTX addendum: %w", err)
+			}
+			if err := w.writeLine(addendumLine); err != nil {
+				return err
+			}
+		}
+
 		for _, cars := range p.CARSTASBETC {
 			carsLine, err := w.formatCARSTASBETC(cars)
 			if err != nil {
@@ -294,6 +337,127 @@ func (w *Writer) WriteFileTrailer(trailer *FileTrailer) error {
 	return nil
 }
 
+// BeginSchedule writes the schedule header and opens the schedule for AddPayment.
+// Counts and amounts are accumulated so EndSchedule can write the "T " trailer.
+// ACH payments must be added in routing number order; payments already attached to
+// an ACH schedule can be ordered with SortACHSchedule.
+func (w *Writer) BeginSchedule(schedule Schedule) error {
+	if w.isFinalized {
+		return fmt.Errorf("file already finalized")
+	}
+	if w.openSchedule != nil {
+		return fmt.Errorf("schedule %s must be ended before beginning another", w.openSchedule.GetScheduleNumber())
+	}
+
+	if err := w.WriteScheduleHeader(schedule); err != nil {
+		return err
+	}
+
+	w.openSchedule = schedule
+	w.schedulePayments = 0
+	w.scheduleAmount = 0
+	w.lastRoutingNumber = ""
+	return nil
+}
+
+// AddPayment writes a payment and its associated records to the open schedule
+func (w *Writer) AddPayment(payment Payment) error {
+	if w.openSchedule == nil {
+		return fmt.Errorf("no schedule begun")
+	}
+
+	switch p := payment.(type) {
+	case *ACHPayment:
+		schedule, ok := w.openSchedule.(*ACHSchedule)
+		if !ok {
+			return fmt.Errorf("cannot add ACH payment %s to check schedule %s", p.PaymentID, w.openSchedule.GetScheduleNumber())
+		}
+		p.RecordCode = "02"
+		if p.StandardEntryClassCode == "" && schedule.Header != nil {
+			p.StandardEntryClassCode = schedule.Header.StandardEntryClassCode
+		}
+		if w.config.EnableValidation {
+			if err := w.validator.ValidateACHPayment(p); err != nil {
+				return fmt.Errorf("validating ACH payment %s: %w", p.PaymentID, err)
+			}
+			if err := w.validator.ValidateACHAddenda(p); err != nil {
+				return fmt.Errorf("validating ACH payment %s: %w", p.PaymentID, err)
+			}
+			if w.schedulePayments > 0 {
+				if err := checkRoutingNumberOrder(w.lastRoutingNumber, p.RoutingNumber); err != nil {
+					return fmt.Errorf("validating ACH payment %s: %w", p.PaymentID, err)
+				}
+			}
+		}
+	case *CheckPayment:
+		if _, ok := w.openSchedule.(*CheckSchedule); !ok {
+			return fmt.Errorf("cannot add check payment %s to ACH schedule %s", p.PaymentID, w.openSchedule.GetScheduleNumber())
+		}
+		p.RecordCode = "12"
+		if w.config.EnableValidation {
+			if err := w.validator.ValidateCheckPayment(p); err != nil {
+				return fmt.Errorf("validating check payment %s: %w", p.PaymentID, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown payment type")
+	}
+
+	if err := w.WritePayment(payment); err != nil {
+		return err
+	}
+
+	w.schedulePayments++
+	w.scheduleAmount += payment.GetAmount()
+	if p, ok := payment.(*ACHPayment); ok {
+		w.lastRoutingNumber = p.RoutingNumber
+	}
+	return nil
+}
+
+// EndSchedule writes the "T " trailer for the open schedule from the accumulated
+// payment count and amount, and returns it
+func (w *Writer) EndSchedule() (*ScheduleTrailer, error) {
+	if w.openSchedule == nil {
+		return nil, fmt.Errorf("no schedule begun")
+	}
+
+	trailer := &ScheduleTrailer{
+		RecordCode:     "T ",
+		ScheduleCount:  w.schedulePayments,
+		ScheduleAmount: w.scheduleAmount,
+	}
+	if err := w.WriteScheduleTrailer(trailer); err != nil {
+		return nil, err
+	}
+
+	w.openSchedule.SetTrailer(trailer)
+	w.openSchedule = nil
+	return trailer, nil
+}
+
+// Close writes the "E " trailer from the accumulated totals and flushes the output.
+// Any open schedule must be ended first. Close does not close the underlying io.Writer.
+func (w *Writer) Close() (*FileTrailer, error) {
+	if w.openSchedule != nil {
+		return nil, fmt.Errorf("schedule %s must be ended before closing", w.openSchedule.GetScheduleNumber())
+	}
+	if w.recordCount == 0 {
+		return nil, fmt.Errorf("file header must be written before closing")
+	}
+
+	trailer := &FileTrailer{
+		RecordCode:          "E ",
+		TotalCountRecords:   w.recordCount + 1, // +1 for the trailer itself
+		TotalCountPayments:  w.paymentCount,
+		TotalAmountPayments: w.totalAmount,
+	}
+	if err := w.WriteFileTrailer(trailer); err != nil {
+		return nil, err
+	}
+	return trailer, nil
+}
+
 // Flush forces a buffer flush
 func (w *Writer) Flush() error {
 	return w.buffer.Flush()
@@ -436,22 +600,34 @@ func (w *Writer) formatCheckPayment(payment *CheckPayment) (string, error) {
 }
 
 func (w *Writer) formatACHAddendum(addendum *ACHAddendum) (string, error) {
+	if addendum.RecordCode != string(RecordTypeACHAddendum) {
+		return "", fmt.Errorf("invalid addendum record code: %s", addendum.RecordCode)
+	}
+
 	w.lineBuffer.Reset()
 	w.lineBuffer.Grow(RecordLength)
 
 	w.appendField(addendum.RecordCode, 2)
 	w.appendField(addendum.PaymentID, 20)
+	w.appendField(addendum.AddendaInformation, 80)
+	w.appendFiller(748)
 
-	if addendum.RecordCode == "03" {
-		w.appendField(addendum.AddendaInformation, 80)
-		w.appendFiller(748)
-	} else if addendum.RecordCode == "04" { // CTX
-		w.appendField(addendum.AddendaInformation, 800)
-		w.appendFiller(28)
-	} else {
-		return "", fmt.Errorf("invalid addendum record code: %s", addendum.RecordCode)
+	return w.lineBuffer.String(), nil
+}
+
+func (w *Writer) formatCTXAddendum(addendum *CTXAddendum) (string, error) {
+	if addendum.RecordCode != string(RecordTypeACHAddendumCTX) {
+		return "", fmt.Errorf("invalid CTX addendum record code: %s", addendum.RecordCode)
 	}
 
+	w.lineBuffer.Reset()
+	w.lineBuffer.Grow(RecordLength)
+
+	w.appendField(addendum.RecordCode, 2)
+	w.appendField(addendum.PaymentID, 20)
+	w.appendField(addendum.AddendaInformation, 800)
+	w.appendFiller(28)
+
 	return w.lineBuffer.String(), nil
 }
 
@@ -604,18 +780,32 @@ func (w *Writer) appendSpaces(count int) {
 }
 
 func (w *Writer) writeLine(line string) error {
+	if w.ctx != nil {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+	}
 	if len(line) != RecordLength {
 		return fmt.Errorf("invalid line length: expected %d, got %d", RecordLength, len(line))
 	}
+	if w.config.EnableValidation {
+		if errs := w.validator.ValidateRecordFields(line); len(errs) > 0 {
+			return fmt.Errorf("record %d: %w", w.recordCount+1, errs[0])
+		}
+	}
 
 	if _, err := w.buffer.WriteString(line); err != nil {
 		return err
 	}
-	if err := w.buffer.WriteByte('\n'); err != nil {
+	if _, err := w.buffer.WriteString(w.config.LineTerminator.bytes()); err != nil {
 		return err
 	}
 
 	w.recordCount++
+	w.bytesWritten += int64(len(line) + len(w.config.LineTerminator.bytes()))
+	if w.config.Progress != nil && w.recordCount%int64(progressInterval(w.config.ProgressInterval)) == 0 {
+		w.config.Progress(w.progressStats())
+	}
 	return nil
 }
 
@@ -623,6 +813,16 @@ func (w *Writer) writeLine(line string) error {
 // This method provides compatibility with the traditional Writer.Write() API
 // while using streaming internally for memory efficiency
 func (w *Writer) Write(file *File) error {
+	if w.config.Progress != nil {
+		defer func() { w.config.Progress(w.progressStats()) }()
+	}
+	if file.Header == nil {
+		return fmt.Errorf("file header is required")
+	}
+	if file.Trailer == nil {
+		return fmt.Errorf("file trailer is required")
+	}
+
 	// Write file header
 	if err := w.WriteFileHeader(file.Header); err != nil {
 		return fmt.Errorf("writing file header: %w", err)
@@ -635,13 +835,21 @@ func (w *Writer) Write(file *File) error {
 		}
 
 		// Write payments
-		for _, payment := range schedule.GetPayments() {
+		payments := schedule.GetPayments()
+		if _, ok := schedule.(*ACHSchedule); ok && w.config.SortACHPayments {
+			payments = append([]Payment(nil), payments...)
+			sortByRoutingNumber(payments)
+		}
+		for _, payment := range payments {
 			if err := w.WritePayment(payment); err != nil {
 				return fmt.Errorf("writing payment in schedule %d: %w", i, err)
 			}
 		}
 
 		// Write schedule trailer
+		if schedule.GetTrailer() == nil {
+			return fmt.Errorf("schedule %d trailer is required", i)
+		}
 		if err := w.WriteScheduleTrailer(schedule.GetTrailer()); err != nil {
 			return fmt.Errorf("writing schedule %d trailer: %w", i, err)
 		}
@@ -223,6 +223,53 @@ func TestWriter_WriteError(t *testing.T) {
 	}
 }
 
+// TestWriter_SortACHPayments tests payments are written in routing number order
+func TestWriter_SortACHPayments(t *testing.T) {
+	file := createTestACHFile()
+	schedule := file.Schedules[0].(*ACHSchedule)
+	schedule.Payments[0], schedule.Payments[1] = schedule.Payments[1], schedule.Payments[0]
+
+	config := DefaultWriterConfig()
+	config.SortACHPayments = true
+	var buf bytes.Buffer
+	if err := NewWriterWithConfig(&buf, config).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if strings.TrimSpace(schedule.Payments[0].GetPaymentID()) != "PAY002" {
+		t.Error("Write should not reorder the file's payments")
+	}
+
+	read, err := NewReader(strings.NewReader(buf.String())).Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var ids []string
+	for _, payment := range read.Schedules[0].GetPayments() {
+		ids = append(ids, strings.TrimSpace(payment.GetPaymentID()))
+	}
+	if strings.Join(ids, ",") != "PAY001,PAY002" {
+		t.Errorf("Expected PAY001,PAY002, got %v", ids)
+	}
+	if err := NewValidator().validateACHPaymentOrder(read.Schedules[0].(*ACHSchedule)); err != nil {
+		t.Errorf("Written schedule is out of order: %v", err)
+	}
+}
+
+// TestWriter_AccentedCharacters tests characters above HEX "3F" outside ASCII are written
+func TestWriter_AccentedCharacters(t *testing.T) {
+	file := createTestACHFile()
+	file.Schedules[0].(*ACHSchedule).Payments[0].(*ACHPayment).PayeeName = "JOS\xC9 NU\xD1EZ" // CP037 0x71 and 0x69
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "JOS\xC9 NU\xD1EZ") {
+		t.Error("Expected the payee name to be written as given")
+	}
+}
+
 // TestWriter_GetStats tests statistics tracking
 func TestWriter_GetStats(t *testing.T) {
 	var buf bytes.Buffer
@@ -302,3 +349,162 @@ func TestWriter_GetStats(t *testing.T) {
 		t.Errorf("Expected total amount 100000, got %d", totalAmount)
 	}
 }
+
+// TestWriter_StreamingSchedules tests BeginSchedule/AddPayment/EndSchedule/Close
+// produce the same output as Write with precomputed trailers
+func TestWriter_StreamingSchedules(t *testing.T) {
+	file := createTestMixedFile()
+
+	var expected bytes.Buffer
+	if err := NewWriter(&expected).Write(file); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	config := DefaultWriterConfig()
+	config.EnableValidation = false
+	writer := NewWriterWithConfig(&buf, config)
+	if err := writer.WriteFileHeader(file.Header); err != nil {
+		t.Fatalf("WriteFileHeader failed: %v", err)
+	}
+	for _, schedule := range file.Schedules {
+		want := schedule.GetTrailer()
+		if err := writer.BeginSchedule(schedule); err != nil {
+			t.Fatalf("BeginSchedule failed: %v", err)
+		}
+		for _, payment := range schedule.GetPayments() {
+			if err := writer.AddPayment(payment); err != nil {
+				t.Fatalf("AddPayment failed: %v", err)
+			}
+		}
+		trailer, err := writer.EndSchedule()
+		if err != nil {
+			t.Fatalf("EndSchedule failed: %v", err)
+		}
+		if *trailer != *want {
+			t.Errorf("Schedule %s trailer: expected %+v, got %+v", schedule.GetScheduleNumber(), *want, *trailer)
+		}
+	}
+	trailer, err := writer.Close()
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if *trailer != *file.Trailer {
+		t.Errorf("File trailer: expected %+v, got %+v", *file.Trailer, *trailer)
+	}
+
+	if buf.String() != expected.String() {
+		t.Error("Streamed output differs from Write output")
+	}
+}
+
+// TestWriter_StreamingErrors tests misuse of the streaming schedule API
+func TestWriter_StreamingErrors(t *testing.T) {
+	ach := createTestACHFile()
+	check := createTestCheckFile()
+	achSchedule := ach.Schedules[0]
+	checkPayment := check.Schedules[0].GetPayments()[0]
+
+	tests := []struct {
+		name   string
+		run    func(w *Writer) error
+		errMsg string
+	}{
+		{
+			name: "AddPayment without schedule",
+			run: func(w *Writer) error {
+				return w.AddPayment(checkPayment)
+			},
+			errMsg: "no schedule begun",
+		},
+		{
+			name: "EndSchedule without schedule",
+			run: func(w *Writer) error {
+				_, err := w.EndSchedule()
+				return err
+			},
+			errMsg: "no schedule begun",
+		},
+		{
+			name: "Nested BeginSchedule",
+			run: func(w *Writer) error {
+				if err := w.BeginSchedule(achSchedule); err != nil {
+					return err
+				}
+				return w.BeginSchedule(check.Schedules[0])
+			},
+			errMsg: "must be ended before beginning another",
+		},
+		{
+			name: "Check payment in ACH schedule",
+			run: func(w *Writer) error {
+				if err := w.BeginSchedule(achSchedule); err != nil {
+					return err
+				}
+				return w.AddPayment(checkPayment)
+			},
+			errMsg: "cannot add check payment",
+		},
+		{
+			name: "Invalid payment",
+			run: func(w *Writer) error {
+				if err := w.BeginSchedule(achSchedule); err != nil {
+					return err
+				}
+				return w.AddPayment(&ACHPayment{PaymentID: "BAD", Amount: 100})
+			},
+			errMsg: "validating ACH payment BAD",
+		},
+		{
+			name: "Routing number out of order",
+			run: func(w *Writer) error {
+				if err := w.BeginSchedule(achSchedule); err != nil {
+					return err
+				}
+				second := *achSchedule.GetPayments()[1].(*ACHPayment)
+				second.ACH_TransactionCode = "22"
+				if err := w.AddPayment(&second); err != nil {
+					return err
+				}
+				return w.AddPayment(achSchedule.GetPayments()[0])
+			},
+			errMsg: "routing number order",
+		},
+		{
+			name: "Close with open schedule",
+			run: func(w *Writer) error {
+				if err := w.BeginSchedule(achSchedule); err != nil {
+					return err
+				}
+				_, err := w.Close()
+				return err
+			},
+			errMsg: "must be ended before closing",
+		},
+		{
+			name: "Close twice",
+			run: func(w *Writer) error {
+				if _, err := w.Close(); err != nil {
+					return err
+				}
+				_, err := w.Close()
+				return err
+			},
+			errMsg: "file already finalized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := NewWriter(&buf)
+			if err := writer.WriteFileHeader(ach.Header); err != nil {
+				t.Fatalf("WriteFileHeader failed: %v", err)
+			}
+			err := tt.run(writer)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -43,7 +43,7 @@ func (rb *RecordBuilder) SetField(recordCode, fieldName, value string) error {
 	}
 
 	// Truncate or pad the value to fit the field
-	padded := padField(value, fieldDef.Length)
+	padded := padField(value, fieldDef)
 
 	// Copy the padded value to the correct position (convert to 0-based indexing)
 	start := fieldDef.Start - 1
@@ -63,12 +63,18 @@ func (rb *RecordBuilder) String() string {
 	return string(rb.data)
 }
 
-// padField pads a field value to the specified length
-func padField(value string, length int) string {
+// padField pads a field value to the field's length
+func padField(value string, fieldDef pamspr.FieldDefinition) string {
+	length := fieldDef.Length
 	if len(value) >= length {
 		return value[:length]
 	}
 
+	// Blank A and AN fields stay blank rather than zero filled
+	if value == "" && fieldDef.Type != pamspr.FieldTypeNumeric {
+		return strings.Repeat(" ", length)
+	}
+
 	// For numeric fields (all digits), pad with zeros on the left
 	if isNumeric(value) {
 		return fmt.Sprintf("%0*s", length, value)
@@ -136,7 +142,7 @@ func generateACHPayment(accountID, amount, paymentID string) string {
 	rb.SetField("02", "PostalCode", "12345")
 	rb.SetField("02", "PostalCodeExtension", "")
 	rb.SetField("02", "CountryCodeText", "US")
-	rb.SetField("02", "RoutingNumber", "123456789")
+	rb.SetField("02", "RoutingNumber", "021000021")
 	rb.SetField("02", "AccountNumber", "12345678901234567")
 	rb.SetField("02", "ACHTransactionCode", "22")
 	rb.SetField("02", "PayeeIdentifierAdditional", "")
@@ -350,7 +356,7 @@ func main() {
 		rb.SetField("02", "PostalCode", "20001")
 		rb.SetField("02", "PostalCodeExtension", "")
 		rb.SetField("02", "CountryCodeText", "US")
-		rb.SetField("02", "RoutingNumber", "123456789")
+		rb.SetField("02", "RoutingNumber", "021000021")
 		rb.SetField("02", "AccountNumber", "98765432109876543")
 		rb.SetField("02", "ACHTransactionCode", "22")
 		rb.SetField("02", "PayeeIdentifierAdditional", "")